@@ -1,6 +1,7 @@
 package base
 
 import (
+	"context"
 	"errors"
 	"net/url"
 	"sort"
@@ -13,6 +14,10 @@ var LoginFailErr = errors.New("登录失败")
 var SiteSettingErr = errors.New("设置站点失败")
 var ArticleGetErr = errors.New("获取文章失败")
 var ArticleNewErr = errors.New("新建文章失败")
+
+// ArticleLocateErr 文章或页面已保存 但未能读取到其ID及链接，调用方不应重复提交
+var ArticleLocateErr = errors.New("文章已保存但未能获取ID及链接")
+
 var ArticleDelErr = errors.New("删除文章失败")
 var CategoryNewErr = errors.New("新建分类失败")
 var CategoryGetErr = errors.New("获取分类失败")
@@ -21,14 +26,24 @@ var TagNewErr = errors.New("新建标签失败")
 var TagDelErr = errors.New("删除标签失败")
 var TagUndefinedErr = errors.New("无法找到标签")
 var NavbarNewErr = errors.New("新建导航失败")
+var UnsupportedErr = errors.New("程序不支持该操作")
 
 const UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/100.0.4896.75 Safari/537.36"
 
+// SiteSetting 站点设置 设置时字符串为空、数值为0、指针为nil的项保持站点当前值
 type SiteSetting struct {
-	SiteName        string `json:"site_title"`
-	SubSiteName     string `json:"site_sub_title"`
-	SiteKeywords    string `json:"site_keywords"`
-	SiteDescription string `json:"site_description"`
+	SiteName        string            `json:"site_title"`
+	SubSiteName     string            `json:"site_sub_title"`
+	SiteKeywords    string            `json:"site_keywords"`
+	SiteDescription string            `json:"site_description"`
+	Copyright       string            `json:"site_copyright"`     // 版权及页脚
+	ICP             string            `json:"site_icp"`           // 备案号
+	TimeZone        string            `json:"site_time_zone"`     // 时区 如 Asia/Shanghai
+	Language        string            `json:"site_language"`      // 语言包 如 zh-cn
+	PageSize        int               `json:"site_page_size"`     // 列表每页数量
+	CommentOff      *bool             `json:"site_comment_off"`   // 关闭评论
+	CommentAudit    *bool             `json:"site_comment_audit"` // 评论需审核
+	Extra           map[string]string `json:"site_extra"`         // 其它设置 键为程序的原始字段名
 }
 
 // Union 通用结构体
@@ -40,18 +55,19 @@ type Union struct {
 // Article 文章
 type Article struct {
 	Union
-	Title    string    `json:"title"`     // 标题
-	Content  string    `json:"content"`   // 正文
-	Alias    string    `json:"alias"`     // 别名
-	Tag      []string  `json:"tag"`       // 标签
-	Cate     *Category `json:"cate"`      // 分类
-	Status   string    `json:"status"`    // 状态		0 公开	1 草稿	2 审核
-	Template string    `json:"template"`  // 内容模板
-	AuthorID string    `json:"author_id"` // 作者id
-	PostTime time.Time `json:"post_time"` // 发布时间
-	IsTop    string    `json:"is_top"`    // 置顶		0 无	1 全局	2 首页	3 分类
-	IsLock   string    `json:"is_lock"`   // 评论		0 允许	2 禁止
-	Intro    string    `json:"intro"`     // 摘要
+	Title     string    `json:"title"`     // 标题
+	Content   string    `json:"content"`   // 正文
+	Alias     string    `json:"alias"`     // 别名
+	Tag       []string  `json:"tag"`       // 标签
+	Cate      *Category `json:"cate"`      // 分类
+	Status    Status    `json:"status"`    // 状态		见 StatusPublic 等
+	Template  string    `json:"template"`  // 内容模板
+	AuthorID  string    `json:"author_id"` // 作者id
+	PostTime  time.Time `json:"post_time"` // 发布时间
+	IsTop     TopLevel  `json:"is_top"`    // 置顶		见 TopNone 等
+	IsLock    LockState `json:"is_lock"`   // 评论		见 LockOpen 等
+	Intro     string    `json:"intro"`     // 摘要
+	Permalink string    `json:"permalink"` // 链接		发布后由适配器填充
 }
 
 // Category 分类
@@ -150,9 +166,11 @@ func UrlQueryBuild(value url.Values) string {
 }
 
 type ProgramBaseInfo struct {
-	HomeURL       string `json:"home_url"`       // 主站 http://blog.isolezvoscombles.com/
-	BackstagePath string `json:"backstage_path"` // 后台路径 zb_system/
-	LoginPath     string `json:"login_path"`     // 登录路径 cmd.php?act=verify
+	HomeURL       string     `json:"home_url"`       // 主站 http://blog.isolezvoscombles.com/
+	BackstagePath string     `json:"backstage_path"` // 后台路径 zb_system/
+	LoginPath     string     `json:"login_path"`     // 登录路径 cmd.php?act=verify
+	Plugins       []string   `json:"plugins"`        // 必备插件 Init时启用
+	Permalink     *Permalink `json:"permalink"`      // 链接结构 Init时应用，为空使用 DefaultPermalink
 }
 
 // LoginFunc 登录
@@ -167,7 +185,27 @@ func RegisterProgram(name string, f LoginFunc) {
 	programList[name] = f
 }
 
+// GetProgram 获取登录函数，以 RegisterProgramContext 注册的程序会被包装为旧接口
 func GetProgram(name string) LoginFunc {
+	if f := getLegacyProgram(name); f != nil {
+		return f
+	}
+	plcMutex.Lock()
+	f, ok := programContextList[name]
+	plcMutex.Unlock()
+	if !ok {
+		return nil
+	}
+	return func(u, p string, info ProgramBaseInfo) (ProgramAPI, error) {
+		api, err := f(context.Background(), u, p, info)
+		if err != nil {
+			return nil, err
+		}
+		return Legacy(api), nil
+	}
+}
+
+func getLegacyProgram(name string) LoginFunc {
 	plMutex.Lock()
 	defer plMutex.Unlock()
 	if _, ok := programList[name]; ok {
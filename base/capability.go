@@ -0,0 +1,92 @@
+package base
+
+import "sync"
+
+// Feature 程序支持的功能
+type Feature string
+
+const (
+	FeatureArticle   Feature = "article"   // ArticleNew ArticleGet ArticleDel
+	FeatureCategory  Feature = "category"  // CategoryNew CategoryGet CategoryDel
+	FeatureTag       Feature = "tag"       // TagNew TagGet TagDel
+	FeatureNavbar    Feature = "navbar"    // NavbarNew
+	FeatureSetting   Feature = "setting"   // SiteSetting
+	FeatureList      Feature = "list"      // ArticleList CategoryList TagList
+	FeatureComment   Feature = "comment"   // Comment*
+	FeaturePage      Feature = "page"      // Page*
+	FeatureMember    Feature = "member"    // Member*
+	FeatureMedia     Feature = "media"     // MediaUpload MediaDel
+	FeaturePlugin    Feature = "plugin"    // Plugin*
+	FeaturePermalink Feature = "permalink" // PermalinkSet PermalinkGet
+)
+
+// 文章内容格式
+const (
+	FormatHTML     = "html"
+	FormatMarkdown = "markdown"
+)
+
+// Capabilities 程序的能力描述
+type Capabilities struct {
+	Features []Feature `json:"features"` // 支持的功能
+	Formats  []string  `json:"formats"`  // 支持的文章内容格式
+}
+
+// Has 是否支持功能
+func (c Capabilities) Has(f Feature) bool {
+	for _, v := range c.Features {
+		if v == f {
+			return true
+		}
+	}
+	return false
+}
+
+// HasFormat 是否支持内容格式
+func (c Capabilities) HasFormat(format string) bool {
+	for _, v := range c.Formats {
+		if v == format {
+			return true
+		}
+	}
+	return false
+}
+
+// LegacyCapabilities 以 RegisterProgram 注册且未声明能力的程序，即旧 ProgramAPI 的全部功能
+var LegacyCapabilities = Capabilities{
+	Features: []Feature{FeatureArticle, FeatureCategory, FeatureTag, FeatureNavbar, FeatureSetting},
+	Formats:  []string{FormatHTML},
+}
+
+var capabilityList = make(map[string]Capabilities)
+var clMutex = &sync.Mutex{}
+
+// RegisterCapabilities 声明程序的能力
+func RegisterCapabilities(name string, c Capabilities) {
+	clMutex.Lock()
+	defer clMutex.Unlock()
+	capabilityList[name] = c
+}
+
+// GetCapabilities 获取程序的能力 未声明时返回 LegacyCapabilities
+func GetCapabilities(name string) Capabilities {
+	clMutex.Lock()
+	defer clMutex.Unlock()
+	if c, ok := capabilityList[name]; ok {
+		return c
+	}
+	return LegacyCapabilities
+}
+
+// CapabilityReporter 能力随站点变化的会话 如按服务端支持的方法判断功能的 metaweblog
+type CapabilityReporter interface {
+	Capabilities() Capabilities
+}
+
+// SessionCapabilities 已登录会话的能力 会话实现了 CapabilityReporter 时以其为准，否则为程序声明的能力
+func SessionCapabilities(name string, api ProgramAPIContext) Capabilities {
+	if r, ok := Unwrap(api).(CapabilityReporter); ok {
+		return r.Capabilities()
+	}
+	return GetCapabilities(name)
+}
@@ -0,0 +1,23 @@
+package base
+
+import (
+	"errors"
+	"time"
+)
+
+var CommentChkErr = errors.New("审核评论失败")
+var CommentReplyErr = errors.New("回复评论失败")
+var CommentDelErr = errors.New("删除评论失败")
+
+// Comment 评论
+type Comment struct {
+	Union
+	LogID      string    `json:"log_id"`      // 文章ID
+	ParentID   string    `json:"parent_id"`   // 父级评论ID	0 无
+	Name       string    `json:"name"`        // 昵称
+	Email      string    `json:"email"`       // 邮箱
+	HomePage   string    `json:"home_page"`   // 主页
+	Content    string    `json:"content"`     // 内容
+	PostTime   time.Time `json:"post_time"`   // 发布时间
+	IsChecking bool      `json:"is_checking"` // 待审核
+}
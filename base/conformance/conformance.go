@@ -0,0 +1,254 @@
+// Package conformance 程序适配器的通用测试
+//
+// 新适配器在测试中调用 Run，以相同的用例验证错误密码登录、文章、分类、标签、导航的增删改查，
+// 重复创建标签的幂等性，查找失败时返回的错误，以及会话失效后的重新登录。
+package conformance
+
+import (
+	"context"
+	"errors"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Config 测试配置
+type Config struct {
+	Login    base.LoginContextFunc // 旧接口可先以 base.WrapLegacy 包装
+	Info     base.ProgramBaseInfo
+	Server   *httptest.Server // 模拟的站点 Info.HomeURL 为空时使用其地址
+	Username string
+	Password string
+	Caps     *base.Capabilities // 为nil时全部测试
+
+	// NavbarList 读取导航 为nil时只检查 NavbarNew 不返回错误
+	NavbarList func(ctx context.Context, api base.ProgramAPIContext) ([]base.Navbar, error)
+
+	// SkipBadLogin 不测试错误密码 用于不校验密码的适配器
+	SkipBadLogin bool
+
+	// Expire 使模拟站点上已登录的会话失效 不为nil时检查之后的请求会自动重新登录
+	Expire func()
+}
+
+func (c *Config) has(f base.Feature) bool {
+	return c.Caps == nil || c.Caps.Has(f)
+}
+
+// Run 执行所有用例
+func Run(t *testing.T, c Config) {
+	t.Helper()
+	if c.Info.HomeURL == "" && c.Server != nil {
+		c.Info.HomeURL = c.Server.URL + "/"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if !c.SkipBadLogin {
+		t.Run("LoginFail", func(t *testing.T) {
+			_, err := c.Login(ctx, c.Username, c.Password+"-wrong", c.Info)
+			if !errors.Is(err, base.LoginFailErr) || !base.IsAuth(err) {
+				t.Fatalf("错误密码登录 期望 LoginFailErr 得到 %v", err)
+			}
+		})
+	}
+	api, err := c.Login(ctx, c.Username, c.Password, c.Info)
+	if err != nil {
+		t.Fatalf("登录失败 %v", err)
+	}
+	if err = api.Init(ctx); err != nil {
+		var pe *base.PluginError
+		if !errors.As(err, &pe) {
+			t.Fatalf("Init %v", err)
+		}
+	}
+	cate := &base.Category{Name: "conformance-cate", Alias: "conformance-cate"}
+	t.Run("Category", func(t *testing.T) {
+		if !c.has(base.FeatureCategory) {
+			t.Skip("不支持分类")
+		}
+		testCategory(ctx, t, api, cate)
+	})
+	t.Run("Article", func(t *testing.T) {
+		if !c.has(base.FeatureArticle) {
+			t.Skip("不支持文章")
+		}
+		testArticle(ctx, t, api, cate)
+	})
+	t.Run("Tag", func(t *testing.T) {
+		if !c.has(base.FeatureTag) {
+			t.Skip("不支持标签")
+		}
+		testTag(ctx, t, api)
+	})
+	t.Run("Navbar", func(t *testing.T) {
+		if !c.has(base.FeatureNavbar) {
+			t.Skip("不支持导航")
+		}
+		testNavbar(ctx, t, api, c.NavbarList)
+	})
+	t.Run("Relogin", func(t *testing.T) {
+		if c.Expire == nil {
+			t.Skip("未提供 Expire")
+		}
+		testRelogin(ctx, t, api, c.Expire)
+	})
+	if cate.ID != "" {
+		_ = api.CategoryDel(ctx, cate)
+	}
+}
+
+// testRelogin 会话失效后的请求应重新登录并成功 适配器提供 Relogins 时检查只登录了一次
+func testRelogin(ctx context.Context, t *testing.T, api base.ProgramAPIContext, expire func()) {
+	counter, _ := base.Unwrap(api).(interface{ Relogins() int })
+	before := 0
+	if counter != nil {
+		before = counter.Relogins()
+	}
+	expire()
+	if _, _, err := api.CategoryList(ctx, nil); err != nil {
+		t.Fatalf("会话失效后 CategoryList %v", err)
+	}
+	if counter != nil && counter.Relogins() != before+1 {
+		t.Fatalf("重新登录 %d 次 期望 1 次", counter.Relogins()-before)
+	}
+}
+
+func testCategory(ctx context.Context, t *testing.T, api base.ProgramAPIContext, cate *base.Category) {
+	if err := api.CategoryGet(ctx, &base.Category{Name: "conformance-missing"}); !errors.Is(err, base.CategoryGetErr) {
+		t.Fatalf("获取不存在的分类 期望 CategoryGetErr 得到 %v", err)
+	}
+	if err := api.CategoryNew(ctx, cate); err != nil {
+		t.Fatalf("CategoryNew %v", err)
+	}
+	got := &base.Category{Name: cate.Name}
+	if err := api.CategoryGet(ctx, got); err != nil || got.ID == "" {
+		t.Fatalf("CategoryGet id=%q %v", got.ID, err)
+	}
+	cate.ID = got.ID
+	got.Intro = "updated"
+	if err := api.CategoryNew(ctx, got); err != nil {
+		t.Fatalf("修改分类 %v", err)
+	}
+	again := &base.Category{Name: cate.Name}
+	if err := api.CategoryGet(ctx, again); err != nil || again.ID != cate.ID {
+		t.Fatalf("修改后分类ID变化 %q -> %q %v", cate.ID, again.ID, err)
+	}
+	tmp := &base.Category{Name: "conformance-del"}
+	if err := api.CategoryNew(ctx, tmp); err != nil {
+		t.Fatalf("CategoryNew %v", err)
+	}
+	if err := api.CategoryGet(ctx, tmp); err != nil {
+		t.Fatalf("CategoryGet %v", err)
+	}
+	if err := api.CategoryDel(ctx, tmp); err != nil {
+		t.Fatalf("CategoryDel %v", err)
+	}
+	if err := api.CategoryGet(ctx, &base.Category{Name: tmp.Name}); !errors.Is(err, base.CategoryGetErr) {
+		t.Fatalf("删除后获取分类 期望 CategoryGetErr 得到 %v", err)
+	}
+}
+
+func testArticle(ctx context.Context, t *testing.T, api base.ProgramAPIContext, cate *base.Category) {
+	if err := api.ArticleGet(ctx, &base.Article{Title: "conformance-missing"}); !errors.Is(err, base.ArticleGetErr) {
+		t.Fatalf("获取不存在的文章 期望 ArticleGetErr 得到 %v", err)
+	}
+	a := &base.Article{
+		Union:    base.Union{ID: "0", Type: base.TypeArticle},
+		Title:    "conformance-article",
+		Content:  "<p>conformance</p>",
+		Status:   base.StatusPublic,
+		AuthorID: "1",
+		PostTime: time.Now(),
+		IsTop:    base.TopNone,
+		IsLock:   base.LockOpen,
+	}
+	if cate.ID != "" {
+		a.Cate = cate
+	} else {
+		a.Cate = &base.Category{}
+	}
+	if err := api.ArticleNew(ctx, a); err != nil {
+		t.Fatalf("ArticleNew %v", err)
+	}
+	if a.ID == "" || a.ID == "0" {
+		t.Fatalf("ArticleNew 未填充ID")
+	}
+	got := &base.Article{Title: a.Title}
+	if err := api.ArticleGet(ctx, got); err != nil || got.ID != a.ID {
+		t.Fatalf("按标题获取文章 id=%q 期望%q %v", got.ID, a.ID, err)
+	}
+	got.Title = "conformance-article-updated"
+	got.Content = "<p>updated</p>"
+	if err := api.ArticleNew(ctx, got); err != nil || got.ID != a.ID {
+		t.Fatalf("修改文章 id=%q %v", got.ID, err)
+	}
+	// 重新读取确认修改已保存 正文可能被程序转换格式，只检查其中的文字
+	again := &base.Article{Union: base.Union{ID: a.ID}}
+	if err := api.ArticleGet(ctx, again); err != nil {
+		t.Fatalf("按ID获取文章 %v", err)
+	}
+	if again.Title != got.Title || !strings.Contains(again.Content, "updated") {
+		t.Fatalf("修改后的文章 标题%q 正文%q", again.Title, again.Content)
+	}
+	if err := api.ArticleDel(ctx, again); err != nil {
+		t.Fatalf("ArticleDel %v", err)
+	}
+	if err := api.ArticleGet(ctx, &base.Article{Title: got.Title}); !errors.Is(err, base.ArticleGetErr) {
+		t.Fatalf("删除后获取文章 期望 ArticleGetErr 得到 %v", err)
+	}
+}
+
+func testTag(ctx context.Context, t *testing.T, api base.ProgramAPIContext) {
+	if err := api.TagGet(ctx, &base.Tag{Name: "conformance-missing"}); !errors.Is(err, base.TagUndefinedErr) {
+		t.Fatalf("获取不存在的标签 期望 TagUndefinedErr 得到 %v", err)
+	}
+	tag := &base.Tag{Name: "conformance-tag", Alias: "conformance-tag"}
+	if err := api.TagNew(ctx, tag); err != nil {
+		t.Fatalf("TagNew %v", err)
+	}
+	if err := api.TagNew(ctx, &base.Tag{Name: tag.Name, Alias: tag.Alias}); err != nil {
+		t.Fatalf("重复创建标签应视为成功 %v", err)
+	}
+	got := &base.Tag{Name: tag.Name}
+	if err := api.TagGet(ctx, got); err != nil || got.ID == "" {
+		t.Fatalf("TagGet id=%q %v", got.ID, err)
+	}
+	if err := api.TagDel(ctx, got); err != nil {
+		t.Fatalf("TagDel %v", err)
+	}
+	if err := api.TagGet(ctx, &base.Tag{Name: tag.Name}); !errors.Is(err, base.TagUndefinedErr) {
+		t.Fatalf("删除后获取标签 期望 TagUndefinedErr 得到 %v", err)
+	}
+}
+
+func testNavbar(ctx context.Context, t *testing.T, api base.ProgramAPIContext, list func(context.Context, base.ProgramAPIContext) ([]base.Navbar, error)) {
+	n := &base.Navbar{Href: "/conformance.html", Title: "conformance", Text: "conformance"}
+	if err := api.NavbarNew(ctx, n); err != nil {
+		t.Fatalf("NavbarNew %v", err)
+	}
+	if err := api.NavbarNew(ctx, &base.Navbar{Href: n.Href, Title: n.Title, Text: "updated"}); err != nil {
+		t.Fatalf("修改导航 %v", err)
+	}
+	if list == nil {
+		return
+	}
+	data, err := list(ctx, api)
+	if err != nil {
+		t.Fatalf("读取导航 %v", err)
+	}
+	count := 0
+	for _, v := range data {
+		if v.Href == n.Href {
+			count++
+			if v.Text != "updated" {
+				t.Fatalf("导航未更新 %q", v.Text)
+			}
+		}
+	}
+	if count != 1 {
+		t.Fatalf("导航 %s 出现%d次", n.Href, count)
+	}
+}
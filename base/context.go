@@ -0,0 +1,326 @@
+package base
+
+import (
+	"context"
+	"sync"
+)
+
+// ProgramAPIContext 带上下文的程序接口
+// 每个调用都接收 context.Context，用于取消、超时，新的适配器应优先实现该接口
+type ProgramAPIContext interface {
+
+	// Init 初始化
+	Init(context.Context) error
+
+	// SiteSetting 设置站点
+	SiteSetting(context.Context, *SiteSetting) error
+
+	// SiteSettingGet 读取站点设置
+	SiteSettingGet(context.Context, *SiteSetting) error
+
+	// ArticleNew 新建或修改文章 已保存但未能获取ID及链接时返回 ArticleLocateErr
+	ArticleNew(context.Context, *Article) error
+
+	// ArticleGet 获取文章
+	ArticleGet(context.Context, *Article) error
+
+	// ArticleDel 删除文章 必须指定 Article.ID
+	ArticleDel(context.Context, *Article) error
+
+	// CategoryGet 获取分类
+	CategoryGet(context.Context, *Category) error
+
+	// CategoryNew 创建或修改分类
+	CategoryNew(context.Context, *Category) error
+
+	// CategoryDel 删除文章 必须指定 Category.ID
+	CategoryDel(context.Context, *Category) error
+
+	// NavbarNew 创建或修改导航
+	NavbarNew(context.Context, *Navbar) error
+
+	// TagNew 创建或修改标签
+	TagNew(context.Context, *Tag) error
+
+	// TagGet 获取tag
+	TagGet(context.Context, *Tag) error
+
+	// TagDel 删除标签
+	TagDel(context.Context, *Tag) error
+
+	// ArticleList 文章列表 返回当页记录及符合条件的总数
+	ArticleList(context.Context, *ListOption) ([]Article, int, error)
+
+	// CategoryList 分类列表 返回当页记录及符合条件的总数
+	CategoryList(context.Context, *ListOption) ([]Category, int, error)
+
+	// TagList 标签列表 返回当页记录及符合条件的总数
+	TagList(context.Context, *ListOption) ([]Tag, int, error)
+
+	// CommentList 评论列表 ListOption.Status 为 CommentChecking 时仅列出待审核评论
+	CommentList(context.Context, *ListOption) ([]Comment, int, error)
+
+	// CommentApprove 通过评论 必须指定 Comment.ID
+	CommentApprove(context.Context, *Comment) error
+
+	// CommentUnapprove 取消通过，评论转为待审核 必须指定 Comment.ID
+	CommentUnapprove(context.Context, *Comment) error
+
+	// CommentReply 回复评论 第一个参数为被回复的评论，必须指定 ID 及 LogID
+	CommentReply(context.Context, *Comment, *Comment) error
+
+	// CommentDel 删除评论 必须指定 Comment.ID
+	CommentDel(context.Context, *Comment) error
+
+	// PageNew 新建或修改独立页面 页面使用 Article 表示，Union.Type 为 TypePage
+	PageNew(context.Context, *Article) error
+
+	// PageGet 获取独立页面
+	PageGet(context.Context, *Article) error
+
+	// PageList 独立页面列表 返回当页记录及符合条件的总数
+	PageList(context.Context, *ListOption) ([]Article, int, error)
+
+	// PageDel 删除独立页面 必须指定 Article.ID
+	PageDel(context.Context, *Article) error
+
+	// MemberList 用户列表 返回当页记录及符合条件的总数
+	MemberList(context.Context, *ListOption) ([]Member, int, error)
+
+	// MemberGet 获取用户 按 Member.ID 或 Member.Name 查找
+	MemberGet(context.Context, *Member) error
+
+	// MemberNew 创建或修改用户
+	MemberNew(context.Context, *Member) error
+
+	// MemberPassword 修改用户密码
+	MemberPassword(context.Context, *Member, string) error
+
+	// MemberRole 设置用户角色
+	MemberRole(context.Context, *Member, string) error
+
+	// MemberDel 删除用户 必须指定 Member.ID
+	MemberDel(context.Context, *Member) error
+
+	// MediaUpload 上传附件 成功后填充 Media.ID 及 Media.URL
+	MediaUpload(context.Context, *Media) error
+
+	// MediaDel 删除附件 必须指定 Media.ID
+	MediaDel(context.Context, *Media) error
+
+	// PluginList 已安装的插件
+	PluginList(context.Context) ([]Plugin, error)
+
+	// PluginEnable 启用插件 必须指定 Plugin.ID
+	PluginEnable(context.Context, *Plugin) error
+
+	// PluginDisable 停用插件 必须指定 Plugin.ID
+	PluginDisable(context.Context, *Plugin) error
+
+	// PluginInstall 上传并安装插件包
+	PluginInstall(context.Context, *Plugin, []byte) error
+
+	// PermalinkSet 设置链接结构
+	PermalinkSet(context.Context, *Permalink) error
+
+	// PermalinkGet 读取站点当前的链接结构
+	PermalinkGet(context.Context) (*Permalink, error)
+}
+
+// LoginContextFunc 带上下文的登录
+type LoginContextFunc func(ctx context.Context, u, p string, info ProgramBaseInfo) (ProgramAPIContext, error)
+
+// WrapLegacy 将旧的 ProgramAPI 包装为 ProgramAPIContext
+// 旧实现无法中断进行中的请求，只在调用前检查 ctx 是否已取消
+func WrapLegacy(api ProgramAPI) ProgramAPIContext {
+	if l, ok := api.(*legacyAPI); ok {
+		return l.api
+	}
+	return &contextAPI{api: api}
+}
+
+// Legacy 将 ProgramAPIContext 包装为旧的 ProgramAPI，调用时使用 context.Background()
+func Legacy(api ProgramAPIContext) ProgramAPI {
+	if c, ok := api.(*contextAPI); ok {
+		return c.api
+	}
+	return &legacyAPI{api: api}
+}
+
+type contextAPI struct {
+	UnsupportedAPI
+	api ProgramAPI
+}
+
+func (c *contextAPI) Init(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.api.Init()
+}
+
+func (c *contextAPI) SiteSetting(ctx context.Context, s *SiteSetting) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.api.SiteSetting(s)
+}
+
+func (c *contextAPI) ArticleNew(ctx context.Context, a *Article) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.api.ArticleNew(a)
+}
+
+func (c *contextAPI) ArticleGet(ctx context.Context, a *Article) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.api.ArticleGet(a)
+}
+
+func (c *contextAPI) ArticleDel(ctx context.Context, a *Article) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.api.ArticleDel(a)
+}
+
+func (c *contextAPI) CategoryGet(ctx context.Context, cate *Category) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.api.CategoryGet(cate)
+}
+
+func (c *contextAPI) CategoryNew(ctx context.Context, cate *Category) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.api.CategoryNew(cate)
+}
+
+func (c *contextAPI) CategoryDel(ctx context.Context, cate *Category) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.api.CategoryDel(cate)
+}
+
+func (c *contextAPI) NavbarNew(ctx context.Context, n *Navbar) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.api.NavbarNew(n)
+}
+
+func (c *contextAPI) TagNew(ctx context.Context, t *Tag) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.api.TagNew(t)
+}
+
+func (c *contextAPI) TagGet(ctx context.Context, t *Tag) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.api.TagGet(t)
+}
+
+func (c *contextAPI) TagDel(ctx context.Context, t *Tag) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.api.TagDel(t)
+}
+
+type legacyAPI struct {
+	api ProgramAPIContext
+}
+
+func (l *legacyAPI) Init() error {
+	return l.api.Init(context.Background())
+}
+
+func (l *legacyAPI) SiteSetting(s *SiteSetting) error {
+	return l.api.SiteSetting(context.Background(), s)
+}
+
+func (l *legacyAPI) ArticleNew(a *Article) error {
+	return l.api.ArticleNew(context.Background(), a)
+}
+
+func (l *legacyAPI) ArticleGet(a *Article) error {
+	return l.api.ArticleGet(context.Background(), a)
+}
+
+func (l *legacyAPI) ArticleDel(a *Article) error {
+	return l.api.ArticleDel(context.Background(), a)
+}
+
+func (l *legacyAPI) CategoryGet(c *Category) error {
+	return l.api.CategoryGet(context.Background(), c)
+}
+
+func (l *legacyAPI) CategoryNew(c *Category) error {
+	return l.api.CategoryNew(context.Background(), c)
+}
+
+func (l *legacyAPI) CategoryDel(c *Category) error {
+	return l.api.CategoryDel(context.Background(), c)
+}
+
+func (l *legacyAPI) NavbarNew(n *Navbar) error {
+	return l.api.NavbarNew(context.Background(), n)
+}
+
+func (l *legacyAPI) TagNew(t *Tag) error {
+	return l.api.TagNew(context.Background(), t)
+}
+
+func (l *legacyAPI) TagGet(t *Tag) error {
+	return l.api.TagGet(context.Background(), t)
+}
+
+func (l *legacyAPI) TagDel(t *Tag) error {
+	return l.api.TagDel(context.Background(), t)
+}
+
+var programContextList = make(map[string]LoginContextFunc)
+var plcMutex = &sync.Mutex{}
+
+// RegisterProgramContext 注册带上下文的程序 可同时声明程序的能力
+func RegisterProgramContext(name string, f LoginContextFunc, caps ...Capabilities) {
+	if len(caps) > 0 {
+		RegisterCapabilities(name, caps[0])
+	}
+	plcMutex.Lock()
+	defer plcMutex.Unlock()
+	programContextList[name] = f
+}
+
+// GetProgramContext 获取带上下文的登录函数，仅以 RegisterProgram 注册的程序会被自动包装
+func GetProgramContext(name string) LoginContextFunc {
+	plcMutex.Lock()
+	f, ok := programContextList[name]
+	plcMutex.Unlock()
+	if ok {
+		return f
+	}
+	legacy := getLegacyProgram(name)
+	if legacy == nil {
+		return nil
+	}
+	return func(ctx context.Context, u, p string, info ProgramBaseInfo) (ProgramAPIContext, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		api, err := legacy(u, p, info)
+		if err != nil {
+			return nil, err
+		}
+		return WrapLegacy(api), nil
+	}
+}
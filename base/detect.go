@@ -0,0 +1,146 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var DetectFailErr = errors.New("无法识别站点程序")
+
+// DetectBodyLimit 识别时读取响应内容的上限
+const DetectBodyLimit = 1 << 20
+
+// DetectClient 识别程序时使用的客户端 不跟随跳转
+var DetectClient = &http.Client{
+	Timeout: 10 * time.Second,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// Fingerprint 站点页面的响应 供 DetectFunc 识别
+type Fingerprint struct {
+	URL    string
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+var generatorRegexp = regexp.MustCompile(`(?i)<meta[^>]+name=["']generator["'][^>]+content=["']([^"']+)["']`)
+
+// Generator 页面 meta generator 的内容
+func (f *Fingerprint) Generator() string {
+	if m := generatorRegexp.FindSubmatch(f.Body); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
+
+// Contains 页面内容是否包含 s
+func (f *Fingerprint) Contains(s string) bool {
+	return strings.Contains(string(f.Body), s)
+}
+
+// Fetch 请求站点下的其它路径 path 相对于首页
+func (f *Fingerprint) Fetch(ctx context.Context, path string) (*Fingerprint, error) {
+	base := f.URL
+	if i := strings.IndexAny(base, "?#"); i != -1 {
+		base = base[:i]
+	}
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	return fetchFingerprint(ctx, base+strings.TrimPrefix(path, "/"))
+}
+
+func fetchFingerprint(ctx context.Context, u string) (*Fingerprint, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	resp, err := DetectClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, DetectBodyLimit))
+	if err != nil {
+		return nil, err
+	}
+	return &Fingerprint{URL: u, Status: resp.StatusCode, Header: resp.Header, Body: body}, nil
+}
+
+// Detection 识别结果
+type Detection struct {
+	Program       string `json:"program_name"`
+	Version       string `json:"version"`
+	BackstagePath string `json:"backstage_path"`
+	LoginPath     string `json:"login_path"`
+	Score         int    `json:"score"` // 匹配程度 越大越可信
+}
+
+// DetectFunc 根据首页识别程序 不匹配时返回nil
+type DetectFunc func(ctx context.Context, home *Fingerprint) *Detection
+
+var detectList = make(map[string]DetectFunc)
+var dlMutex = &sync.Mutex{}
+
+// RegisterDetector 注册程序的识别函数
+func RegisterDetector(name string, f DetectFunc) {
+	dlMutex.Lock()
+	defer dlMutex.Unlock()
+	detectList[name] = f
+}
+
+// Detect 请求首页并由各程序识别 返回匹配程度最高的结果
+func Detect(ctx context.Context, homeURL string) (*Detection, error) {
+	home, err := fetchFingerprint(ctx, homeURL)
+	if err != nil {
+		return nil, &Error{Op: "Detect", Site: homeURL, Sentinel: DetectFailErr, Err: err}
+	}
+	dlMutex.Lock()
+	names := make([]string, 0, len(detectList))
+	for name := range detectList {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	funcs := make([]DetectFunc, len(names))
+	for i, name := range names {
+		funcs[i] = detectList[name]
+	}
+	dlMutex.Unlock()
+	var best *Detection
+	for i, name := range names {
+		d := funcs[i](ctx, home)
+		if d == nil || d.Score <= 0 {
+			continue
+		}
+		if d.Program == "" {
+			d.Program = name
+		}
+		if best == nil || d.Score > best.Score {
+			best = d
+		}
+	}
+	if best == nil {
+		e := &Error{Op: "Detect", Site: homeURL, Status: home.Status, Endpoint: "GET " + homeURL, Sentinel: DetectFailErr}
+		if len(home.Body) > ErrorBodyLimit {
+			e.Body = string(home.Body[:ErrorBodyLimit])
+		} else {
+			e.Body = string(home.Body)
+		}
+		return nil, e
+	}
+	return best, nil
+}
@@ -0,0 +1,37 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<html><head><meta name="generator" content="TestCMS 2.1"></head></html>`))
+	}))
+	defer srv.Close()
+	RegisterDetector("test-cms", func(ctx context.Context, home *Fingerprint) *Detection {
+		if home.Generator() != "TestCMS 2.1" {
+			return nil
+		}
+		return &Detection{Version: "2.1", Score: 100}
+	})
+	defer func() {
+		dlMutex.Lock()
+		delete(detectList, "test-cms")
+		dlMutex.Unlock()
+	}()
+	d, err := Detect(context.Background(), srv.URL+"/")
+	if err != nil || d.Program != "test-cms" || d.Version != "2.1" {
+		t.Fatal(d, err)
+	}
+	dlMutex.Lock()
+	delete(detectList, "test-cms")
+	dlMutex.Unlock()
+	if _, err = Detect(context.Background(), srv.URL+"/"); !errors.Is(err, DetectFailErr) {
+		t.Fatal(err)
+	}
+}
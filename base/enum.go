@@ -0,0 +1,216 @@
+package base
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var InvalidValueErr = errors.New("无效的取值")
+
+// Status 文章状态
+type Status string
+
+const (
+	StatusPublic Status = "0" // 公开
+	StatusDraft  Status = "1" // 草稿
+	StatusAudit  Status = "2" // 审核
+)
+
+// TopLevel 文章置顶
+type TopLevel string
+
+const (
+	TopNone     TopLevel = "0" // 不置顶
+	TopGlobal   TopLevel = "1" // 全局置顶
+	TopHome     TopLevel = "2" // 首页置顶
+	TopCategory TopLevel = "3" // 分类置顶
+)
+
+// LockState 文章评论开关
+type LockState string
+
+const (
+	LockOpen   LockState = "0" // 允许评论
+	LockClosed LockState = "2" // 禁止评论
+)
+
+// enumValue 枚举值及其可读名称 第一个名称用于输出
+type enumValue struct {
+	value string
+	names []string
+}
+
+var statusValues = []enumValue{
+	{"0", []string{"public", "publish", "公开"}},
+	{"1", []string{"draft", "草稿"}},
+	{"2", []string{"audit", "pending", "审核"}},
+}
+
+var topValues = []enumValue{
+	{"0", []string{"none", "无"}},
+	{"1", []string{"global", "全局"}},
+	{"2", []string{"home", "首页"}},
+	{"3", []string{"category", "分类"}},
+}
+
+var lockValues = []enumValue{
+	{"0", []string{"open", "allow", "允许"}},
+	{"2", []string{"closed", "lock", "禁止"}},
+}
+
+// parseEnum 按值或名称解析 空字符串表示未设置
+func parseEnum(kind string, values []enumValue, s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	for _, v := range values {
+		if v.value == s {
+			return v.value, nil
+		}
+		for _, name := range v.names {
+			if strings.EqualFold(name, s) {
+				return v.value, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: %s %q", InvalidValueErr, kind, s)
+}
+
+// unmarshalEnum 同时接受 "0"、0 和 "draft" 这样的写法
+func unmarshalEnum(kind string, values []enumValue, data []byte) (string, error) {
+	s := string(data)
+	if s == "null" {
+		return "", nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return "", err
+		}
+	}
+	return parseEnum(kind, values, s)
+}
+
+// marshalEnum 输出取值本身 如 "1"，取值是 JSON 中的标准写法，名称只在读取时接受
+func marshalEnum(s string) ([]byte, error) {
+	return json.Marshal(s)
+}
+
+// validEnum 是否为已定义的值 名称需先经过 Parse 转换
+func validEnum(values []enumValue, s string) bool {
+	for _, v := range values {
+		if v.value == s {
+			return true
+		}
+	}
+	return false
+}
+
+func enumName(values []enumValue, s string) string {
+	for _, v := range values {
+		if v.value == s {
+			return v.names[0]
+		}
+	}
+	return s
+}
+
+// ParseStatus 解析文章状态
+func ParseStatus(s string) (Status, error) {
+	v, err := parseEnum("status", statusValues, s)
+	return Status(v), err
+}
+
+// Valid 是否为已定义的状态
+func (s Status) Valid() bool {
+	return validEnum(statusValues, string(s))
+}
+
+func (s Status) String() string {
+	return enumName(statusValues, string(s))
+}
+
+func (s Status) MarshalJSON() ([]byte, error) {
+	return marshalEnum(string(s))
+}
+
+func (s *Status) UnmarshalJSON(data []byte) error {
+	v, err := unmarshalEnum("status", statusValues, data)
+	if err != nil {
+		return err
+	}
+	*s = Status(v)
+	return nil
+}
+
+// ParseTopLevel 解析置顶
+func ParseTopLevel(s string) (TopLevel, error) {
+	v, err := parseEnum("is_top", topValues, s)
+	return TopLevel(v), err
+}
+
+// Valid 是否为已定义的置顶
+func (t TopLevel) Valid() bool {
+	return validEnum(topValues, string(t))
+}
+
+func (t TopLevel) String() string {
+	return enumName(topValues, string(t))
+}
+
+func (t TopLevel) MarshalJSON() ([]byte, error) {
+	return marshalEnum(string(t))
+}
+
+func (t *TopLevel) UnmarshalJSON(data []byte) error {
+	v, err := unmarshalEnum("is_top", topValues, data)
+	if err != nil {
+		return err
+	}
+	*t = TopLevel(v)
+	return nil
+}
+
+// ParseLockState 解析评论开关
+func ParseLockState(s string) (LockState, error) {
+	v, err := parseEnum("is_lock", lockValues, s)
+	return LockState(v), err
+}
+
+// Valid 是否为已定义的评论开关
+func (l LockState) Valid() bool {
+	return validEnum(lockValues, string(l))
+}
+
+func (l LockState) String() string {
+	return enumName(lockValues, string(l))
+}
+
+func (l LockState) MarshalJSON() ([]byte, error) {
+	return marshalEnum(string(l))
+}
+
+func (l *LockState) UnmarshalJSON(data []byte) error {
+	v, err := unmarshalEnum("is_lock", lockValues, data)
+	if err != nil {
+		return err
+	}
+	*l = LockState(v)
+	return nil
+}
+
+// Validate 检查文章的枚举字段 空值表示使用默认值
+func (a *Article) Validate() error {
+	if a.Status != "" && !a.Status.Valid() {
+		return fmt.Errorf("%w: status %q", InvalidValueErr, string(a.Status))
+	}
+	if a.IsTop != "" && !a.IsTop.Valid() {
+		return fmt.Errorf("%w: is_top %q", InvalidValueErr, string(a.IsTop))
+	}
+	if a.IsLock != "" && !a.IsLock.Valid() {
+		return fmt.Errorf("%w: is_lock %q", InvalidValueErr, string(a.IsLock))
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package base
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestArticle_UnmarshalEnum(t *testing.T) {
+	var a Article
+	if err := json.Unmarshal([]byte(`{"status":"draft","is_top":3,"is_lock":"禁止"}`), &a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Status != StatusDraft || a.IsTop != TopCategory || a.IsLock != LockClosed {
+		t.Fatal(a.Status, a.IsTop, a.IsLock)
+	}
+	if err := json.Unmarshal([]byte(`{"status":"9"}`), &a); !errors.Is(err, InvalidValueErr) {
+		t.Fatal(err)
+	}
+	a.Status = "publish"
+	if err := a.Validate(); !IsValidation(err) {
+		t.Fatal(err)
+	}
+}
+
+func TestArticle_MarshalEnum(t *testing.T) {
+	a := Article{Status: StatusDraft, IsTop: TopCategory, IsLock: LockClosed}
+	data, err := json.Marshal(&a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err = json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["status"] != "1" || m["is_top"] != "3" || m["is_lock"] != "2" {
+		t.Fatal(m["status"], m["is_top"], m["is_lock"])
+	}
+	var b Article
+	if err = json.Unmarshal(data, &b); err != nil || b.Status != a.Status || b.IsTop != a.IsTop || b.IsLock != a.IsLock {
+		t.Fatal(b.Status, b.IsTop, b.IsLock, err)
+	}
+}
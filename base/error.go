@@ -0,0 +1,151 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ErrorKind 错误分类
+type ErrorKind int
+
+const (
+	KindUnknown    ErrorKind = iota // 未知
+	KindRetryable                   // 可重试 网络错误、超时、服务端错误
+	KindAuth                        // 认证失败 登录失败、会话过期、无权限
+	KindNotFound                    // 对象不存在
+	KindValidation                  // 参数错误
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindRetryable:
+		return "retryable"
+	case KindAuth:
+		return "auth"
+	case KindNotFound:
+		return "not_found"
+	case KindValidation:
+		return "validation"
+	}
+	return "unknown"
+}
+
+// ErrorBodyLimit Error.Body 保留的最大字节数
+const ErrorBodyLimit = 1024
+
+// Error 程序接口错误 可以 errors.Is 匹配 Sentinel（如 ArticleNewErr）及 Err
+type Error struct {
+	Op       string    // 操作 如 ArticleNew
+	Site     string    // 站点 如 http://blog.isolezvoscombles.com/
+	Status   int       // HTTP状态码 未收到响应时为0
+	Endpoint string    // 请求地址 不含 csrfToken
+	Body     string    // 响应内容 最多 ErrorBodyLimit 字节
+	Kind     ErrorKind // 分类 为 KindUnknown 时由 KindOf 推断
+	Sentinel error     // 对应的预定义错误
+	Err      error     // 底层错误
+}
+
+func (e *Error) Error() string {
+	b := &strings.Builder{}
+	b.WriteString(e.Op)
+	if e.Site != "" {
+		b.WriteString(" " + e.Site)
+	}
+	b.WriteString(": ")
+	switch {
+	case e.Sentinel != nil && e.Err != nil:
+		b.WriteString(e.Sentinel.Error() + ": " + e.Err.Error())
+	case e.Sentinel != nil:
+		b.WriteString(e.Sentinel.Error())
+	case e.Err != nil:
+		b.WriteString(e.Err.Error())
+	default:
+		b.WriteString("unknown error")
+	}
+	if e.Status != 0 {
+		_, _ = fmt.Fprintf(b, " [%d %s]", e.Status, e.Endpoint)
+	} else if e.Endpoint != "" {
+		_, _ = fmt.Fprintf(b, " [%s]", e.Endpoint)
+	}
+	if e.Body != "" {
+		body := strings.Join(strings.Fields(e.Body), " ")
+		if r := []rune(body); len(r) > 120 {
+			body = string(r[:120]) + "..."
+		}
+		_, _ = fmt.Fprintf(b, " body: %q", body)
+	}
+	return b.String()
+}
+
+func (e *Error) Is(target error) bool {
+	return e.Sentinel != nil && e.Sentinel == target
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+var notFoundErrs = []error{ArticleGetErr, CategoryGetErr, TagUndefinedErr, PageGetErr, MemberGetErr, PluginUndefinedErr}
+
+// KindOf 错误分类
+func KindOf(err error) ErrorKind {
+	if err == nil {
+		return KindUnknown
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		if e.Kind != KindUnknown {
+			return e.Kind
+		}
+		switch {
+		case e.Status == 401 || e.Status == 403:
+			return KindAuth
+		case e.Status == 404:
+			return KindNotFound
+		case e.Status == 429 || e.Status >= 500:
+			return KindRetryable
+		}
+	}
+	if errors.Is(err, LoginFailErr) || errors.Is(err, SessionExpiredErr) {
+		return KindAuth
+	}
+	if errors.Is(err, InvalidValueErr) {
+		return KindValidation
+	}
+	for _, nf := range notFoundErrs {
+		if errors.Is(err, nf) {
+			return KindNotFound
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return KindRetryable
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return KindRetryable
+	}
+	return KindUnknown
+}
+
+// IsRetryable 是否可以重试
+func IsRetryable(err error) bool {
+	return KindOf(err) == KindRetryable
+}
+
+// IsAuth 是否为认证错误
+func IsAuth(err error) bool {
+	return KindOf(err) == KindAuth
+}
+
+// IsNotFound 是否为对象不存在
+func IsNotFound(err error) bool {
+	return KindOf(err) == KindNotFound
+}
+
+// IsValidation 是否为参数错误
+func IsValidation(err error) bool {
+	return KindOf(err) == KindValidation
+}
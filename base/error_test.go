@@ -0,0 +1,35 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestError_Is(t *testing.T) {
+	var err error = &Error{Op: "ArticleNew", Site: "http://a.com/", Status: 200, Sentinel: ArticleNewErr}
+	if !errors.Is(err, ArticleNewErr) || errors.Is(err, ArticleDelErr) {
+		t.Fatal(err)
+	}
+	err = fmt.Errorf("wrap: %w", &Error{Op: "TagGet", Sentinel: TagUndefinedErr})
+	if !errors.Is(err, TagUndefinedErr) || !IsNotFound(err) {
+		t.Fatal(err)
+	}
+	err = &Error{Op: "Login", Status: 200, Sentinel: LoginFailErr}
+	if !IsAuth(err) {
+		t.Fatal(KindOf(err))
+	}
+	err = &Error{Op: "ArticleGet", Err: context.DeadlineExceeded}
+	if !IsRetryable(err) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal(KindOf(err))
+	}
+	err = &Error{Op: "CategoryDel", Status: 502, Sentinel: CategoryDelErr}
+	if !IsRetryable(err) {
+		t.Fatal(KindOf(err))
+	}
+	err = &Error{Op: "ArticleDel", Kind: KindValidation, Err: errors.New("请指定文章的id")}
+	if !IsValidation(err) {
+		t.Fatal(KindOf(err))
+	}
+}
@@ -0,0 +1,607 @@
+// Package fake 内存中的程序接口 用于离线测试及试运行配置
+//
+// 导入后以 "fake" 注册，同一 HomeURL 的多次登录共用一份站点数据。
+package fake
+
+import (
+	"context"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Name 注册的程序名称
+const Name = "fake"
+
+// Capabilities fake 支持的功能
+var Capabilities = base.Capabilities{
+	Features: []base.Feature{
+		base.FeatureArticle, base.FeatureCategory, base.FeatureTag, base.FeatureNavbar,
+		base.FeatureSetting, base.FeatureList, base.FeaturePermalink, base.FeatureMedia,
+	},
+	Formats: []string{base.FormatHTML, base.FormatMarkdown},
+}
+
+func init() {
+	base.RegisterProgramContext(Name, Login, Capabilities)
+}
+
+// Site 一个站点的数据
+type Site struct {
+	mu         sync.Mutex
+	home       string
+	users      map[string]string
+	setting    base.SiteSetting
+	permalink  *base.Permalink
+	articles   []*base.Article
+	categories []*base.Category
+	tags       []*base.Tag
+	navbar     []*base.Navbar
+	media      []*base.Media
+	lastID     int
+}
+
+var sites = make(map[string]*Site)
+var sitesMutex = &sync.Mutex{}
+
+// Open 获取站点 不存在时创建
+func Open(home string) *Site {
+	sitesMutex.Lock()
+	defer sitesMutex.Unlock()
+	s, ok := sites[home]
+	if !ok {
+		s = &Site{home: home}
+		sites[home] = s
+	}
+	return s
+}
+
+// Reset 清除所有站点
+func Reset() {
+	sitesMutex.Lock()
+	defer sitesMutex.Unlock()
+	sites = make(map[string]*Site)
+}
+
+// SetUser 设置可登录的用户 未设置任何用户时任意非空密码均可登录
+func (s *Site) SetUser(username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.users == nil {
+		s.users = make(map[string]string)
+	}
+	s.users[username] = password
+}
+
+// Setting 当前站点设置
+func (s *Site) Setting() base.SiteSetting {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.setting
+}
+
+// Navbar 当前导航
+func (s *Site) Navbar() []base.Navbar {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data := make([]base.Navbar, len(s.navbar))
+	for i, n := range s.navbar {
+		data[i] = *n
+	}
+	return data
+}
+
+// Media 已上传的附件
+func (s *Site) Media() []base.Media {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data := make([]base.Media, len(s.media))
+	for i, m := range s.media {
+		data[i] = *m
+	}
+	return data
+}
+
+// nextID 分配ID 所有类型共用一个自增序列
+func (s *Site) nextID() string {
+	s.lastID++
+	return strconv.Itoa(s.lastID)
+}
+
+func (s *Site) pl() *base.Permalink {
+	return s.permalink.Resolve()
+}
+
+// Session 登录后的接口
+type Session struct {
+	base.UnsupportedAPI
+	site *Site
+}
+
+// Login 登录 HomeURL 对应的站点
+func Login(ctx context.Context, username, password string, z base.ProgramBaseInfo) (base.ProgramAPIContext, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	site := Open(z.HomeURL)
+	site.mu.Lock()
+	defer site.mu.Unlock()
+	if pw, ok := site.users[username]; password == "" || (len(site.users) > 0 && (!ok || pw != password)) {
+		return nil, &base.Error{Op: "Login", Site: z.HomeURL, Sentinel: base.LoginFailErr}
+	}
+	if site.permalink == nil {
+		site.permalink = z.Permalink.Resolve()
+	}
+	return &Session{site: site}, nil
+}
+
+// Site 会话对应的站点
+func (f *Session) Site() *Site {
+	return f.site
+}
+
+func (f *Session) fail(op string, sentinel error) error {
+	return &base.Error{Op: op, Site: f.site.home, Sentinel: sentinel}
+}
+
+func (f *Session) invalid(op string, sentinel error, msg string) error {
+	return &base.Error{Op: op, Site: f.site.home, Kind: base.KindValidation, Sentinel: sentinel, Body: msg}
+}
+
+// lock 加锁并检查上下文 返回解锁函数
+func (f *Session) lock(ctx context.Context) (func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.site.mu.Lock()
+	return f.site.mu.Unlock, nil
+}
+
+// Init 初始化
+func (f *Session) Init(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// SiteSetting 设置站点 空值保持原值
+func (f *Session) SiteSetting(ctx context.Context, ss *base.SiteSetting) error {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	cur := &f.site.setting
+	for _, v := range []struct {
+		dst *string
+		src string
+	}{
+		{&cur.SiteName, ss.SiteName}, {&cur.SubSiteName, ss.SubSiteName},
+		{&cur.SiteKeywords, ss.SiteKeywords}, {&cur.SiteDescription, ss.SiteDescription},
+		{&cur.Copyright, ss.Copyright}, {&cur.ICP, ss.ICP}, {&cur.TimeZone, ss.TimeZone}, {&cur.Language, ss.Language},
+	} {
+		if v.src != "" {
+			*v.dst = v.src
+		}
+	}
+	if ss.PageSize != 0 {
+		cur.PageSize = ss.PageSize
+	}
+	if ss.CommentOff != nil {
+		b := *ss.CommentOff
+		cur.CommentOff = &b
+	}
+	if ss.CommentAudit != nil {
+		b := *ss.CommentAudit
+		cur.CommentAudit = &b
+	}
+	for k, v := range ss.Extra {
+		if cur.Extra == nil {
+			cur.Extra = make(map[string]string)
+		}
+		cur.Extra[k] = v
+	}
+	return nil
+}
+
+// SiteSettingGet 读取站点设置
+func (f *Session) SiteSettingGet(ctx context.Context, ss *base.SiteSetting) error {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	*ss = f.site.setting
+	ss.Extra = make(map[string]string, len(f.site.setting.Extra))
+	for k, v := range f.site.setting.Extra {
+		ss.Extra[k] = v
+	}
+	return nil
+}
+
+func copyArticle(a *base.Article) base.Article {
+	c := *a
+	c.Tag = append([]string(nil), a.Tag...)
+	if a.Cate != nil {
+		cate := *a.Cate
+		c.Cate = &cate
+	}
+	return c
+}
+
+func (s *Site) article(id string) *base.Article {
+	for _, a := range s.articles {
+		if a.ID == id {
+			return a
+		}
+	}
+	return nil
+}
+
+func (s *Site) category(id string) *base.Category {
+	for _, c := range s.categories {
+		if c.ID == id {
+			return c
+		}
+	}
+	return nil
+}
+
+func (s *Site) tag(name string) *base.Tag {
+	for _, t := range s.tags {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// ArticleNew 新建或修改文章 ID为空或0时新建，标签按名称自动创建
+func (f *Session) ArticleNew(ctx context.Context, a *base.Article) error {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err = a.Validate(); err != nil {
+		return &base.Error{Op: "ArticleNew", Site: f.site.home, Sentinel: base.ArticleNewErr, Err: err}
+	}
+	if a.Title == "" {
+		return f.invalid("ArticleNew", base.ArticleNewErr, "标题不能为空")
+	}
+	var cate *base.Category
+	if a.Cate != nil && a.Cate.ID != "" && a.Cate.ID != "0" {
+		if cate = f.site.category(a.Cate.ID); cate == nil {
+			return f.invalid("ArticleNew", base.ArticleNewErr, "分类不存在")
+		}
+	}
+	var stored *base.Article
+	if a.ID == "" || a.ID == "0" {
+		stored = &base.Article{}
+		a.ID = f.site.nextID()
+		f.site.articles = append(f.site.articles, stored)
+	} else if stored = f.site.article(a.ID); stored == nil {
+		return f.fail("ArticleNew", base.ArticleNewErr)
+	}
+	if a.Type == "" {
+		a.Type = base.TypeArticle
+	}
+	if a.Status == "" {
+		a.Status = base.StatusPublic
+	}
+	for _, name := range a.Tag {
+		if name = strings.TrimSpace(name); name != "" && f.site.tag(name) == nil {
+			f.site.tags = append(f.site.tags, &base.Tag{Union: base.Union{ID: f.site.nextID(), Type: "0"}, Name: name})
+		}
+	}
+	a.Permalink = f.site.pl().ArticleURL(f.site.home, a.ID, a.Alias)
+	*stored = copyArticle(a)
+	if cate != nil {
+		*stored.Cate = *cate
+	}
+	return nil
+}
+
+// ArticleGet 获取文章 依次按 ID、标题、别名查找
+func (f *Session) ArticleGet(ctx context.Context, a *base.Article) error {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	var found *base.Article
+	if a.ID != "" && a.ID != "0" {
+		found = f.site.article(a.ID)
+	}
+	for _, v := range f.site.articles {
+		if found == nil && a.Title != "" && v.Title == a.Title {
+			found = v
+		}
+	}
+	for _, v := range f.site.articles {
+		if found == nil && a.Alias != "" && v.Alias == a.Alias {
+			found = v
+		}
+	}
+	if found == nil {
+		return f.fail("ArticleGet", base.ArticleGetErr)
+	}
+	*a = copyArticle(found)
+	return nil
+}
+
+// ArticleDel 删除文章
+func (f *Session) ArticleDel(ctx context.Context, a *base.Article) error {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	for i, v := range f.site.articles {
+		if v.ID == a.ID {
+			f.site.articles = append(f.site.articles[:i], f.site.articles[i+1:]...)
+			return nil
+		}
+	}
+	return f.fail("ArticleDel", base.ArticleDelErr)
+}
+
+// ArticleList 文章列表
+func (f *Session) ArticleList(ctx context.Context, opt *base.ListOption) ([]base.Article, int, error) {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer unlock()
+	if opt == nil {
+		opt = &base.ListOption{}
+	}
+	data := make([]base.Article, 0)
+	for i := len(f.site.articles) - 1; i >= 0; i-- {
+		a := f.site.articles[i]
+		if opt.CateID != "" && (a.Cate == nil || a.Cate.ID != opt.CateID) {
+			continue
+		}
+		if opt.Status != "" && string(a.Status) != opt.Status {
+			continue
+		}
+		if opt.Search != "" && !strings.Contains(a.Title, opt.Search) {
+			continue
+		}
+		data = append(data, copyArticle(a))
+	}
+	start, end := opt.Paginate(len(data))
+	return data[start:end], len(data), nil
+}
+
+// CategoryGet 获取分类 按 ID 或名称查找
+func (f *Session) CategoryGet(ctx context.Context, c *base.Category) error {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	for _, v := range f.site.categories {
+		if (c.Name != "" && v.Name == c.Name) || (c.Name == "" && c.ID != "" && v.ID == c.ID) {
+			*c = *v
+			return nil
+		}
+	}
+	return f.fail("CategoryGet", base.CategoryGetErr)
+}
+
+// CategoryNew 创建或修改分类 ID为空或0时新建
+func (f *Session) CategoryNew(ctx context.Context, c *base.Category) error {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if c.Name == "" {
+		return f.invalid("CategoryNew", base.CategoryNewErr, "名称不能为空")
+	}
+	if c.ID == "" || c.ID == "0" {
+		c.ID = f.site.nextID()
+		c.Type = "0"
+		v := *c
+		f.site.categories = append(f.site.categories, &v)
+		return nil
+	}
+	v := f.site.category(c.ID)
+	if v == nil {
+		return f.fail("CategoryNew", base.CategoryNewErr)
+	}
+	*v = *c
+	return nil
+}
+
+// CategoryDel 删除分类
+func (f *Session) CategoryDel(ctx context.Context, c *base.Category) error {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	for i, v := range f.site.categories {
+		if v.ID == c.ID {
+			f.site.categories = append(f.site.categories[:i], f.site.categories[i+1:]...)
+			return nil
+		}
+	}
+	return f.fail("CategoryDel", base.CategoryDelErr)
+}
+
+// CategoryList 分类列表
+func (f *Session) CategoryList(ctx context.Context, opt *base.ListOption) ([]base.Category, int, error) {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer unlock()
+	if opt == nil {
+		opt = &base.ListOption{}
+	}
+	data := make([]base.Category, 0)
+	for _, c := range f.site.categories {
+		if opt.Search != "" && !strings.Contains(c.Name, opt.Search) {
+			continue
+		}
+		data = append(data, *c)
+	}
+	start, end := opt.Paginate(len(data))
+	return data[start:end], len(data), nil
+}
+
+// NavbarNew 创建或修改导航 链接相同时覆盖
+func (f *Session) NavbarNew(ctx context.Context, n *base.Navbar) error {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if n.Href == "" {
+		return f.invalid("NavbarNew", base.NavbarNewErr, "链接不能为空")
+	}
+	v := *n
+	for i, old := range f.site.navbar {
+		if old.Href == n.Href {
+			f.site.navbar[i] = &v
+			return nil
+		}
+	}
+	f.site.navbar = append(f.site.navbar, &v)
+	return nil
+}
+
+// TagNew 创建或修改标签 同名标签已存在时视为成功并填充ID
+func (f *Session) TagNew(ctx context.Context, t *base.Tag) error {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if t.Name == "" {
+		return f.invalid("TagNew", base.TagNewErr, "名称不能为空")
+	}
+	if v := f.site.tag(t.Name); v != nil {
+		if t.ID != "" && t.ID != "0" && t.ID == v.ID {
+			*v = *t
+		}
+		t.ID = v.ID
+		return nil
+	}
+	t.ID = f.site.nextID()
+	t.Type = "0"
+	v := *t
+	f.site.tags = append(f.site.tags, &v)
+	return nil
+}
+
+// TagGet 按名称获取标签
+func (f *Session) TagGet(ctx context.Context, t *base.Tag) error {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if v := f.site.tag(t.Name); v != nil {
+		*t = *v
+		return nil
+	}
+	return f.fail("TagGet", base.TagUndefinedErr)
+}
+
+// TagDel 按名称删除标签
+func (f *Session) TagDel(ctx context.Context, t *base.Tag) error {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	for i, v := range f.site.tags {
+		if v.Name == t.Name {
+			f.site.tags = append(f.site.tags[:i], f.site.tags[i+1:]...)
+			return nil
+		}
+	}
+	return f.fail("TagDel", base.TagUndefinedErr)
+}
+
+// TagList 标签列表 按名称排序
+func (f *Session) TagList(ctx context.Context, opt *base.ListOption) ([]base.Tag, int, error) {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer unlock()
+	if opt == nil {
+		opt = &base.ListOption{}
+	}
+	data := make([]base.Tag, 0)
+	for _, t := range f.site.tags {
+		if opt.Search != "" && !strings.Contains(t.Name, opt.Search) {
+			continue
+		}
+		data = append(data, *t)
+	}
+	sort.Slice(data, func(i, j int) bool {
+		return data[i].Name < data[j].Name
+	})
+	start, end := opt.Paginate(len(data))
+	return data[start:end], len(data), nil
+}
+
+// PermalinkSet 设置链接结构 为空的项使用默认值
+func (f *Session) PermalinkSet(ctx context.Context, pl *base.Permalink) error {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	f.site.permalink = pl.Resolve()
+	return nil
+}
+
+// PermalinkGet 读取链接结构
+func (f *Session) PermalinkGet(ctx context.Context) (*base.Permalink, error) {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return f.site.pl(), nil
+}
+
+// MediaUpload 上传附件 地址为站点的 upload/文件名
+func (f *Session) MediaUpload(ctx context.Context, m *base.Media) error {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if m.Name == "" {
+		return f.invalid("MediaUpload", base.MediaUploadErr, "文件名不能为空")
+	}
+	m.ID = f.site.nextID()
+	m.URL = f.site.home + "upload/" + m.Name
+	v := *m
+	v.Data = nil
+	f.site.media = append(f.site.media, &v)
+	return nil
+}
+
+// MediaDel 删除附件
+func (f *Session) MediaDel(ctx context.Context, m *base.Media) error {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	for i, v := range f.site.media {
+		if v.ID == m.ID {
+			f.site.media = append(f.site.media[:i], f.site.media[i+1:]...)
+			return nil
+		}
+	}
+	return f.fail("MediaDel", base.MediaDelErr)
+}
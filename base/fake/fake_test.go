@@ -0,0 +1,69 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"github.com/cgghui/bt_site_cluster_program_api/base/conformance"
+	"testing"
+)
+
+func TestSession(t *testing.T) {
+	Reset()
+	ctx := context.Background()
+	login := base.GetProgramContext(Name)
+	if _, err := login(ctx, "admin", "", base.ProgramBaseInfo{HomeURL: "http://a.com/"}); !errors.Is(err, base.LoginFailErr) {
+		t.Fatal(err)
+	}
+	api, err := login(ctx, "admin", "123", base.ProgramBaseInfo{HomeURL: "http://a.com/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cate := base.Category{Name: "新闻"}
+	if err = api.CategoryNew(ctx, &cate); err != nil || cate.ID == "" {
+		t.Fatal(cate.ID, err)
+	}
+	a := base.Article{Title: "hello", Cate: &base.Category{Union: base.Union{ID: cate.ID}}, Tag: []string{"go"}}
+	if err = api.ArticleNew(ctx, &a); err != nil || a.Permalink != "http://a.com/post/"+a.ID+".html" {
+		t.Fatal(a.Permalink, err)
+	}
+	got := base.Article{Title: "hello"}
+	if err = api.ArticleGet(ctx, &got); err != nil || got.ID != a.ID || got.Cate.Name != "新闻" {
+		t.Fatal(got, err)
+	}
+	tag := base.Tag{Name: "go"}
+	if err = api.TagNew(ctx, &tag); err != nil || tag.ID == "" {
+		t.Fatal(tag, err)
+	}
+	if err = api.ArticleDel(ctx, &got); err != nil {
+		t.Fatal(err)
+	}
+	if err = api.ArticleGet(ctx, &got); !base.IsNotFound(err) {
+		t.Fatal(err)
+	}
+	m := &base.Media{Name: "a.png", Data: []byte("png")}
+	if err = api.MediaUpload(ctx, m); err != nil || m.URL != "http://a.com/upload/a.png" || len(Open("http://a.com/").Media()) != 1 {
+		t.Fatal(m, err)
+	}
+	if err = api.MediaDel(ctx, m); err != nil || len(Open("http://a.com/").Media()) != 0 {
+		t.Fatal(err)
+	}
+	if err = api.MediaDel(ctx, m); !errors.Is(err, base.MediaDelErr) {
+		t.Fatal(err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	Reset()
+	Open("http://conformance.test/").SetUser("admin", "123456")
+	conformance.Run(t, conformance.Config{
+		Login:    Login,
+		Info:     base.ProgramBaseInfo{HomeURL: "http://conformance.test/"},
+		Username: "admin",
+		Password: "123456",
+		Caps:     &Capabilities,
+		NavbarList: func(ctx context.Context, api base.ProgramAPIContext) ([]base.Navbar, error) {
+			return base.Unwrap(api).(*Session).Site().Navbar(), nil
+		},
+	})
+}
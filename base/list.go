@@ -0,0 +1,36 @@
+package base
+
+// DefaultPageSize 默认每页数量
+const DefaultPageSize = 20
+
+// CommentChecking ListOption.Status 为该值时评论列表仅列出待审核评论
+const CommentChecking = "1"
+
+// ListOption 列表查询条件
+type ListOption struct {
+	Page     int    `json:"page"`      // 页码 从1开始	0 返回全部
+	PageSize int    `json:"page_size"` // 每页数量	0 使用 DefaultPageSize
+	CateID   string `json:"cate_id"`   // 分类ID 仅文章有效
+	Status   string `json:"status"`    // 状态 仅文章、评论有效
+	Search   string `json:"search"`    // 搜索关键词
+}
+
+// Paginate 按查询条件计算切片区间，total 为记录总数
+func (o *ListOption) Paginate(total int) (start, end int) {
+	if o == nil || o.Page <= 0 {
+		return 0, total
+	}
+	size := o.PageSize
+	if size <= 0 {
+		size = DefaultPageSize
+	}
+	start = (o.Page - 1) * size
+	if start > total {
+		start = total
+	}
+	end = start + size
+	if end > total {
+		end = total
+	}
+	return start, end
+}
@@ -0,0 +1,34 @@
+package base
+
+import (
+	"errors"
+	"io/ioutil"
+	"mime"
+	"path/filepath"
+)
+
+var MediaUploadErr = errors.New("上传附件失败")
+var MediaDelErr = errors.New("删除附件失败")
+
+// Media 附件
+type Media struct {
+	Union
+	Name     string `json:"name"`      // 文件名
+	MimeType string `json:"mime_type"` // 文件类型
+	Data     []byte `json:"-"`         // 文件内容
+	URL      string `json:"url"`       // 访问地址 上传后由适配器填充
+}
+
+// NewMediaFile 读取本地文件创建附件
+func NewMediaFile(path string) (*Media, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Base(path)
+	mt := mime.TypeByExtension(filepath.Ext(name))
+	if mt == "" {
+		mt = "application/octet-stream"
+	}
+	return &Media{Union: Union{ID: "0"}, Name: name, MimeType: mt, Data: data}, nil
+}
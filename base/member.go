@@ -0,0 +1,21 @@
+package base
+
+import "errors"
+
+var MemberNewErr = errors.New("保存用户失败")
+var MemberGetErr = errors.New("获取用户失败")
+var MemberDelErr = errors.New("删除用户失败")
+
+// Member 用户
+type Member struct {
+	Union
+	Name     string `json:"name"`      // 登录名
+	Password string `json:"password"`  // 密码 仅新建或修改密码时使用，为空不修改
+	Alias    string `json:"alias"`     // 显示名称
+	Level    string `json:"level"`     // 角色		1 管理员	2 网站编辑	3 作者	4 协作者	5 评论员	6 游客
+	Status   string `json:"status"`    // 状态		0 正常	1 审核	2 禁止
+	Email    string `json:"email"`     // 邮箱
+	HomePage string `json:"home_page"` // 主页
+	Intro    string `json:"intro"`     // 简介
+	Template string `json:"template"`  // 作者页模板
+}
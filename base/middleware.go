@@ -0,0 +1,311 @@
+package base
+
+import "context"
+
+// Call 一次接口调用
+type Call struct {
+	Op    string      // 方法名 如 ArticleNew
+	Arg   interface{} // 第一个参数 如 *Article，无参数时为nil
+	Write bool        // 是否修改站点数据
+}
+
+// Handler 中间件处理一次调用 next 执行下一层，不调用 next 即拦截该调用
+type Handler func(ctx context.Context, c *Call, next func(context.Context) error) error
+
+// Middleware 包装 ProgramAPIContext
+type Middleware func(ProgramAPIContext) ProgramAPIContext
+
+// Use 以 Handler 构造中间件 所有方法都经过 h
+func Use(h Handler) Middleware {
+	return func(next ProgramAPIContext) ProgramAPIContext {
+		return &hooked{next: next, h: h}
+	}
+}
+
+// Chain 依次应用中间件 第一个位于最外层
+func Chain(api ProgramAPIContext, mws ...Middleware) ProgramAPIContext {
+	for i := len(mws) - 1; i >= 0; i-- {
+		api = mws[i](api)
+	}
+	return api
+}
+
+// Unwrap 去除所有中间件 返回最内层的接口
+func Unwrap(api ProgramAPIContext) ProgramAPIContext {
+	for {
+		h, ok := api.(*hooked)
+		if !ok {
+			return api
+		}
+		api = h.next
+	}
+}
+
+type hooked struct {
+	next ProgramAPIContext
+	h    Handler
+}
+
+func (h *hooked) Init(ctx context.Context) error {
+	return h.h(ctx, &Call{Op: "Init", Arg: nil, Write: true}, func(ctx context.Context) error {
+		return h.next.Init(ctx)
+	})
+}
+
+func (h *hooked) SiteSetting(ctx context.Context, s *SiteSetting) error {
+	return h.h(ctx, &Call{Op: "SiteSetting", Arg: s, Write: true}, func(ctx context.Context) error {
+		return h.next.SiteSetting(ctx, s)
+	})
+}
+
+func (h *hooked) SiteSettingGet(ctx context.Context, s *SiteSetting) error {
+	return h.h(ctx, &Call{Op: "SiteSettingGet", Arg: s, Write: false}, func(ctx context.Context) error {
+		return h.next.SiteSettingGet(ctx, s)
+	})
+}
+
+func (h *hooked) ArticleNew(ctx context.Context, a *Article) error {
+	return h.h(ctx, &Call{Op: "ArticleNew", Arg: a, Write: true}, func(ctx context.Context) error {
+		return h.next.ArticleNew(ctx, a)
+	})
+}
+
+func (h *hooked) ArticleGet(ctx context.Context, a *Article) error {
+	return h.h(ctx, &Call{Op: "ArticleGet", Arg: a, Write: false}, func(ctx context.Context) error {
+		return h.next.ArticleGet(ctx, a)
+	})
+}
+
+func (h *hooked) ArticleDel(ctx context.Context, a *Article) error {
+	return h.h(ctx, &Call{Op: "ArticleDel", Arg: a, Write: true}, func(ctx context.Context) error {
+		return h.next.ArticleDel(ctx, a)
+	})
+}
+
+func (h *hooked) CategoryGet(ctx context.Context, c *Category) error {
+	return h.h(ctx, &Call{Op: "CategoryGet", Arg: c, Write: false}, func(ctx context.Context) error {
+		return h.next.CategoryGet(ctx, c)
+	})
+}
+
+func (h *hooked) CategoryNew(ctx context.Context, c *Category) error {
+	return h.h(ctx, &Call{Op: "CategoryNew", Arg: c, Write: true}, func(ctx context.Context) error {
+		return h.next.CategoryNew(ctx, c)
+	})
+}
+
+func (h *hooked) CategoryDel(ctx context.Context, c *Category) error {
+	return h.h(ctx, &Call{Op: "CategoryDel", Arg: c, Write: true}, func(ctx context.Context) error {
+		return h.next.CategoryDel(ctx, c)
+	})
+}
+
+func (h *hooked) NavbarNew(ctx context.Context, n *Navbar) error {
+	return h.h(ctx, &Call{Op: "NavbarNew", Arg: n, Write: true}, func(ctx context.Context) error {
+		return h.next.NavbarNew(ctx, n)
+	})
+}
+
+func (h *hooked) TagNew(ctx context.Context, t *Tag) error {
+	return h.h(ctx, &Call{Op: "TagNew", Arg: t, Write: true}, func(ctx context.Context) error {
+		return h.next.TagNew(ctx, t)
+	})
+}
+
+func (h *hooked) TagGet(ctx context.Context, t *Tag) error {
+	return h.h(ctx, &Call{Op: "TagGet", Arg: t, Write: false}, func(ctx context.Context) error {
+		return h.next.TagGet(ctx, t)
+	})
+}
+
+func (h *hooked) TagDel(ctx context.Context, t *Tag) error {
+	return h.h(ctx, &Call{Op: "TagDel", Arg: t, Write: true}, func(ctx context.Context) error {
+		return h.next.TagDel(ctx, t)
+	})
+}
+
+func (h *hooked) ArticleList(ctx context.Context, opt *ListOption) ([]Article, int, error) {
+	var data []Article
+	var total int
+	err := h.h(ctx, &Call{Op: "ArticleList", Arg: opt, Write: false}, func(ctx context.Context) (err error) {
+		data, total, err = h.next.ArticleList(ctx, opt)
+		return err
+	})
+	return data, total, err
+}
+
+func (h *hooked) CategoryList(ctx context.Context, opt *ListOption) ([]Category, int, error) {
+	var data []Category
+	var total int
+	err := h.h(ctx, &Call{Op: "CategoryList", Arg: opt, Write: false}, func(ctx context.Context) (err error) {
+		data, total, err = h.next.CategoryList(ctx, opt)
+		return err
+	})
+	return data, total, err
+}
+
+func (h *hooked) TagList(ctx context.Context, opt *ListOption) ([]Tag, int, error) {
+	var data []Tag
+	var total int
+	err := h.h(ctx, &Call{Op: "TagList", Arg: opt, Write: false}, func(ctx context.Context) (err error) {
+		data, total, err = h.next.TagList(ctx, opt)
+		return err
+	})
+	return data, total, err
+}
+
+func (h *hooked) CommentList(ctx context.Context, opt *ListOption) ([]Comment, int, error) {
+	var data []Comment
+	var total int
+	err := h.h(ctx, &Call{Op: "CommentList", Arg: opt, Write: false}, func(ctx context.Context) (err error) {
+		data, total, err = h.next.CommentList(ctx, opt)
+		return err
+	})
+	return data, total, err
+}
+
+func (h *hooked) CommentApprove(ctx context.Context, cm *Comment) error {
+	return h.h(ctx, &Call{Op: "CommentApprove", Arg: cm, Write: true}, func(ctx context.Context) error {
+		return h.next.CommentApprove(ctx, cm)
+	})
+}
+
+func (h *hooked) CommentUnapprove(ctx context.Context, cm *Comment) error {
+	return h.h(ctx, &Call{Op: "CommentUnapprove", Arg: cm, Write: true}, func(ctx context.Context) error {
+		return h.next.CommentUnapprove(ctx, cm)
+	})
+}
+
+func (h *hooked) CommentReply(ctx context.Context, cm *Comment, reply *Comment) error {
+	return h.h(ctx, &Call{Op: "CommentReply", Arg: cm, Write: true}, func(ctx context.Context) error {
+		return h.next.CommentReply(ctx, cm, reply)
+	})
+}
+
+func (h *hooked) CommentDel(ctx context.Context, cm *Comment) error {
+	return h.h(ctx, &Call{Op: "CommentDel", Arg: cm, Write: true}, func(ctx context.Context) error {
+		return h.next.CommentDel(ctx, cm)
+	})
+}
+
+func (h *hooked) PageNew(ctx context.Context, a *Article) error {
+	return h.h(ctx, &Call{Op: "PageNew", Arg: a, Write: true}, func(ctx context.Context) error {
+		return h.next.PageNew(ctx, a)
+	})
+}
+
+func (h *hooked) PageGet(ctx context.Context, a *Article) error {
+	return h.h(ctx, &Call{Op: "PageGet", Arg: a, Write: false}, func(ctx context.Context) error {
+		return h.next.PageGet(ctx, a)
+	})
+}
+
+func (h *hooked) PageList(ctx context.Context, opt *ListOption) ([]Article, int, error) {
+	var data []Article
+	var total int
+	err := h.h(ctx, &Call{Op: "PageList", Arg: opt, Write: false}, func(ctx context.Context) (err error) {
+		data, total, err = h.next.PageList(ctx, opt)
+		return err
+	})
+	return data, total, err
+}
+
+func (h *hooked) PageDel(ctx context.Context, a *Article) error {
+	return h.h(ctx, &Call{Op: "PageDel", Arg: a, Write: true}, func(ctx context.Context) error {
+		return h.next.PageDel(ctx, a)
+	})
+}
+
+func (h *hooked) MemberList(ctx context.Context, opt *ListOption) ([]Member, int, error) {
+	var data []Member
+	var total int
+	err := h.h(ctx, &Call{Op: "MemberList", Arg: opt, Write: false}, func(ctx context.Context) (err error) {
+		data, total, err = h.next.MemberList(ctx, opt)
+		return err
+	})
+	return data, total, err
+}
+
+func (h *hooked) MemberGet(ctx context.Context, m *Member) error {
+	return h.h(ctx, &Call{Op: "MemberGet", Arg: m, Write: false}, func(ctx context.Context) error {
+		return h.next.MemberGet(ctx, m)
+	})
+}
+
+func (h *hooked) MemberNew(ctx context.Context, m *Member) error {
+	return h.h(ctx, &Call{Op: "MemberNew", Arg: m, Write: true}, func(ctx context.Context) error {
+		return h.next.MemberNew(ctx, m)
+	})
+}
+
+func (h *hooked) MemberPassword(ctx context.Context, m *Member, password string) error {
+	return h.h(ctx, &Call{Op: "MemberPassword", Arg: m, Write: true}, func(ctx context.Context) error {
+		return h.next.MemberPassword(ctx, m, password)
+	})
+}
+
+func (h *hooked) MemberRole(ctx context.Context, m *Member, role string) error {
+	return h.h(ctx, &Call{Op: "MemberRole", Arg: m, Write: true}, func(ctx context.Context) error {
+		return h.next.MemberRole(ctx, m, role)
+	})
+}
+
+func (h *hooked) MemberDel(ctx context.Context, m *Member) error {
+	return h.h(ctx, &Call{Op: "MemberDel", Arg: m, Write: true}, func(ctx context.Context) error {
+		return h.next.MemberDel(ctx, m)
+	})
+}
+
+func (h *hooked) MediaUpload(ctx context.Context, m *Media) error {
+	return h.h(ctx, &Call{Op: "MediaUpload", Arg: m, Write: true}, func(ctx context.Context) error {
+		return h.next.MediaUpload(ctx, m)
+	})
+}
+
+func (h *hooked) MediaDel(ctx context.Context, m *Media) error {
+	return h.h(ctx, &Call{Op: "MediaDel", Arg: m, Write: true}, func(ctx context.Context) error {
+		return h.next.MediaDel(ctx, m)
+	})
+}
+
+func (h *hooked) PluginList(ctx context.Context) ([]Plugin, error) {
+	var data []Plugin
+	err := h.h(ctx, &Call{Op: "PluginList", Arg: nil, Write: false}, func(ctx context.Context) (err error) {
+		data, err = h.next.PluginList(ctx)
+		return err
+	})
+	return data, err
+}
+
+func (h *hooked) PluginEnable(ctx context.Context, p *Plugin) error {
+	return h.h(ctx, &Call{Op: "PluginEnable", Arg: p, Write: true}, func(ctx context.Context) error {
+		return h.next.PluginEnable(ctx, p)
+	})
+}
+
+func (h *hooked) PluginDisable(ctx context.Context, p *Plugin) error {
+	return h.h(ctx, &Call{Op: "PluginDisable", Arg: p, Write: true}, func(ctx context.Context) error {
+		return h.next.PluginDisable(ctx, p)
+	})
+}
+
+func (h *hooked) PluginInstall(ctx context.Context, p *Plugin, data []byte) error {
+	return h.h(ctx, &Call{Op: "PluginInstall", Arg: p, Write: true}, func(ctx context.Context) error {
+		return h.next.PluginInstall(ctx, p, data)
+	})
+}
+
+func (h *hooked) PermalinkSet(ctx context.Context, pl *Permalink) error {
+	return h.h(ctx, &Call{Op: "PermalinkSet", Arg: pl, Write: true}, func(ctx context.Context) error {
+		return h.next.PermalinkSet(ctx, pl)
+	})
+}
+
+func (h *hooked) PermalinkGet(ctx context.Context) (*Permalink, error) {
+	var data *Permalink
+	err := h.h(ctx, &Call{Op: "PermalinkGet", Arg: nil, Write: false}, func(ctx context.Context) (err error) {
+		data, err = h.next.PermalinkGet(ctx)
+		return err
+	})
+	return data, err
+}
@@ -0,0 +1,76 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type countAPI struct {
+	UnsupportedAPI
+	calls int
+	err   []error
+}
+
+func (c *countAPI) Init(context.Context) error                      { return nil }
+func (c *countAPI) SiteSetting(context.Context, *SiteSetting) error { return nil }
+func (c *countAPI) ArticleDel(context.Context, *Article) error      { return nil }
+func (c *countAPI) CategoryGet(context.Context, *Category) error    { return nil }
+func (c *countAPI) CategoryNew(context.Context, *Category) error    { return nil }
+func (c *countAPI) CategoryDel(context.Context, *Category) error    { return nil }
+func (c *countAPI) NavbarNew(context.Context, *Navbar) error        { return nil }
+func (c *countAPI) TagNew(context.Context, *Tag) error              { return nil }
+func (c *countAPI) TagGet(context.Context, *Tag) error              { return nil }
+func (c *countAPI) TagDel(context.Context, *Tag) error              { return nil }
+func (c *countAPI) ArticleGet(ctx context.Context, a *Article) error {
+	return c.ArticleNew(ctx, a)
+}
+
+func (c *countAPI) ArticleNew(_ context.Context, a *Article) error {
+	c.calls++
+	if len(c.err) > 0 {
+		err := c.err[0]
+		c.err = c.err[1:]
+		return err
+	}
+	a.ID = "1"
+	return nil
+}
+
+func TestChain(t *testing.T) {
+	api := &countAPI{err: []error{&Error{Status: 502}, &Error{Status: 503}}}
+	var ops []string
+	chained := Chain(api, Retry(3, time.Millisecond), Use(func(ctx context.Context, c *Call, next func(context.Context) error) error {
+		ops = append(ops, c.Op)
+		return next(ctx)
+	}))
+	a := &Article{}
+	if err := chained.ArticleGet(context.Background(), a); err != nil || a.ID != "1" || api.calls != 3 || len(ops) != 3 {
+		t.Fatal(err, a.ID, api.calls, ops)
+	}
+	api.err = []error{ArticleGetErr}
+	if err := chained.ArticleGet(context.Background(), a); !errors.Is(err, ArticleGetErr) || api.calls != 4 {
+		t.Fatal(err, api.calls)
+	}
+	// 修改操作不重试
+	api.err = []error{&Error{Status: 502}}
+	if err := chained.ArticleNew(context.Background(), a); !IsRetryable(err) || api.calls != 5 {
+		t.Fatal(err, api.calls)
+	}
+	if Unwrap(chained) != api {
+		t.Fatal("unwrap")
+	}
+	dry := Chain(api, DryRun(""))
+	if err := dry.ArticleNew(context.Background(), &Article{}); err != nil || api.calls != 5 {
+		t.Fatal(err, api.calls)
+	}
+	skipped := false
+	if err := dry.ArticleNew(WithSkipped(context.Background(), &skipped), &Article{}); err != nil || !skipped {
+		t.Fatal(err, skipped)
+	}
+	skipped = false
+	if _, _, err := dry.ArticleList(WithSkipped(context.Background(), &skipped), nil); skipped {
+		t.Fatal(err, skipped)
+	}
+}
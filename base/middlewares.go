@@ -0,0 +1,164 @@
+package base
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// Logging 记录失败的调用 verbose 为true时记录所有调用
+func Logging(prefix string, verbose bool) Middleware {
+	return Use(func(ctx context.Context, c *Call, next func(context.Context) error) error {
+		err := next(ctx)
+		if err != nil {
+			log.Printf("%s%s Error: %v", prefix, c.Op, err)
+		} else if verbose {
+			log.Printf("%s%s", prefix, c.Op)
+		}
+		return err
+	})
+}
+
+// Timing 统计每次调用的耗时
+func Timing(observe func(op string, d time.Duration, err error)) Middleware {
+	return Use(func(ctx context.Context, c *Call, next func(context.Context) error) error {
+		start := time.Now()
+		err := next(ctx)
+		observe(c.Op, time.Since(start), err)
+		return err
+	})
+}
+
+// Retry 读取操作失败且错误可重试时重试 最多执行 attempts 次，间隔从 backoff 开始逐次翻倍
+// 修改站点数据的调用不重试：超时或服务端错误时站点可能已经保存，重试会重复新建文章、用户、评论或附件
+func Retry(attempts int, backoff time.Duration) Middleware {
+	return Use(func(ctx context.Context, c *Call, next func(context.Context) error) error {
+		if c.Write {
+			return next(ctx)
+		}
+		wait := backoff
+		for i := 1; ; i++ {
+			err := next(ctx)
+			if err == nil || i >= attempts || !IsRetryable(err) {
+				return err
+			}
+			if err = sleep(ctx, wait); err != nil {
+				return err
+			}
+			wait *= 2
+		}
+	})
+}
+
+// RateLimit 限制调用频率 相邻两次调用至少间隔 interval，同一个 Middleware 包装的接口共用限制
+func RateLimit(interval time.Duration) Middleware {
+	var (
+		mu   sync.Mutex
+		next time.Time
+	)
+	return Use(func(ctx context.Context, c *Call, call func(context.Context) error) error {
+		mu.Lock()
+		now := time.Now()
+		if next.Before(now) {
+			next = now
+		}
+		wait := next.Sub(now)
+		next = next.Add(interval)
+		mu.Unlock()
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+		return call(ctx)
+	})
+}
+
+// DryRun 只执行读取操作 修改站点数据的调用被跳过并视为成功
+// 调用方需要区分跳过与真正的成功时，以 WithSkipped 传入上下文
+func DryRun(prefix string) Middleware {
+	return Use(func(ctx context.Context, c *Call, next func(context.Context) error) error {
+		if !c.Write {
+			return next(ctx)
+		}
+		log.Printf("%s[dry-run] 跳过 %s", prefix, c.Op)
+		if skipped, ok := ctx.Value(skippedKey{}).(*bool); ok {
+			*skipped = true
+		}
+		return ctx.Err()
+	})
+}
+
+type skippedKey struct{}
+
+// WithSkipped 经返回的上下文发起的修改调用被 DryRun 跳过时 *skipped 置为 true
+// 用于跳过依赖修改结果的后续操作，如文章未真正入库时不再上传图片
+func WithSkipped(ctx context.Context, skipped *bool) context.Context {
+	return context.WithValue(ctx, skippedKey{}, skipped)
+}
+
+// AuditRecord 审计记录
+type AuditRecord struct {
+	Time     time.Time     `json:"time"`
+	Site     string        `json:"site"`
+	Op       string        `json:"op"`
+	Arg      interface{}   `json:"arg,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Audit 记录修改站点数据的调用
+func Audit(site string, record func(AuditRecord)) Middleware {
+	return Use(func(ctx context.Context, c *Call, next func(context.Context) error) error {
+		if !c.Write {
+			return next(ctx)
+		}
+		start := time.Now()
+		err := next(ctx)
+		r := AuditRecord{Time: start, Site: site, Op: c.Op, Arg: redact(c.Arg), Duration: time.Since(start)}
+		if err != nil {
+			r.Error = err.Error()
+		}
+		record(r)
+		return err
+	})
+}
+
+// redact 去除参数中的密码
+func redact(arg interface{}) interface{} {
+	if m, ok := arg.(*Member); ok && m != nil && m.Password != "" {
+		cp := *m
+		cp.Password = "******"
+		return &cp
+	}
+	return arg
+}
+
+// AuditWriter 以每行一个JSON的形式写入审计记录
+func AuditWriter(w io.Writer) func(AuditRecord) {
+	var mu sync.Mutex
+	return func(r AuditRecord) {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = w.Write(append(data, '\n'))
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
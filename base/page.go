@@ -0,0 +1,13 @@
+package base
+
+import "errors"
+
+var PageNewErr = errors.New("新建页面失败")
+var PageGetErr = errors.New("获取页面失败")
+var PageDelErr = errors.New("删除页面失败")
+
+// Union.Type 的取值
+const (
+	TypeArticle = "0" // 文章
+	TypePage    = "1" // 独立页面
+)
@@ -0,0 +1,88 @@
+package base
+
+import (
+	"errors"
+	"strings"
+)
+
+var PermalinkSetErr = errors.New("设置链接结构失败")
+
+// Permalink 链接结构
+// 可用变量 {%host%} {%id%} {%alias%} {%page%} {%date%}，首页时 {%page%} 及其前面的 _ 会被省略
+type Permalink struct {
+	Article  string `json:"article"`  // 文章
+	Page     string `json:"page"`     // 独立页面
+	Index    string `json:"index"`    // 首页分页
+	Category string `json:"category"` // 分类
+	Tag      string `json:"tag"`      // 标签
+	Date     string `json:"date"`     // 日期归档
+	Author   string `json:"author"`   // 作者
+}
+
+// DefaultPermalink 默认链接结构 与此前初始化站点时写入的伪静态规则一致
+// 作者页沿用原有的日期规则，需要独立的作者页时设置 Permalink.Author，如 {%host%}author-{%id%}_{%page%}.html
+var DefaultPermalink = Permalink{
+	Article:  "{%host%}post/{%id%}.html",
+	Page:     "{%host%}{%id%}.html",
+	Index:    "{%host%}page_{%page%}.html",
+	Category: "{%host%}category-{%id%}_{%page%}.html",
+	Tag:      "{%host%}tags-{%alias%}_{%page%}.html",
+	Date:     "{%host%}date-{%date%}_{%page%}.html",
+	Author:   "{%host%}date-{%date%}_{%page%}.html",
+}
+
+// Fill 用默认值补全未设置的项
+func (p *Permalink) Fill() {
+	if p.Article == "" {
+		p.Article = DefaultPermalink.Article
+	}
+	if p.Page == "" {
+		p.Page = DefaultPermalink.Page
+	}
+	if p.Index == "" {
+		p.Index = DefaultPermalink.Index
+	}
+	if p.Category == "" {
+		p.Category = DefaultPermalink.Category
+	}
+	if p.Tag == "" {
+		p.Tag = DefaultPermalink.Tag
+	}
+	if p.Date == "" {
+		p.Date = DefaultPermalink.Date
+	}
+	if p.Author == "" {
+		p.Author = DefaultPermalink.Author
+	}
+}
+
+// Resolve 返回补全后的副本 p 为 nil 时返回默认链接结构
+func (p *Permalink) Resolve() *Permalink {
+	pl := DefaultPermalink
+	if p != nil {
+		pl = *p
+		pl.Fill()
+	}
+	return &pl
+}
+
+// BuildLink 按链接结构生成首页的链接 host 以 / 结尾，为 / 时生成站内相对链接
+func BuildLink(pattern, host, id, alias string) string {
+	return strings.NewReplacer(
+		"{%host%}", host,
+		"{%id%}", id,
+		"{%alias%}", alias,
+		"_{%page%}", "",
+		"{%page%}", "",
+	).Replace(pattern)
+}
+
+// ArticleURL 文章链接
+func (p *Permalink) ArticleURL(host, id, alias string) string {
+	return BuildLink(p.Article, host, id, alias)
+}
+
+// TagURL 标签链接
+func (p *Permalink) TagURL(host, id, alias string) string {
+	return BuildLink(p.Tag, host, id, alias)
+}
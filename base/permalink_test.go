@@ -0,0 +1,25 @@
+package base
+
+import "testing"
+
+func TestPermalink_TagURL(t *testing.T) {
+	var pl *Permalink
+	if u := pl.Resolve().TagURL("/", "", "jingdong"); u != "/tags-jingdong.html" {
+		t.Fatal(u)
+	}
+	pl = &Permalink{Tag: "{%host%}tag/{%alias%}/{%page%}"}
+	if u := pl.Resolve().TagURL("http://a.com/", "3", "jd"); u != "http://a.com/tag/jd/" {
+		t.Fatal(u)
+	}
+	if u := pl.Resolve().ArticleURL("/", "12", ""); u != "/post/12.html" {
+		t.Fatal(u)
+	}
+	// 作者页默认沿用原有规则 只有显式设置时才改变
+	if DefaultPermalink.Author != "{%host%}date-{%date%}_{%page%}.html" {
+		t.Fatal(DefaultPermalink.Author)
+	}
+	pl = &Permalink{Author: "{%host%}author-{%id%}_{%page%}.html"}
+	if a := pl.Resolve().Author; a != "{%host%}author-{%id%}_{%page%}.html" {
+		t.Fatal(a)
+	}
+}
@@ -0,0 +1,38 @@
+package base
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+var PluginEnableErr = errors.New("启用插件失败")
+var PluginDisableErr = errors.New("停用插件失败")
+var PluginInstallErr = errors.New("安装插件失败")
+var PluginUndefinedErr = errors.New("插件未安装")
+
+// Plugin 插件
+type Plugin struct {
+	ID      string `json:"id"`      // 标识 如 STACentre
+	Name    string `json:"name"`    // 名称
+	Version string `json:"version"` // 版本
+	Enabled bool   `json:"enabled"` // 是否启用
+}
+
+// PluginError 必备插件处理失败 Failed 以插件标识为键
+type PluginError struct {
+	Failed map[string]error
+}
+
+func (e *PluginError) Error() string {
+	ids := make([]string, 0, len(e.Failed))
+	for id := range e.Failed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	msg := make([]string, 0, len(ids))
+	for _, id := range ids {
+		msg = append(msg, id+"("+e.Failed[id].Error()+")")
+	}
+	return "插件处理失败: " + strings.Join(msg, ", ")
+}
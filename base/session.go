@@ -0,0 +1,165 @@
+package base
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var SessionExpiredErr = errors.New("会话已过期")
+
+// Session 可持久化的登录会话
+type Session struct {
+	Program    string            `json:"program"`     // 程序名称
+	Site       string            `json:"site"`        // 站点 ProgramBaseInfo.HomeURL
+	Username   string            `json:"username"`    // 登录用户
+	Cookies    []*http.Cookie    `json:"cookies"`     // 登录后的cookie
+	CSRF       string            `json:"csrf"`        // CSRF
+	CSRFExpire time.Time         `json:"csrf_expire"` // CSRF过期时间
+	Expire     time.Time         `json:"expire"`      // 会话过期时间
+	Data       map[string]string `json:"data"`        // 程序自定义数据
+}
+
+// Expired 会话是否已过期
+func (s *Session) Expired() bool {
+	return !s.Expire.IsZero() && time.Now().After(s.Expire)
+}
+
+// SessionAPI 支持会话保存与恢复的程序
+type SessionAPI interface {
+
+	// Session 导出当前会话
+	Session() *Session
+
+	// Probe 以较小的开销检查会话是否仍然有效
+	Probe(context.Context) error
+}
+
+// ReloginNotifier 会话失效后自动重新登录的程序 fn 在每次重新登录成功后调用
+type ReloginNotifier interface {
+	OnRelogin(fn func())
+}
+
+// ResumeFunc 以保存的会话恢复登录 不发起登录请求，用户名密码用于会话失效后重新登录
+type ResumeFunc func(ctx context.Context, s *Session, u, p string, info ProgramBaseInfo) (ProgramAPIContext, error)
+
+var resumeList = make(map[string]ResumeFunc)
+var rlMutex = &sync.Mutex{}
+
+// RegisterResume 注册恢复会话的函数
+func RegisterResume(name string, f ResumeFunc) {
+	rlMutex.Lock()
+	defer rlMutex.Unlock()
+	resumeList[name] = f
+}
+
+// GetResume 获取恢复会话的函数
+func GetResume(name string) ResumeFunc {
+	rlMutex.Lock()
+	defer rlMutex.Unlock()
+	return resumeList[name]
+}
+
+// SessionStore 会话存储 以站点为键
+type SessionStore interface {
+	Load(key string) (*Session, error)
+	Save(key string, s *Session) error
+	Delete(key string) error
+}
+
+// FileSessionStore 以 JSON 文件保存会话，每个站点一个文件
+type FileSessionStore struct {
+	Dir string
+}
+
+func (f *FileSessionStore) path(key string) string {
+	return filepath.Join(f.Dir, url.QueryEscape(key)+".json")
+}
+
+func (f *FileSessionStore) Load(key string) (*Session, error) {
+	data, err := ioutil.ReadFile(f.path(key))
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{}
+	if err = json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (f *FileSessionStore) Save(key string, s *Session) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(f.Dir, 0700); err != nil {
+		return err
+	}
+	tmp := f.path(key) + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path(key))
+}
+
+func (f *FileSessionStore) Delete(key string) error {
+	err := os.Remove(f.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// LoginWithStore 优先恢复保存的会话，会话不存在、已过期或检查无效时重新登录并保存
+// 程序实现了 ReloginNotifier 时，之后会话失效自动重新登录得到的新会话也会保存
+// store 为 nil 时等同于直接登录
+func LoginWithStore(ctx context.Context, name string, store SessionStore, u, p string, info ProgramBaseInfo) (ProgramAPIContext, error) {
+	login := GetProgramContext(name)
+	if login == nil {
+		return nil, errors.New("program not undefined: " + name)
+	}
+	if store == nil {
+		return login(ctx, u, p, info)
+	}
+	key := info.HomeURL
+	save := func(api ProgramAPIContext) {
+		if sa, ok := api.(SessionAPI); ok {
+			s := sa.Session()
+			s.Program = name
+			s.Username = u
+			_ = store.Save(key, s)
+		}
+	}
+	watch := func(api ProgramAPIContext) {
+		if n, ok := api.(ReloginNotifier); ok {
+			n.OnRelogin(func() {
+				save(api)
+			})
+		}
+	}
+	if resume := GetResume(name); resume != nil {
+		if s, err := store.Load(key); err == nil && s.Program == name && s.Username == u && !s.Expired() {
+			if api, err := resume(ctx, s, u, p, info); err == nil {
+				if sa, ok := api.(SessionAPI); ok && sa.Probe(ctx) == nil {
+					watch(api)
+					return api, nil
+				}
+			}
+		}
+	}
+	api, err := login(ctx, u, p, info)
+	if err != nil {
+		return nil, err
+	}
+	save(api)
+	watch(api)
+	return api, nil
+}
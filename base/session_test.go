@@ -0,0 +1,37 @@
+package base
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFileSessionStore(t *testing.T) {
+	store := &FileSessionStore{Dir: t.TempDir()}
+	key := "http://blog.isolezvoscombles.com/"
+	if _, err := store.Load(key); err == nil {
+		t.Fatal("expected error for missing session")
+	}
+	s := &Session{
+		Program: "z-blog",
+		Site:    key,
+		Cookies: []*http.Cookie{{Name: "username", Value: "admin"}},
+		Expire:  time.Now().Add(time.Hour),
+	}
+	if err := store.Save(key, s); err != nil {
+		t.Fatal(err)
+	}
+	v, err := store.Load(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Program != "z-blog" || len(v.Cookies) != 1 || v.Cookies[0].Value != "admin" || v.Expired() {
+		t.Fatal(v)
+	}
+	if err = store.Delete(key); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Delete(key); err != nil {
+		t.Fatal(err)
+	}
+}
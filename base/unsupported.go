@@ -0,0 +1,114 @@
+package base
+
+import "context"
+
+// UnsupportedAPI 可嵌入适配器中，为未实现的扩展方法返回 UnsupportedErr
+type UnsupportedAPI struct{}
+
+func (UnsupportedAPI) ArticleList(context.Context, *ListOption) ([]Article, int, error) {
+	return nil, 0, UnsupportedErr
+}
+
+func (UnsupportedAPI) CategoryList(context.Context, *ListOption) ([]Category, int, error) {
+	return nil, 0, UnsupportedErr
+}
+
+func (UnsupportedAPI) TagList(context.Context, *ListOption) ([]Tag, int, error) {
+	return nil, 0, UnsupportedErr
+}
+
+func (UnsupportedAPI) CommentList(context.Context, *ListOption) ([]Comment, int, error) {
+	return nil, 0, UnsupportedErr
+}
+
+func (UnsupportedAPI) CommentApprove(context.Context, *Comment) error {
+	return UnsupportedErr
+}
+
+func (UnsupportedAPI) CommentUnapprove(context.Context, *Comment) error {
+	return UnsupportedErr
+}
+
+func (UnsupportedAPI) CommentReply(context.Context, *Comment, *Comment) error {
+	return UnsupportedErr
+}
+
+func (UnsupportedAPI) CommentDel(context.Context, *Comment) error {
+	return UnsupportedErr
+}
+
+func (UnsupportedAPI) PageNew(context.Context, *Article) error {
+	return UnsupportedErr
+}
+
+func (UnsupportedAPI) PageGet(context.Context, *Article) error {
+	return UnsupportedErr
+}
+
+func (UnsupportedAPI) PageList(context.Context, *ListOption) ([]Article, int, error) {
+	return nil, 0, UnsupportedErr
+}
+
+func (UnsupportedAPI) PageDel(context.Context, *Article) error {
+	return UnsupportedErr
+}
+
+func (UnsupportedAPI) MemberList(context.Context, *ListOption) ([]Member, int, error) {
+	return nil, 0, UnsupportedErr
+}
+
+func (UnsupportedAPI) MemberGet(context.Context, *Member) error {
+	return UnsupportedErr
+}
+
+func (UnsupportedAPI) MemberNew(context.Context, *Member) error {
+	return UnsupportedErr
+}
+
+func (UnsupportedAPI) MemberPassword(context.Context, *Member, string) error {
+	return UnsupportedErr
+}
+
+func (UnsupportedAPI) MemberRole(context.Context, *Member, string) error {
+	return UnsupportedErr
+}
+
+func (UnsupportedAPI) MemberDel(context.Context, *Member) error {
+	return UnsupportedErr
+}
+
+func (UnsupportedAPI) MediaUpload(context.Context, *Media) error {
+	return UnsupportedErr
+}
+
+func (UnsupportedAPI) MediaDel(context.Context, *Media) error {
+	return UnsupportedErr
+}
+
+func (UnsupportedAPI) PluginList(context.Context) ([]Plugin, error) {
+	return nil, UnsupportedErr
+}
+
+func (UnsupportedAPI) PluginEnable(context.Context, *Plugin) error {
+	return UnsupportedErr
+}
+
+func (UnsupportedAPI) PluginDisable(context.Context, *Plugin) error {
+	return UnsupportedErr
+}
+
+func (UnsupportedAPI) PluginInstall(context.Context, *Plugin, []byte) error {
+	return UnsupportedErr
+}
+
+func (UnsupportedAPI) PermalinkSet(context.Context, *Permalink) error {
+	return UnsupportedErr
+}
+
+func (UnsupportedAPI) PermalinkGet(context.Context) (*Permalink, error) {
+	return nil, UnsupportedErr
+}
+
+func (UnsupportedAPI) SiteSettingGet(context.Context, *SiteSetting) error {
+	return UnsupportedErr
+}
@@ -0,0 +1,384 @@
+// Package xmlrpc XML-RPC 的编码与解码
+//
+// 编码支持 nil int int64 bool string float64 time.Time []byte Struct Array []string []int；
+// 解码得到的值为 int bool string float64 time.Time []byte Struct Array nil。
+package xmlrpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Struct 结构体
+type Struct = map[string]interface{}
+
+// Array 数组
+type Array = []interface{}
+
+// Fault 服务端返回的错误
+type Fault struct {
+	Code   int
+	String string
+}
+
+func (f *Fault) Error() string {
+	return fmt.Sprintf("xmlrpc fault %d: %s", f.Code, f.String)
+}
+
+var InvalidResponseErr = errors.New("无效的XML-RPC响应")
+
+// TimeLayout 编码时间使用的格式 以UTC表示
+const TimeLayout = "20060102T15:04:05Z"
+
+// timeLayouts 解码时尝试的时间格式 不带时区时按本地时间解析
+var timeLayouts = []string{
+	"20060102T15:04:05Z07:00",
+	"20060102T15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05",
+	"20060102T150405Z07:00",
+	"20060102T150405",
+}
+
+// EncodeCall 编码方法调用
+func EncodeCall(method string, params ...interface{}) ([]byte, error) {
+	b := &bytes.Buffer{}
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?><methodCall><methodName>`)
+	_ = xml.EscapeText(b, []byte(method))
+	b.WriteString(`</methodName><params>`)
+	for _, p := range params {
+		b.WriteString(`<param>`)
+		if err := encodeValue(b, p); err != nil {
+			return nil, err
+		}
+		b.WriteString(`</param>`)
+	}
+	b.WriteString(`</params></methodCall>`)
+	return b.Bytes(), nil
+}
+
+func encodeValue(b *bytes.Buffer, v interface{}) error {
+	switch x := v.(type) {
+	case []string:
+		a := make(Array, 0, len(x))
+		for _, s := range x {
+			a = append(a, s)
+		}
+		v = a
+	case []int:
+		a := make(Array, 0, len(x))
+		for _, n := range x {
+			a = append(a, n)
+		}
+		v = a
+	}
+	b.WriteString(`<value>`)
+	switch x := v.(type) {
+	case nil:
+		b.WriteString(`<nil/>`)
+	case int:
+		b.WriteString(`<int>` + strconv.Itoa(x) + `</int>`)
+	case int64:
+		b.WriteString(`<int>` + strconv.FormatInt(x, 10) + `</int>`)
+	case bool:
+		if x {
+			b.WriteString(`<boolean>1</boolean>`)
+		} else {
+			b.WriteString(`<boolean>0</boolean>`)
+		}
+	case string:
+		b.WriteString(`<string>`)
+		_ = xml.EscapeText(b, []byte(x))
+		b.WriteString(`</string>`)
+	case float64:
+		b.WriteString(`<double>` + strconv.FormatFloat(x, 'f', -1, 64) + `</double>`)
+	case time.Time:
+		b.WriteString(`<dateTime.iso8601>` + x.UTC().Format(TimeLayout) + `</dateTime.iso8601>`)
+	case []byte:
+		b.WriteString(`<base64>` + base64.StdEncoding.EncodeToString(x) + `</base64>`)
+	case Struct:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b.WriteString(`<struct>`)
+		for _, k := range keys {
+			b.WriteString(`<member><name>`)
+			_ = xml.EscapeText(b, []byte(k))
+			b.WriteString(`</name>`)
+			if err := encodeValue(b, x[k]); err != nil {
+				return err
+			}
+			b.WriteString(`</member>`)
+		}
+		b.WriteString(`</struct>`)
+	case Array:
+		b.WriteString(`<array><data>`)
+		for _, m := range x {
+			if err := encodeValue(b, m); err != nil {
+				return err
+			}
+		}
+		b.WriteString(`</data></array>`)
+	default:
+		return fmt.Errorf("xmlrpc: 不支持的类型 %T", v)
+	}
+	b.WriteString(`</value>`)
+	return nil
+}
+
+// DecodeResponse 解码方法响应 服务端返回错误时 error 为 *Fault
+func DecodeResponse(r io.Reader) (interface{}, error) {
+	p := &parser{d: xml.NewDecoder(r)}
+	p.d.Strict = false
+	p.d.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	var fault bool
+	for {
+		tok, err := p.d.Token()
+		if err != nil {
+			if err == io.EOF {
+				return nil, InvalidResponseErr
+			}
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "fault":
+			fault = true
+		case "value":
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			if !fault {
+				return v, nil
+			}
+			s, _ := v.(Struct)
+			f := &Fault{}
+			f.Code, _ = s["faultCode"].(int)
+			f.String, _ = s["faultString"].(string)
+			return nil, f
+		}
+	}
+}
+
+// DecodeCall 解码方法调用 用于实现服务端
+func DecodeCall(r io.Reader) (string, Array, error) {
+	p := &parser{d: xml.NewDecoder(r)}
+	p.d.Strict = false
+	var method string
+	params := make(Array, 0)
+	for {
+		tok, err := p.d.Token()
+		if err == io.EOF {
+			return method, params, nil
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "methodName":
+			if method, err = p.text(); err != nil {
+				return "", nil, err
+			}
+		case "value":
+			v, err := p.value()
+			if err != nil {
+				return "", nil, err
+			}
+			params = append(params, v)
+		}
+	}
+}
+
+// EncodeResponse 编码方法响应 v 为 *Fault 时编码为错误
+func EncodeResponse(v interface{}) ([]byte, error) {
+	b := &bytes.Buffer{}
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?><methodResponse>`)
+	if f, ok := v.(*Fault); ok {
+		b.WriteString(`<fault>`)
+		if err := encodeValue(b, Struct{"faultCode": f.Code, "faultString": f.String}); err != nil {
+			return nil, err
+		}
+		b.WriteString(`</fault>`)
+	} else {
+		b.WriteString(`<params><param>`)
+		if err := encodeValue(b, v); err != nil {
+			return nil, err
+		}
+		b.WriteString(`</param></params>`)
+	}
+	b.WriteString(`</methodResponse>`)
+	return b.Bytes(), nil
+}
+
+type parser struct {
+	d *xml.Decoder
+}
+
+// text 读取当前元素的文本 直到元素结束
+func (p *parser) text() (string, error) {
+	b := &strings.Builder{}
+	depth := 0
+	for {
+		tok, err := p.d.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			b.Write(t)
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			if depth == 0 {
+				return b.String(), nil
+			}
+			depth--
+		}
+	}
+}
+
+// value 解析 <value> 的内容 调用时 <value> 已读取
+func (p *parser) value() (interface{}, error) {
+	b := &strings.Builder{}
+	for {
+		tok, err := p.d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			b.Write(t)
+		case xml.StartElement:
+			v, err := p.typed(t.Name.Local)
+			if err != nil {
+				return nil, err
+			}
+			// 跳过类型元素之后的空白直到 </value>
+			if _, err = p.text(); err != nil {
+				return nil, err
+			}
+			return v, nil
+		case xml.EndElement:
+			// 未指定类型时为字符串
+			return b.String(), nil
+		}
+	}
+}
+
+// typed 解析类型元素 调用时类型元素的开始标签已读取
+func (p *parser) typed(name string) (interface{}, error) {
+	switch name {
+	case "struct":
+		return p.structValue()
+	case "array":
+		return p.arrayValue()
+	case "nil":
+		_, err := p.text()
+		return nil, err
+	}
+	s, err := p.text()
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case "int", "i4", "i8":
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("xmlrpc: %s %q: %w", name, s, err)
+		}
+		return n, nil
+	case "boolean":
+		s = strings.TrimSpace(s)
+		return s == "1" || s == "true", nil
+	case "double":
+		return strconv.ParseFloat(strings.TrimSpace(s), 64)
+	case "dateTime.iso8601":
+		return ParseTime(s)
+	case "base64":
+		return base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	}
+	return s, nil
+}
+
+func (p *parser) structValue() (Struct, error) {
+	s := make(Struct)
+	var name string
+	for {
+		tok, err := p.d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "name":
+				if name, err = p.text(); err != nil {
+					return nil, err
+				}
+			case "value":
+				if s[name], err = p.value(); err != nil {
+					return nil, err
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local == "struct" {
+				return s, nil
+			}
+		}
+	}
+}
+
+func (p *parser) arrayValue() (Array, error) {
+	a := make(Array, 0)
+	for {
+		tok, err := p.d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "value" {
+				v, err := p.value()
+				if err != nil {
+					return nil, err
+				}
+				a = append(a, v)
+			}
+		case xml.EndElement:
+			if t.Name.Local == "array" {
+				return a, nil
+			}
+		}
+	}
+}
+
+// ParseTime 解析 dateTime.iso8601 不带时区时按本地时间解析
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("xmlrpc: dateTime.iso8601 %q", s)
+}
@@ -0,0 +1,95 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	now := time.Date(2022, 5, 1, 8, 30, 0, 0, time.UTC)
+	params := Array{
+		1, "a<b&c", true, 1.5, now, []byte("bits"), nil,
+		Struct{"title": "标题", "tags": []string{"x", "y"}, "ids": []int{1, 2}},
+	}
+	body, err := EncodeCall("metaWeblog.newPost", params...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	method, got, err := DecodeCall(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method != "metaWeblog.newPost" {
+		t.Fatalf("method %q", method)
+	}
+	want := Array{
+		1, "a<b&c", true, 1.5, time.Date(2022, 5, 1, 8, 30, 0, 0, time.UTC), []byte("bits"), nil,
+		Struct{"title": "标题", "tags": Array{"x", "y"}, "ids": Array{1, 2}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("参数数量 %d", len(got))
+	}
+	for i := range want {
+		if tm, ok := want[i].(time.Time); ok {
+			if g, _ := got[i].(time.Time); !g.Equal(tm) {
+				t.Fatalf("参数%d %v", i, got[i])
+			}
+			continue
+		}
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Fatalf("参数%d %#v 期望 %#v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDecodeResponse(t *testing.T) {
+	body, err := EncodeResponse(Array{Struct{"blogid": "1", "isAdmin": true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := DecodeResponse(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, Array{Struct{"blogid": "1", "isAdmin": true}}) {
+		t.Fatalf("%#v", v)
+	}
+	// 未指定类型的值为字符串 且允许空白
+	v, err = DecodeResponse(strings.NewReader("<methodResponse>\n<params><param>\n<value>plain</value>\n</param></params></methodResponse>"))
+	if err != nil || v != "plain" {
+		t.Fatalf("%#v %v", v, err)
+	}
+	if _, err = DecodeResponse(strings.NewReader("<html></html>")); !errors.Is(err, InvalidResponseErr) {
+		t.Fatalf("期望 InvalidResponseErr 得到 %v", err)
+	}
+}
+
+func TestDecodeFault(t *testing.T) {
+	body, err := EncodeResponse(&Fault{Code: 403, String: "Incorrect username or password."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = DecodeResponse(bytes.NewReader(body))
+	var f *Fault
+	if !errors.As(err, &f) || f.Code != 403 || f.String != "Incorrect username or password." {
+		t.Fatalf("%v", err)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tm, err := ParseTime("20220501T08:30:00Z")
+	if err != nil || !tm.Equal(time.Date(2022, 5, 1, 8, 30, 0, 0, time.UTC)) {
+		t.Fatalf("%v %v", tm, err)
+	}
+	tm, err = ParseTime("20220501T08:30:00")
+	if err != nil || tm.Location() != time.Local || tm.Hour() != 8 {
+		t.Fatalf("不带时区应按本地时间解析 %v %v", tm, err)
+	}
+	if _, err = ParseTime("yesterday"); err == nil {
+		t.Fatal("期望错误")
+	}
+}
@@ -0,0 +1,81 @@
+// detect 根据站点列表中的 home_url 识别程序 填充 program_name backstage_path login_path
+//
+//	go run ./cmd/detect -i site.json -o site.json
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"github.com/cgghui/bt_site_cluster_program_api/core"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	input := flag.String("i", "", "站点列表文件")
+	output := flag.String("o", "", "输出文件 为空时输出到标准输出")
+	force := flag.Bool("force", false, "覆盖已配置的程序信息")
+	workers := flag.Int("n", 10, "并发数")
+	flag.Parse()
+	if *input == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	data, err := ioutil.ReadFile(*input)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// raw 保留文件中 SiteConfig 未声明的字段
+	var raw []map[string]json.RawMessage
+	if err = json.Unmarshal(data, &raw); err != nil {
+		log.Fatal(err)
+	}
+	var sites []core.SiteConfig
+	if err = json.Unmarshal(data, &sites); err != nil {
+		log.Fatal(err)
+	}
+	before := make([]map[string]json.RawMessage, len(sites))
+	for i := range sites {
+		before[i] = fields(&sites[i])
+	}
+	for i, r := range core.DetectSites(ctx, sites, *workers, *force) {
+		if r.Err != nil {
+			log.Printf("【%s】识别失败 Error: %v", sites[i].HomeURL, r.Err)
+			continue
+		}
+		log.Printf("【%s】%s %s", sites[i].HomeURL, r.Detection.Program, r.Detection.Version)
+		// 只写回识别后发生变化的字段
+		for k, v := range fields(&sites[i]) {
+			if !bytes.Equal(before[i][k], v) {
+				raw[i][k] = v
+			}
+		}
+	}
+	if data, err = json.MarshalIndent(raw, "", "  "); err != nil {
+		log.Fatal(err)
+	}
+	if *output == "" {
+		_, _ = os.Stdout.Write(append(data, '\n'))
+		return
+	}
+	if err = ioutil.WriteFile(*output, append(data, '\n'), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func fields(s *core.SiteConfig) map[string]json.RawMessage {
+	m := make(map[string]json.RawMessage)
+	if data, err := json.Marshal(s); err == nil {
+		_ = json.Unmarshal(data, &m)
+	}
+	return m
+}
@@ -0,0 +1,79 @@
+// rotate 修改站点列表中各站点的后台密码 每个站点修改成功后立即写回 login_password
+//
+//	go run ./cmd/rotate -i site.json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"github.com/cgghui/bt_site_cluster_program_api/core"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	input := flag.String("i", "", "站点列表文件 修改后的密码写回该文件")
+	length := flag.Int("l", 16, "密码长度")
+	flag.Parse()
+	if *input == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	data, err := ioutil.ReadFile(*input)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// raw 保留文件中 SiteConfig 未声明的字段
+	var raw []map[string]json.RawMessage
+	if err = json.Unmarshal(data, &raw); err != nil {
+		log.Fatal(err)
+	}
+	var sites []core.SiteConfig
+	if err = json.Unmarshal(data, &sites); err != nil {
+		log.Fatal(err)
+	}
+	index := make(map[*core.SiteConfig]int, len(sites))
+	list := make([]*core.SiteConfig, 0, len(sites))
+	for i := range sites {
+		index[&sites[i]] = i
+		list = append(list, &sites[i])
+	}
+	save := func(s *core.SiteConfig) error {
+		v, err := json.Marshal(s.Password)
+		if err != nil {
+			return err
+		}
+		raw[index[s]]["login_password"] = v
+		return write(*input, raw)
+	}
+	gen := func(*core.SiteConfig) string {
+		return core.RandomPassword(*length)
+	}
+	failed := core.RotatePasswords(ctx, list, gen, save)
+	for s, err := range failed {
+		log.Printf("【%s】修改密码失败 Error: %v", s.HomeURL, err)
+	}
+	if len(failed) > 0 {
+		os.Exit(1)
+	}
+}
+
+// write 先写入临时文件再替换 避免写入中断后站点列表损坏
+func write(name string, raw []map[string]json.RawMessage) error {
+	data, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(name+".tmp", append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(name+".tmp", name)
+}
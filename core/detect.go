@@ -0,0 +1,60 @@
+package core
+
+import (
+	"context"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"sync"
+)
+
+// Detect 根据 HomeURL 识别站点程序 填充为空的 ProgramName BackstagePath LoginPath，force 为true时覆盖已有的值
+func (s *SiteConfig) Detect(ctx context.Context, force bool) (*base.Detection, error) {
+	d, err := base.Detect(ctx, s.HomeURL)
+	if err != nil {
+		return nil, err
+	}
+	if force || s.ProgramName == "" {
+		s.ProgramName = d.Program
+	}
+	if force || s.BackstagePath == "" {
+		s.BackstagePath = d.BackstagePath
+	}
+	if force || s.LoginPath == "" {
+		s.LoginPath = d.LoginPath
+	}
+	return d, nil
+}
+
+// DetectResult 批量识别单个站点的结果
+type DetectResult struct {
+	Detection *base.Detection
+	Err       error
+}
+
+// DetectSites 并发识别站点列表 workers 为并发数 返回与 sites 下标对应的结果
+func DetectSites(ctx context.Context, sites []SiteConfig, workers int, force bool) []DetectResult {
+	if workers <= 0 {
+		workers = 1
+	}
+	result := make([]DetectResult, len(sites))
+	ch := make(chan int)
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range ch {
+				result[j].Detection, result[j].Err = sites[j].Detect(ctx, force)
+			}
+		}()
+	}
+	for i := range sites {
+		if ctx.Err() != nil {
+			result[i].Err = ctx.Err()
+			continue
+		}
+		ch <- i
+	}
+	close(ch)
+	wg.Wait()
+	return result
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"context"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/cgghui/bt_site_cluster_collect/collect"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"log"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SiteConfig.ImageUpload 的取值
+const (
+	ImageUploadBT    = "bt"    // 通过宝塔写入站点目录（默认）
+	ImageUploadMedia = "media" // 通过程序的附件上传
+)
+
+// imageStore 以宝塔方式写入站点目录的图片 测试中替换为离线实现
+type imageStore interface {
+	// login 登录宝塔 已登录时不重复登录
+	login(ctx context.Context, s *SiteConfig) error
+	// upload 将本地图片写入站点目录
+	upload(s *SiteConfig, local string)
+}
+
+var images imageStore = btStore{}
+
+// btStore 通过宝塔面板写入站点目录
+type btStore struct{}
+
+func (btStore) login(ctx context.Context, s *SiteConfig) error {
+	if s.BtO.GetLoginSession() != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 8*time.Second)
+	defer cancel()
+	ss, err := s.BtO.Login(ctx)
+	if err != nil {
+		return err
+	}
+	s.BtO.SetLoginSession(ss)
+	return nil
+}
+
+func (btStore) upload(s *SiteConfig, local string) {
+	collect.UploadImage(s.BtO.GetLoginSession(), s.SiteRootPath, local)
+}
+
+// uploadMedia 通过程序上传文章图片并将正文中的图片地址替换为附件地址
+// 返回上传失败、需要以宝塔方式上传的图片及已上传的附件，文章入库失败时以 dropMedia 删除附件
+func (s *SiteConfig) uploadMedia(ctx context.Context, api base.ProgramAPIContext, images []string, info *base.Article) ([]string, []*base.Media) {
+	fallback := make([]string, 0)
+	uploaded := make([]*base.Media, 0)
+	replace := make(map[string]string)
+	for _, local := range images {
+		m, err := base.NewMediaFile(local)
+		skipped := false
+		if err == nil {
+			err = api.MediaUpload(base.WithSkipped(ctx, &skipped), m)
+		}
+		if skipped {
+			// dry_run 未真正上传 既不改为宝塔上传也不替换图片地址
+			continue
+		}
+		if err != nil {
+			log.Printf("【%s】《%s》上传图片[%s]失败 改为宝塔上传 Error: %v", s.BindDomain[0], info.Title, local, err)
+			fallback = append(fallback, local)
+			continue
+		}
+		replace[filepath.Base(local)] = m.URL
+		uploaded = append(uploaded, m)
+	}
+	if len(replace) == 0 {
+		return fallback, uploaded
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(info.Content))
+	if err != nil {
+		s.dropMedia(ctx, api, info, uploaded)
+		return images, nil
+	}
+	doc.Find("img").Each(func(_ int, img *goquery.Selection) {
+		if u, ok := replace[path.Base(img.AttrOr("src", ""))]; ok {
+			img.SetAttr("src", u)
+		}
+	})
+	info.Content, _ = doc.Html()
+	return fallback, uploaded
+}
+
+// dropMedia 删除文章未能使用的附件 删除失败的附件记录到日志以便手动清理
+func (s *SiteConfig) dropMedia(ctx context.Context, api base.ProgramAPIContext, info *base.Article, media []*base.Media) {
+	for _, m := range media {
+		if err := api.MediaDel(ctx, m); err != nil {
+			log.Printf("【%s】《%s》删除未使用的附件[%s]失败 需手动清理 Error: %v", s.BindDomain[0], info.Title, m.URL, err)
+		}
+	}
+}
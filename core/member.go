@@ -0,0 +1,80 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"log"
+	"math/big"
+)
+
+const passwordChars = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// RandomPassword 生成随机密码
+func RandomPassword(n int) string {
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(passwordChars)))
+	for i := range b {
+		v, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			panic(err)
+		}
+		b[i] = passwordChars[v.Int64()]
+	}
+	return string(b)
+}
+
+// ErrDryRunRotate 站点启用了 dry_run 中间件 修改密码会被跳过，不能更新配置中的密码
+var ErrDryRunRotate = errors.New("站点启用了 dry_run 不能修改密码")
+
+// dryRun 是否启用了 dry_run 中间件
+func (s *SiteConfig) dryRun() bool {
+	for _, name := range s.Middleware {
+		if name == "dry_run" {
+			return true
+		}
+	}
+	return false
+}
+
+// RotatePassword 修改站点后台登录用户的密码，成功后更新 SiteConfig.Password
+// 启用 dry_run 时返回 ErrDryRunRotate，避免配置中保存站点并未修改的密码
+func (s *SiteConfig) RotatePassword(ctx context.Context, password string) error {
+	if s.dryRun() {
+		return ErrDryRunRotate
+	}
+	api, err := s.Login(ctx)
+	if err != nil {
+		return err
+	}
+	m := base.Member{Name: s.Username}
+	if err = api.MemberPassword(ctx, &m, password); err != nil {
+		return err
+	}
+	s.Password = password
+	return nil
+}
+
+// RotatePasswords 批量修改站点密码 每个站点修改成功后立即调用 save 保存配置，避免中途退出后密码丢失
+// 返回修改失败的站点及原因
+func RotatePasswords(ctx context.Context, sites []*SiteConfig, gen func(*SiteConfig) string, save func(*SiteConfig) error) map[*SiteConfig]error {
+	failed := make(map[*SiteConfig]error)
+	for _, site := range sites {
+		if err := ctx.Err(); err != nil {
+			failed[site] = err
+			continue
+		}
+		if err := site.RotatePassword(ctx, gen(site)); err != nil {
+			failed[site] = err
+			continue
+		}
+		if err := save(site); err != nil {
+			log.Printf("【%s】密码已修改但保存配置失败 Error: %v", site.BindDomain[0], err)
+			failed[site] = err
+			continue
+		}
+		log.Printf("【%s】修改后台密码成功", site.BindDomain[0])
+	}
+	return failed
+}
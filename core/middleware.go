@@ -0,0 +1,57 @@
+package core
+
+import (
+	"fmt"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"io"
+	"log"
+	"time"
+)
+
+// AuditLog 审计记录的输出 为nil时写入日志
+var AuditLog io.Writer
+
+// middlewareList 可在 SiteConfig.Middleware 中使用的中间件
+var middlewareList = map[string]func(s *SiteConfig) base.Middleware{
+	"log": func(s *SiteConfig) base.Middleware {
+		return base.Logging("【"+s.BindDomain[0]+"】", false)
+	},
+	"timing": func(s *SiteConfig) base.Middleware {
+		return base.Timing(func(op string, d time.Duration, err error) {
+			log.Printf("【%s】%s 耗时%s", s.BindDomain[0], op, d)
+		})
+	},
+	"retry": func(s *SiteConfig) base.Middleware {
+		return base.Retry(3, time.Second)
+	},
+	"rate_limit": func(s *SiteConfig) base.Middleware {
+		interval := 500 * time.Millisecond
+		if s.RateInterval > 0 {
+			interval = time.Duration(s.RateInterval) * time.Millisecond
+		}
+		return base.RateLimit(interval)
+	},
+	"dry_run": func(s *SiteConfig) base.Middleware {
+		return base.DryRun("【" + s.BindDomain[0] + "】")
+	},
+	"audit": func(s *SiteConfig) base.Middleware {
+		w := AuditLog
+		if w == nil {
+			w = log.Writer()
+		}
+		return base.Audit(s.BindDomain[0], base.AuditWriter(w))
+	},
+}
+
+// middlewares 站点配置的中间件
+func (s *SiteConfig) middlewares() ([]base.Middleware, error) {
+	mws := make([]base.Middleware, 0, len(s.Middleware))
+	for _, name := range s.Middleware {
+		f, ok := middlewareList[name]
+		if !ok {
+			return nil, fmt.Errorf("middleware not undefined: %s", name)
+		}
+		mws = append(mws, f(s))
+	}
+	return mws, nil
+}
@@ -11,6 +11,10 @@ import (
 	_ "github.com/cgghui/bt_site_cluster_collect/target/techsir_com"
 	_ "github.com/cgghui/bt_site_cluster_collect/target/v2_sohu_com"
 	"github.com/cgghui/bt_site_cluster_program_api/base"
+	_ "github.com/cgghui/bt_site_cluster_program_api/base/fake"
+	_ "github.com/cgghui/bt_site_cluster_program_api/metaweblog"
+	_ "github.com/cgghui/bt_site_cluster_program_api/typecho"
+	_ "github.com/cgghui/bt_site_cluster_program_api/wordpress"
 	_ "github.com/cgghui/bt_site_cluster_program_api/z-blog"
 	"log"
 	"strings"
@@ -21,16 +25,24 @@ import (
 var ErrProgramNotUndefined = errors.New("err program not undefined")
 
 type collectAction struct {
+	ctx  context.Context
 	name string
 	s    *SiteConfig
 	c    *Category
-	api  base.ProgramAPI
+	api  *siteAPI
 	wg   *sync.WaitGroup
 }
 
+// siteAPI 已登录的站点接口 附带站点的链接结构及程序能力
+type siteAPI struct {
+	base.ProgramAPIContext
+	pl   *base.Permalink
+	caps base.Capabilities
+}
+
 func (c *collectAction) run() {
 	defer c.wg.Done()
-	sd := collect.GetStandard(c.name)
+	sd := getStandard(c.name)
 	if sd == nil {
 		log.Printf("【%s】采集名称未定义 Name: %s", c.s.BindDomain[0], c.name)
 		return
@@ -38,19 +50,26 @@ func (c *collectAction) run() {
 	// 标签
 	for _, tag := range c.c.Collect.Cate {
 		for _, p := range c.c.Collect.Page { // 按页进行采集
-			c.s.collect(c.api, sd, tag, p, c.c)
+			if c.ctx.Err() != nil {
+				return
+			}
+			c.s.collect(c.ctx, c.api, sd, tag, p, c.c)
 		}
 	}
 }
 
+// getStandard 按名称获取采集规则 测试中替换
+var getStandard = collect.GetStandard
+
 var SiteCollectChannel = make(chan *SiteConfig)
 var CollectActionChannel = make(chan *collectAction)
 
-func Start() {
+// Start 启动采集线程，ctx 取消后进行中的请求会被中断
+func Start(ctx context.Context) {
 	for i := 0; i < 50; i++ {
 		go func(No int) {
 			for site := range SiteCollectChannel {
-				site.CollectAction()
+				site.CollectAction(ctx)
 				log.Printf("thread No[%d] work[s] Done, site: %s", No, site.BindDomain[0])
 			}
 		}(i + 1)
@@ -69,11 +88,17 @@ type SiteConfig struct {
 	kernel.SiteConfig
 	base.ProgramBaseInfo
 	base.SiteSetting
-	Category     []Category `json:"category"`
-	SiteRootPath string     `json:"site_root_path"`
-	Username     string     `json:"login_username"`
-	Password     string     `json:"login_password"`
-	Open         bool       `json:"open"`
+	Category     []Category     `json:"category"`
+	Pages        []base.Article `json:"pages"` // 必备的独立页面 按标题判断是否存在
+	SiteRootPath string         `json:"site_root_path"`
+	Username     string         `json:"login_username"`
+	Password     string         `json:"login_password"`
+	AuthorID     string         `json:"author_id"` // 发布文章的作者ID 为空时为1
+	Open         bool           `json:"open"`
+	ImageUpload  string         `json:"image_upload"`    // 图片上传方式 bt media
+	Timeout      int            `json:"collect_timeout"` // 单站采集超时（秒） 0 不限制
+	Middleware   []string       `json:"middleware"`      // 接口中间件 按顺序由外到内 见 middlewareList
+	RateInterval int            `json:"rate_interval"`   // rate_limit 中间件的调用间隔（毫秒） 为0时500
 	BtO          *bt.Option
 	BtS          *bt.Session
 }
@@ -105,82 +130,179 @@ func (c *CategoryCollect) InContain(art *collect.Article) bool {
 	return false
 }
 
-// Login 登录站点
-func (s *SiteConfig) Login() (base.ProgramAPI, error) {
-	function := base.GetProgram(s.ProgramName)
-	if function == nil {
+// SessionStore 登录会话存储 为nil时每次都重新登录
+var SessionStore base.SessionStore
+
+// Login 登录站点 优先恢复 SessionStore 中保存的会话，返回的接口已套用 SiteConfig.Middleware
+func (s *SiteConfig) Login(ctx context.Context) (base.ProgramAPIContext, error) {
+	if base.GetProgramContext(s.ProgramName) == nil {
 		return nil, ErrProgramNotUndefined
 	}
-	return function(s.Username, s.Password, s.ProgramBaseInfo)
+	mws, err := s.middlewares()
+	if err != nil {
+		return nil, err
+	}
+	api, err := base.LoginWithStore(ctx, s.ProgramName, SessionStore, s.Username, s.Password, s.ProgramBaseInfo)
+	if err != nil {
+		return nil, err
+	}
+	return base.Chain(api, mws...), nil
 }
 
-// CollectAction 采集动作
-func (s *SiteConfig) CollectAction() {
+// CollectAction 采集动作 站点的所有请求共用一个上下文
+func (s *SiteConfig) CollectAction(parent context.Context) {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if s.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, time.Duration(s.Timeout)*time.Second)
+	} else {
+		ctx, cancel = context.WithCancel(parent)
+	}
+	defer cancel()
 	// 创建宝塔登录会话
-	if s.BtO.GetLoginSession() == nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
-		ss, err := s.BtO.Login(ctx)
-		cancel()
+	if err := images.login(ctx, s); err != nil {
+		log.Printf("登录宝塔失败 Error: %v", err)
+		return
+	}
+	// 未配置程序时根据首页识别
+	if s.ProgramName == "" {
+		d, err := s.Detect(ctx, false)
 		if err != nil {
-			log.Printf("登录宝塔失败 Error: %v", err)
+			log.Printf("【%s】识别站点程序失败 Error: %v", s.BindDomain[0], err)
 			return
 		}
-		s.BtO.SetLoginSession(ss)
+		log.Printf("【%s】识别站点程序为%s %s", s.BindDomain[0], d.Program, d.Version)
 	}
 	// 登录站点
-	api, err := s.Login()
+	login, err := s.Login(ctx)
 	if err != nil {
 		log.Printf("【%s】登录%s失败 Error: %v", s.BindDomain[0], s.ProgramName, err)
 		return
 	}
-	if err = api.Init(); err != nil {
-		log.Printf("【%s】初始化站点信息失败 Error: %v", s.BindDomain[0], err)
-		return
+	if err = login.Init(ctx); err != nil {
+		var pe *base.PluginError
+		if !errors.As(err, &pe) {
+			log.Printf("【%s】初始化站点信息失败 Error: %v", s.BindDomain[0], err)
+			return
+		}
+		log.Printf("【%s】初始化站点信息 Error: %v", s.BindDomain[0], err)
+	}
+	api := &siteAPI{ProgramAPIContext: login, caps: base.SessionCapabilities(s.ProgramName, login)}
+	// 以站点实际的链接结构生成站内链接
+	if api.caps.Has(base.FeaturePermalink) {
+		if api.pl, err = api.PermalinkGet(ctx); err != nil {
+			log.Printf("【%s】读取站点链接结构失败 使用配置中的链接结构 Error: %v", s.BindDomain[0], err)
+		}
+	}
+	if api.pl == nil {
+		api.pl = s.Permalink.Resolve()
 	}
-	if err = api.SiteSetting(&s.SiteSetting); err != nil {
+	if !api.caps.Has(base.FeatureSetting) {
+		log.Printf("【%s】%s不支持站点设置 跳过", s.BindDomain[0], s.ProgramName)
+	} else if err = api.SiteSetting(ctx, &s.SiteSetting); errors.Is(err, base.UnsupportedErr) {
+		// 能力按程序声明，站点实际不支持时跳过这一步，不影响采集
+		log.Printf("【%s】站点不支持设置基本信息 跳过 Error: %v", s.BindDomain[0], err)
+	} else if err != nil {
 		log.Printf("【%s】设定站点基本信息失败 Error: %v", s.BindDomain[0], err)
 		return
 	}
+	s.syncPages(ctx, api)
 	wg := sync.WaitGroup{}
 	for i, category := range s.Category {
 		// 尝试获取分类，分类不存在时，尝试创建分类
-		if err = api.CategoryGet(&category.Category); err != nil {
-			if err = api.CategoryNew(&category.Category); err != nil {
+		skipped := false
+		if err = api.CategoryGet(ctx, &category.Category); err != nil {
+			if err = api.CategoryNew(base.WithSkipped(ctx, &skipped), &category.Category); err != nil {
 				log.Printf("【%s】无法创建分类[%s] Error: %v", s.BindDomain[0], category.Category.Name, err)
 				continue
 			}
 		}
-		if err = api.CategoryGet(&category.Category); err != nil {
+		// dry_run 未真正创建分类时无法再次获取 直接按配置采集
+		if err = api.CategoryGet(ctx, &category.Category); err != nil && !skipped {
 			log.Printf("【%s】无法获取分类[%s] Error: %v", s.BindDomain[0], category.Category.Name, err)
 			continue
 		}
+		// category 是副本 采集使用的分类需带上获取到的ID
+		s.Category[i].Category = category.Category
 		for _, name := range category.Collect.Name {
 			act := &collectAction{
+				ctx:  ctx,
 				wg:   &wg,
 				name: name,
 				s:    s,
 				c:    &s.Category[i],
 				api:  api,
 			}
-			CollectActionChannel <- act
 			wg.Add(1)
+			select {
+			case CollectActionChannel <- act:
+			case <-ctx.Done():
+				wg.Done()
+			}
 		}
 	}
 	wg.Wait()
 }
 
-func (s *SiteConfig) collect(api base.ProgramAPI, sd collect.Standard, tag collect.Tag, page int, cc *Category) {
+func (s *SiteConfig) authorID() string {
+	if s.AuthorID == "" {
+		return "1"
+	}
+	return s.AuthorID
+}
+
+// syncPages 创建站点缺少的独立页面
+func (s *SiteConfig) syncPages(ctx context.Context, api *siteAPI) {
+	if len(s.Pages) > 0 && !api.caps.Has(base.FeaturePage) {
+		log.Printf("【%s】%s不支持独立页面", s.BindDomain[0], s.ProgramName)
+		return
+	}
+	for _, page := range s.Pages {
+		info := base.Article{Title: page.Title}
+		if err := api.PageGet(ctx, &info); err == nil {
+			continue
+		}
+		page.ID = "0"
+		page.Type = base.TypePage
+		if page.Status == "" {
+			page.Status = base.StatusPublic
+		}
+		if page.AuthorID == "" {
+			page.AuthorID = s.authorID()
+		}
+		if page.PostTime.IsZero() {
+			page.PostTime = time.Now()
+		}
+		if err := api.PageNew(ctx, &page); errors.Is(err, base.ArticleLocateErr) {
+			log.Printf("【%s】页面[%s]已创建但未能获取链接 Error: %v", s.BindDomain[0], page.Title, err)
+			continue
+		} else if err != nil {
+			log.Printf("【%s】无法创建页面[%s] Error: %v", s.BindDomain[0], page.Title, err)
+			continue
+		}
+		log.Printf("【%s】创建页面[%s]成功 链接[%s]", s.BindDomain[0], page.Title, page.Permalink)
+	}
+}
+
+func (s *SiteConfig) collect(ctx context.Context, api *siteAPI, sd collect.Standard, tag collect.Tag, page int, cc *Category) {
 	list, err := sd.ArticleList(tag, page)
 	if err != nil {
 		log.Printf("【%s】【%s】采集文章列表页 Error: %v", s.BindDomain[0], sd.Name(), err)
 		return
 	}
 	for i := range list {
+		if ctx.Err() != nil {
+			return
+		}
 		art := &list[i]
 		info := base.Article{Title: art.Title}
-		_ = api.ArticleGet(&info)
-		if info.ID != "" {
+		if err = api.ArticleGet(ctx, &info); err == nil {
 			continue // 已经发布
+		} else if !errors.Is(err, base.ArticleGetErr) {
+			log.Printf("【%s】【%s】《%s》查询文章失败 Error: %v", s.BindDomain[0], sd.Name(), art.Title, err)
+			continue
 		}
 		if err = sd.ArticleDetail(&list[i]); err != nil {
 			log.Printf("【%s】【%s】《%s》采集文章详细失败 Error: %v", s.BindDomain[0], sd.Name(), art.Title, err)
@@ -197,23 +319,24 @@ func (s *SiteConfig) collect(api base.ProgramAPI, sd collect.Standard, tag colle
 			Alias:    "",
 			Tag:      nil,
 			Cate:     &cc.Category,
-			Status:   "0",
+			Status:   base.StatusPublic,
 			Template: "single",
-			AuthorID: "1",
+			AuthorID: s.authorID(),
 			PostTime: art.PostTime,
-			IsTop:    "0",
-			IsLock:   "0",
+			IsTop:    base.TopNone,
+			IsLock:   base.LockOpen,
 			Intro:    "",
 		}
 		// 处理tag
-		if len(art.Tag) > 0 {
+		if len(art.Tag) > 0 && api.caps.Has(base.FeatureTag) {
 			if info.Tag == nil {
 				info.Tag = make([]string, 0)
 			}
+			alias := make(map[string]string)
 			for _, tg := range art.Tag {
-				v := base.Tag{}
-				if err = api.TagGet(&v); err == base.TagUndefinedErr {
-					_ = api.TagNew(&base.Tag{
+				v := base.Tag{Name: tg.Name}
+				if err = api.TagGet(ctx, &v); errors.Is(err, base.TagUndefinedErr) {
+					_ = api.TagNew(ctx, &base.Tag{
 						Union:     base.Union{ID: "0", Type: "0"},
 						Name:      tg.Name,
 						Alias:     tg.Tag,
@@ -221,16 +344,20 @@ func (s *SiteConfig) collect(api base.ProgramAPI, sd collect.Standard, tag colle
 						Intro:     "",
 						AddNavbar: "0",
 					})
-				} else {
+				} else if err == nil {
+					alias[tg.Name] = v.Alias
 					tg.Name = v.Name
-					tg.Tag = v.Alias
 				}
 				info.Tag = append(info.Tag, tg.Name)
 			}
 			info.Tag = StrSliceDistinct(info.Tag)
 			doc, _ := goquery.NewDocumentFromReader(strings.NewReader(info.Content))
 			doc.Find(collect.TagClass).Each(func(_ int, k *goquery.Selection) {
-				k.SetAttr("href", "/tags-"+k.AttrOr(collect.TagAttrValue, "")+".html")
+				a, ok := alias[k.AttrOr(collect.TagAttrName, "")]
+				if !ok {
+					a = k.AttrOr(collect.TagAttrValue, "")
+				}
+				k.SetAttr("href", api.pl.TagURL("/", "", a))
 				k.SetAttr("target", "_blank")
 				k.SetText(k.AttrOr(collect.TagAttrName, ""))
 				k.RemoveAttr(collect.TagAttrValue)
@@ -238,14 +365,29 @@ func (s *SiteConfig) collect(api base.ProgramAPI, sd collect.Standard, tag colle
 			})
 			info.Content, _ = doc.Html()
 		}
-		if err = api.ArticleNew(&info); err != nil {
+		fallback := art.LocalImages
+		var media []*base.Media
+		if s.ImageUpload == ImageUploadMedia && api.caps.Has(base.FeatureMedia) {
+			fallback, media = s.uploadMedia(ctx, api, art.LocalImages, &info)
+		}
+		skipped := false
+		if err = api.ArticleNew(base.WithSkipped(ctx, &skipped), &info); errors.Is(err, base.ArticleLocateErr) {
+			// 文章已经入库 图片照常上传，不能删除附件或重新发布
+			log.Printf("【%s】【%s】《%s》文章已入库但未能获取ID及链接 Error: %v", s.BindDomain[0], sd.Name(), art.Title, err)
+		} else if err != nil {
 			log.Printf("【%s】【%s】《%s》文章入库失败 采集文章入库失败 Error: %v", s.BindDomain[0], sd.Name(), list[i].Title, err)
+			s.dropMedia(ctx, api, &info, media)
+			continue
+		}
+		if skipped {
+			// dry_run 跳过了入库 图片也不写入站点目录
+			log.Printf("【%s】【%s】《%s》[dry-run] 跳过入库及图片上传", s.BindDomain[0], sd.Name(), art.Title)
 			continue
 		}
-		for _, local := range art.LocalImages {
-			collect.UploadImage(s.BtO.GetLoginSession(), s.SiteRootPath, local)
+		for _, local := range fallback {
+			images.upload(s, local)
 		}
-		log.Printf("【%s】【%s】《%s》文章入库成功 标签[%s] 图片[%d]张", s.BindDomain[0], sd.Name(), art.Title, strings.Join(info.Tag, ","), len(art.LocalImages))
+		log.Printf("【%s】【%s】《%s》文章入库成功 ID[%s] 链接[%s] 标签[%s] 图片[%d]张", s.BindDomain[0], sd.Name(), art.Title, info.ID, info.Permalink, strings.Join(info.Tag, ","), len(art.LocalImages))
 	}
 }
 
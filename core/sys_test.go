@@ -0,0 +1,211 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"github.com/cgghui/bt_site_cluster/kernel"
+	"github.com/cgghui/bt_site_cluster_collect/collect"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"github.com/cgghui/bt_site_cluster_program_api/base/fake"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testHome = "http://fake.test/"
+
+// testStandard 固定返回一篇文章的采集规则
+type testStandard struct {
+	image   string
+	details int
+}
+
+func (sd *testStandard) Name() string {
+	return "test"
+}
+
+func (sd *testStandard) ArticleList(tag collect.Tag, page int) ([]collect.Article, error) {
+	return []collect.Article{{Title: "标题" + tag.Tag}}, nil
+}
+
+func (sd *testStandard) ArticleDetail(a *collect.Article) error {
+	sd.details++
+	a.Content = `<p>关键词 <a class="tag" data-n="Go" data-v="golang">Go</a> <a class="tag" data-n="Linux" data-v="linux">Linux</a></p>` +
+		`<p><img src="/upload/` + filepath.Base(sd.image) + `"/></p>`
+	a.Tag = []collect.Tag{{Name: "Go", Tag: "golang"}, {Name: "Linux", Tag: "linux"}}
+	a.LocalImages = []string{sd.image}
+	return nil
+}
+
+// testStore 记录宝塔上传的图片
+type testStore struct {
+	mu       sync.Mutex
+	uploaded []string
+}
+
+func (ts *testStore) login(context.Context, *SiteConfig) error {
+	return nil
+}
+
+func (ts *testStore) upload(_ *SiteConfig, local string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.uploaded = append(ts.uploaded, local)
+}
+
+var workerOnce sync.Once
+
+// setup 替换采集规则及宝塔上传 返回站点配置、采集规则及记录宝塔上传的 testStore
+func setup(t *testing.T) (*SiteConfig, *testStandard, *testStore) {
+	fake.Reset()
+	workerOnce.Do(func() {
+		go func() {
+			for ca := range CollectActionChannel {
+				ca.run()
+			}
+		}()
+	})
+	image := filepath.Join(t.TempDir(), "a.png")
+	if err := ioutil.WriteFile(image, []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sd := &testStandard{image: image}
+	ts := &testStore{}
+	oldStandard, oldImages := getStandard, images
+	getStandard = func(name string) collect.Standard {
+		if name == sd.Name() {
+			return sd
+		}
+		return nil
+	}
+	images = ts
+	t.Cleanup(func() {
+		getStandard, images = oldStandard, oldImages
+	})
+	s := &SiteConfig{
+		SiteConfig:      kernel.SiteConfig{BindDomain: []string{"fake.test"}, ProgramName: fake.Name},
+		ProgramBaseInfo: base.ProgramBaseInfo{HomeURL: testHome},
+		Username:        "admin",
+		Password:        "123",
+		Category: []Category{{
+			Category: base.Category{Name: "技术"},
+			Collect: CategoryCollect{
+				Name:    []string{sd.Name()},
+				Page:    []int{1},
+				Cate:    []collect.Tag{{Name: "科技", Tag: "tech"}},
+				Contain: []CategoryContain{{Word: "关键词", Num: 1}},
+			},
+		}},
+	}
+	return s, sd, ts
+}
+
+// session 直接登录 fake 站点 不经过中间件
+func session(t *testing.T) base.ProgramAPIContext {
+	api, err := fake.Login(context.Background(), "admin", "123", base.ProgramBaseInfo{HomeURL: testHome})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return api
+}
+
+func TestLogin(t *testing.T) {
+	s, _, _ := setup(t)
+	ctx := context.Background()
+	s.ProgramName = "undefined"
+	if _, err := s.Login(ctx); !errors.Is(err, ErrProgramNotUndefined) {
+		t.Fatal(err)
+	}
+	s.ProgramName = fake.Name
+	s.Middleware = []string{"dry_run"}
+	api, err := s.Login(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	skipped := false
+	a := base.Article{Title: "hello"}
+	if err = api.ArticleNew(base.WithSkipped(ctx, &skipped), &a); err != nil || !skipped {
+		t.Fatal(skipped, err)
+	}
+	if err = session(t).ArticleGet(ctx, &base.Article{Title: "hello"}); !errors.Is(err, base.ArticleGetErr) {
+		t.Fatal(err)
+	}
+}
+
+func TestCollectAction(t *testing.T) {
+	s, _, ts := setup(t)
+	ctx := context.Background()
+	api := session(t)
+	// 站点已有的标签使用站点的别名 链接按站点的链接结构生成
+	if err := api.TagNew(ctx, &base.Tag{Name: "Linux", Alias: "linux-os"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.PermalinkSet(ctx, &base.Permalink{Tag: "{%host%}tag/{%alias%}/"}); err != nil {
+		t.Fatal(err)
+	}
+	s.CollectAction(ctx)
+	cate := base.Category{Name: "技术"}
+	if err := api.CategoryGet(ctx, &cate); err != nil {
+		t.Fatal(err)
+	}
+	a := base.Article{Title: "标题tech"}
+	if err := api.ArticleGet(ctx, &a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Cate == nil || a.Cate.ID != cate.ID || s.Category[0].ID != cate.ID {
+		t.Fatal(a.Cate, s.Category[0].ID, cate.ID)
+	}
+	if !strings.Contains(a.Content, `href="/tag/golang/"`) || !strings.Contains(a.Content, `href="/tag/linux-os/"`) {
+		t.Fatal(a.Content)
+	}
+	if len(ts.uploaded) != 1 || len(fake.Open(testHome).Media()) != 0 {
+		t.Fatal(ts.uploaded, fake.Open(testHome).Media())
+	}
+	// 已经发布的文章不再入库
+	s.CollectAction(ctx)
+	if list, total, err := api.ArticleList(ctx, nil); err != nil || total != 1 {
+		t.Fatal(list, err)
+	}
+}
+
+func TestCollectActionMedia(t *testing.T) {
+	s, _, ts := setup(t)
+	s.ImageUpload = ImageUploadMedia
+	s.CollectAction(context.Background())
+	media := fake.Open(testHome).Media()
+	if len(media) != 1 || len(ts.uploaded) != 0 {
+		t.Fatal(media, ts.uploaded)
+	}
+	a := base.Article{Title: "标题tech"}
+	if err := session(t).ArticleGet(context.Background(), &a); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(a.Content, `src="`+media[0].URL+`"`) {
+		t.Fatal(a.Content)
+	}
+}
+
+func TestCollectActionDryRun(t *testing.T) {
+	for _, mode := range []string{ImageUploadBT, ImageUploadMedia} {
+		s, sd, ts := setup(t)
+		s.ImageUpload = mode
+		s.Middleware = []string{"dry_run"}
+		s.CollectAction(context.Background())
+		api := session(t)
+		if list, total, err := api.ArticleList(context.Background(), nil); err != nil || total != 0 {
+			t.Fatal(mode, list, err)
+		}
+		// 分类未真正创建 仍按配置采集 但不上传任何图片
+		if sd.details != 1 {
+			t.Fatal(mode, sd.details)
+		}
+		if err := api.CategoryGet(context.Background(), &base.Category{Name: "技术"}); !errors.Is(err, base.CategoryGetErr) {
+			t.Fatal(mode, err)
+		}
+		if len(ts.uploaded) != 0 || len(fake.Open(testHome).Media()) != 0 {
+			t.Fatal(mode, ts.uploaded, fake.Open(testHome).Media())
+		}
+	}
+}
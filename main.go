@@ -1,18 +1,23 @@
 package main
 
 import (
+	"context"
 	"github.com/cgghui/bt_site_cluster/bt"
 	"github.com/cgghui/bt_site_cluster/kernel"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
 	"github.com/cgghui/bt_site_cluster_program_api/core"
 	"log"
-	"os"
 	"os/signal"
 	"syscall"
 )
 
 func main() {
 
-	core.Start()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	core.SessionStore = &base.FileSessionStore{Dir: "session"}
+	core.Start(ctx)
 
 	var (
 		option []bt.Option
@@ -38,14 +43,11 @@ func main() {
 		if !site.Open {
 			continue
 		}
-		core.SiteCollectChannel <- &SiteList[i]
+		select {
+		case core.SiteCollectChannel <- &SiteList[i]:
+		case <-ctx.Done():
+		}
 	}
-	WaitQuitSignal()
+	<-ctx.Done()
 	log.Println("Byte.")
 }
-
-func WaitQuitSignal() {
-	quit := make(chan os.Signal)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-}
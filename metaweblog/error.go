@@ -0,0 +1,68 @@
+package metaweblog
+
+import (
+	"errors"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"github.com/cgghui/bt_site_cluster_program_api/base/xmlrpc"
+	"net/http"
+)
+
+// methodNotFound 服务端不支持该方法
+const methodNotFound = -32601
+
+func (s *MWSession) endpoint(method string) string {
+	return "POST " + s.url + " " + method
+}
+
+// wrap 包装请求过程中的错误
+func (s *MWSession) wrap(op, method string, err error) error {
+	return &base.Error{Op: op, Site: s.info.HomeURL, Endpoint: s.endpoint(method), Err: err}
+}
+
+// fail 状态码不为200
+func (s *MWSession) fail(op, method string, resp *http.Response, body []byte) error {
+	return &base.Error{Op: op, Site: s.info.HomeURL, Status: resp.StatusCode, Endpoint: s.endpoint(method), Body: string(body)}
+}
+
+// fault 服务端返回的错误 按 WordPress 的错误码分类
+func (s *MWSession) fault(op, method string, f *xmlrpc.Fault) error {
+	e := &base.Error{Op: op, Site: s.info.HomeURL, Endpoint: s.endpoint(method), Err: f}
+	switch f.Code {
+	case 401, 403:
+		e.Kind = base.KindAuth
+	case 404:
+		e.Kind = base.KindNotFound
+	case methodNotFound:
+		e.Sentinel = base.UnsupportedErr
+	}
+	return e
+}
+
+// cause 以 sentinel 包装错误 同一操作中 call 返回的错误直接补充 Sentinel
+func (s *MWSession) cause(op string, sentinel, err error) error {
+	var e *base.Error
+	if errors.As(err, &e) && e.Sentinel == nil && e.Op == op {
+		e.Sentinel = sentinel
+		return e
+	}
+	return &base.Error{Op: op, Site: s.info.HomeURL, Sentinel: sentinel, Err: err}
+}
+
+// notFound 查找失败
+func (s *MWSession) notFound(op string, sentinel error) error {
+	return &base.Error{Op: op, Site: s.info.HomeURL, Sentinel: sentinel}
+}
+
+// invalid 参数错误
+func (s *MWSession) invalid(op, msg string) error {
+	return &base.Error{Op: op, Site: s.info.HomeURL, Kind: base.KindValidation, Err: errors.New(msg)}
+}
+
+// unsupported 服务端不支持 method 为空时表示 XML-RPC 没有对应的接口
+func (s *MWSession) unsupported(op, method string) error {
+	e := &base.Error{Op: op, Site: s.info.HomeURL, Sentinel: base.UnsupportedErr}
+	if method != "" {
+		e.Endpoint = s.endpoint(method)
+	}
+	return e
+}
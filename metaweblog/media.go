@@ -0,0 +1,35 @@
+package metaweblog
+
+import (
+	"context"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"github.com/cgghui/bt_site_cluster_program_api/base/xmlrpc"
+)
+
+// MediaUpload 上传附件 优先使用 wp.uploadFile，否则使用 metaWeblog.newMediaObject
+func (s *MWSession) MediaUpload(ctx context.Context, m *base.Media) error {
+	if m.Name == "" || len(m.Data) == 0 {
+		return s.invalid("MediaUpload", "请指定文件名及文件内容")
+	}
+	method := "metaWeblog.newMediaObject"
+	if s.has("wp.uploadFile") {
+		method = "wp.uploadFile"
+	}
+	mt := m.MimeType
+	if mt == "" {
+		mt = "application/octet-stream"
+	}
+	v, err := s.call(ctx, "MediaUpload", method, s.blogID, s.username, s.password, xmlrpc.Struct{
+		"name": m.Name, "type": mt, "bits": m.Data, "overwrite": false,
+	})
+	if err != nil {
+		return s.cause("MediaUpload", base.MediaUploadErr, err)
+	}
+	r := toStruct(v)
+	m.ID = str(r["id"])
+	if m.ID == "" {
+		m.ID = str(r["attachment_id"])
+	}
+	m.URL = str(r["url"])
+	return nil
+}
@@ -0,0 +1,205 @@
+// Package metaweblog 基于 XML-RPC 的 MetaWeblog 程序接口
+//
+// 适用于关闭了 REST API 的 WordPress 及其它支持 MetaWeblog 的程序（如 Z-Blog 的 xml-rpc）。
+// 接口地址为 HomeURL + BackstagePath + LoginPath，均为空时使用 xmlrpc.php，
+// Z-Blog 为 BackstagePath "zb_system/" LoginPath "xml-rpc/index.php"。
+// 分类的修改、标签、页面及站点设置依赖 wp.* 扩展，服务端不支持时返回 base.UnsupportedErr。
+package metaweblog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"github.com/cgghui/bt_site_cluster_program_api/base/xmlrpc"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Capabilities metaweblog 可能支持的功能 标签、页面及站点设置依赖 wp.* 扩展，
+// 登录后以 MWSession.Capabilities 为准
+var Capabilities = base.Capabilities{
+	Features: []base.Feature{
+		base.FeatureArticle, base.FeatureCategory, base.FeatureTag, base.FeatureSetting,
+		base.FeatureList, base.FeaturePage, base.FeatureMedia,
+	},
+	Formats: []string{base.FormatHTML},
+}
+
+func init() {
+	base.RegisterProgramContext("metaweblog", LoginContext, Capabilities)
+}
+
+// RecentLimit 查找及列表时读取的最近文章数 MetaWeblog 不支持分页，更早的文章无法按标题查找
+var RecentLimit = 200
+
+type MWSession struct {
+	base.UnsupportedAPI
+	info     base.ProgramBaseInfo
+	username string
+	password string
+	url      string
+	blogID   string
+	methods  map[string]bool // system.listMethods 的结果 为nil时服务端不支持列出方法
+}
+
+var Client = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+// Login 登录
+func Login(username, password string, info base.ProgramBaseInfo) (base.ProgramAPI, error) {
+	s, err := LoginContext(context.Background(), username, password, info)
+	if err != nil {
+		return nil, err
+	}
+	return base.Legacy(s), nil
+}
+
+// LoginContext 带上下文的登录 以 blogger.getUsersBlogs 校验密码并取得 blogid
+func LoginContext(ctx context.Context, username, password string, info base.ProgramBaseInfo) (base.ProgramAPIContext, error) {
+	s := &MWSession{info: info, username: username, password: password, url: endpointURL(info)}
+	v, err := s.call(ctx, "Login", "blogger.getUsersBlogs", "", username, password)
+	if err != nil {
+		var f *xmlrpc.Fault
+		if errors.As(err, &f) {
+			return nil, s.cause("Login", base.LoginFailErr, err)
+		}
+		return nil, err
+	}
+	blogs := toArray(v)
+	if len(blogs) == 0 {
+		return nil, s.cause("Login", base.LoginFailErr, errors.New("没有可管理的站点"))
+	}
+	s.blogID = str(toStruct(blogs[0])["blogid"])
+	// 不支持 system.listMethods 时只使用 MetaWeblog 及 Blogger 接口
+	if v, err = s.call(ctx, "Login", "system.listMethods"); err == nil {
+		s.methods = make(map[string]bool)
+		for _, m := range toArray(v) {
+			s.methods[str(m)] = true
+		}
+	}
+	return s, nil
+}
+
+func endpointURL(info base.ProgramBaseInfo) string {
+	if strings.HasPrefix(info.LoginPath, "http://") || strings.HasPrefix(info.LoginPath, "https://") {
+		return info.LoginPath
+	}
+	path := info.BackstagePath + info.LoginPath
+	if path == "" {
+		path = "xmlrpc.php"
+	}
+	return info.HomeURL + path
+}
+
+// has 服务端是否支持该方法
+func (s *MWSession) has(method string) bool {
+	if s.methods == nil {
+		return strings.HasPrefix(method, "metaWeblog.") || strings.HasPrefix(method, "blogger.")
+	}
+	return s.methods[method]
+}
+
+// Capabilities 按服务端支持的方法得出的功能 只支持 MetaWeblog 时没有标签、页面及站点设置
+func (s *MWSession) Capabilities() base.Capabilities {
+	c := base.Capabilities{
+		Features: []base.Feature{base.FeatureArticle, base.FeatureCategory, base.FeatureList, base.FeatureMedia},
+		Formats:  Capabilities.Formats,
+	}
+	if s.has("wp.getTerms") || s.has("wp.getTags") {
+		c.Features = append(c.Features, base.FeatureTag)
+	}
+	if s.has("wp.setOptions") {
+		c.Features = append(c.Features, base.FeatureSetting)
+	}
+	if s.has("wp.getPages") {
+		c.Features = append(c.Features, base.FeaturePage)
+	}
+	return c
+}
+
+// call 调用方法 服务端返回的错误包装为 *base.Error，其 Err 为 *xmlrpc.Fault
+func (s *MWSession) call(ctx context.Context, op, method string, params ...interface{}) (interface{}, error) {
+	body, err := xmlrpc.EncodeCall(method, params...)
+	if err != nil {
+		return nil, err
+	}
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body)); err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", base.UserAgent)
+	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	var resp *http.Response
+	if resp, err = Client.Do(req); err != nil {
+		return nil, s.wrap(op, method, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != 200 {
+		data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, base.ErrorBodyLimit))
+		return nil, s.fail(op, method, resp, data)
+	}
+	v, err := xmlrpc.DecodeResponse(resp.Body)
+	if err != nil {
+		var f *xmlrpc.Fault
+		if errors.As(err, &f) {
+			return nil, s.fault(op, method, f)
+		}
+		return nil, s.wrap(op, method, err)
+	}
+	return v, nil
+}
+
+// Init 初始化 XML-RPC 无法管理插件，ProgramBaseInfo.Plugins 均返回失败
+func (s *MWSession) Init(ctx context.Context) error {
+	if len(s.info.Plugins) == 0 {
+		return nil
+	}
+	failed := make(map[string]error)
+	for _, id := range s.info.Plugins {
+		failed[id] = base.UnsupportedErr
+	}
+	return &base.PluginError{Failed: failed}
+}
+
+// NavbarNew XML-RPC 没有导航接口
+func (s *MWSession) NavbarNew(context.Context, *base.Navbar) error {
+	return s.unsupported("NavbarNew", "")
+}
+
+func toStruct(v interface{}) xmlrpc.Struct {
+	m, _ := v.(xmlrpc.Struct)
+	return m
+}
+
+func toArray(v interface{}) xmlrpc.Array {
+	a, _ := v.(xmlrpc.Array)
+	return a
+}
+
+// str 转为字符串 ID 可能以 int 或 string 返回
+func str(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case int:
+		return strconv.Itoa(x)
+	}
+	return fmt.Sprint(v)
+}
+
+// atoi 解析ID 无效时为0
+func atoi(id string) int {
+	n, _ := strconv.Atoi(id)
+	return n
+}
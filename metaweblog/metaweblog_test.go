@@ -0,0 +1,449 @@
+package metaweblog
+
+import (
+	"context"
+	"errors"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"github.com/cgghui/bt_site_cluster_program_api/base/conformance"
+	"github.com/cgghui/bt_site_cluster_program_api/base/xmlrpc"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rpcServer 内存中的 XML-RPC 服务 只实现适配器用到的方法
+// legacy 为true时只支持 MetaWeblog 及 Blogger 方法，且不支持 system.listMethods
+type rpcServer struct {
+	*httptest.Server
+	legacy  bool
+	mu      sync.Mutex
+	seq     int
+	posts   map[string]xmlrpc.Struct
+	terms   map[string]xmlrpc.Struct
+	options map[string]string
+}
+
+func newRPCServer(legacy bool) *rpcServer {
+	s := &rpcServer{
+		legacy:  legacy,
+		posts:   make(map[string]xmlrpc.Struct),
+		terms:   make(map[string]xmlrpc.Struct),
+		options: map[string]string{"blog_title": "WordPress", "blog_tagline": "Just another WordPress site", "default_comment_status": "open", "date_format": "Y-m-d"},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// userIndex 方法参数中用户名的位置
+var userIndex = map[string]int{"blogger.deletePost": 2, "wp.editPage": 2, "wp.getPage": 2}
+
+func (s *rpcServer) handle(w http.ResponseWriter, r *http.Request) {
+	method, params, err := xmlrpc.DecodeCall(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	s.mu.Lock()
+	v := s.dispatch(method, params)
+	s.mu.Unlock()
+	body, err := xmlrpc.EncodeResponse(v)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "text/xml")
+	_, _ = w.Write(body)
+}
+
+func (s *rpcServer) dispatch(method string, p xmlrpc.Array) interface{} {
+	if method == "system.listMethods" {
+		if s.legacy {
+			return &xmlrpc.Fault{Code: methodNotFound, String: "server error. requested method system.listMethods does not exist."}
+		}
+		methods := xmlrpc.Array{"blogger.getUsersBlogs", "blogger.deletePost", "system.listMethods"}
+		for _, m := range []string{"newPost", "editPost", "getPost", "getRecentPosts", "getCategories", "newMediaObject"} {
+			methods = append(methods, "metaWeblog."+m)
+		}
+		for _, m := range []string{"newPage", "editPage", "getPage", "getPages", "deletePage", "getTerms", "newTerm", "editTerm", "deleteTerm", "getOptions", "setOptions", "uploadFile"} {
+			methods = append(methods, "wp."+m)
+		}
+		return methods
+	}
+	if s.legacy && strings.HasPrefix(method, "wp.") {
+		return &xmlrpc.Fault{Code: methodNotFound, String: "server error. requested method " + method + " does not exist."}
+	}
+	i, ok := userIndex[method]
+	if !ok {
+		i = 1
+	}
+	if len(p) <= i+1 || p[i] != "admin" || p[i+1] != "123456" {
+		return &xmlrpc.Fault{Code: 403, String: "Incorrect username or password."}
+	}
+	switch method {
+	case "blogger.getUsersBlogs":
+		return xmlrpc.Array{xmlrpc.Struct{"blogid": "1", "blogName": "test", "url": s.URL + "/", "isAdmin": true}}
+	case "metaWeblog.newPost":
+		return s.save("", "post", toStruct(p[3]), p[4].(bool))
+	case "wp.newPage":
+		return s.save("", "page", toStruct(p[3]), p[4].(bool))
+	case "metaWeblog.editPost":
+		return s.edit(str(p[0]), "post", toStruct(p[3]), p[4].(bool))
+	case "wp.editPage":
+		return s.edit(str(p[1]), "page", toStruct(p[4]), p[5].(bool))
+	case "metaWeblog.getPost":
+		return s.get(str(p[0]), "post")
+	case "wp.getPage":
+		return s.get(str(p[1]), "page")
+	case "metaWeblog.getRecentPosts":
+		return s.recent("post")
+	case "wp.getPages":
+		return s.recent("page")
+	case "blogger.deletePost":
+		return s.del(str(p[1]), "post")
+	case "wp.deletePage":
+		return s.del(str(p[3]), "page")
+	case "metaWeblog.getCategories":
+		data := xmlrpc.Array{}
+		for _, item := range s.termList("category") {
+			t := toStruct(item)
+			data = append(data, xmlrpc.Struct{"categoryId": t["term_id"], "categoryName": t["name"], "categoryDescription": t["description"], "parentId": t["parent"]})
+		}
+		return data
+	case "wp.getTerms":
+		return s.termList(str(p[3]))
+	case "wp.newTerm":
+		c := toStruct(p[3])
+		for _, t := range s.terms {
+			if t["taxonomy"] == c["taxonomy"] && t["name"] == c["name"] {
+				return &xmlrpc.Fault{Code: 500, String: "A term with the name provided already exists."}
+			}
+		}
+		s.seq++
+		id := strconv.Itoa(s.seq)
+		s.terms[id] = xmlrpc.Struct{"term_id": id, "taxonomy": c["taxonomy"], "name": c["name"], "slug": str(c["slug"]), "description": str(c["description"]), "parent": str(c["parent"])}
+		return id
+	case "wp.editTerm":
+		t, ok := s.terms[str(p[3])]
+		if !ok {
+			return &xmlrpc.Fault{Code: 404, String: "Invalid term ID."}
+		}
+		for k, v := range toStruct(p[4]) {
+			t[k] = str(v)
+		}
+		return true
+	case "wp.deleteTerm":
+		if _, ok := s.terms[str(p[4])]; !ok {
+			return &xmlrpc.Fault{Code: 404, String: "Invalid term ID."}
+		}
+		delete(s.terms, str(p[4]))
+		return true
+	case "wp.getOptions":
+		data := xmlrpc.Struct{}
+		for k, v := range s.options {
+			data[k] = xmlrpc.Struct{"desc": k, "readonly": false, "value": v}
+		}
+		return data
+	case "wp.setOptions":
+		for k, v := range toStruct(p[3]) {
+			s.options[k] = str(v)
+		}
+		return xmlrpc.Struct{}
+	case "wp.uploadFile", "metaWeblog.newMediaObject":
+		f := toStruct(p[3])
+		if bits, _ := f["bits"].([]byte); len(bits) == 0 {
+			return &xmlrpc.Fault{Code: 500, String: "Could not write file"}
+		}
+		s.seq++
+		return xmlrpc.Struct{"id": strconv.Itoa(s.seq), "file": f["name"], "url": s.URL + "/wp-content/uploads/" + str(f["name"]), "type": f["type"]}
+	}
+	return &xmlrpc.Fault{Code: methodNotFound, String: "server error. requested method " + method + " does not exist."}
+}
+
+func (s *rpcServer) save(id, typ string, c xmlrpc.Struct, publish bool) interface{} {
+	if id == "" {
+		s.seq++
+		id = strconv.Itoa(s.seq)
+	}
+	post := xmlrpc.Struct{"type": typ, "permaLink": s.URL + "/?p=" + id}
+	for k, v := range c {
+		post[k] = v
+	}
+	key := "post_status"
+	if typ == "page" {
+		key = "page_status"
+	}
+	if _, ok := post[key]; !ok {
+		post[key] = "draft"
+		if publish {
+			post[key] = "publish"
+		}
+	}
+	if _, ok := post["dateCreated"]; !ok {
+		post["dateCreated"] = time.Now()
+	}
+	s.posts[id] = post
+	return id
+}
+
+func (s *rpcServer) edit(id, typ string, c xmlrpc.Struct, publish bool) interface{} {
+	old, ok := s.posts[id]
+	if !ok || old["type"] != typ {
+		return &xmlrpc.Fault{Code: 404, String: "Invalid post ID."}
+	}
+	// 与 WordPress 相同 未提交状态时由 publish 决定，不沿用原来的状态
+	for k, v := range old {
+		if _, set := c[k]; !set && k != "type" && k != "permaLink" && k != "post_status" && k != "page_status" {
+			c[k] = v
+		}
+	}
+	s.save(id, typ, c, publish)
+	return true
+}
+
+// get 与 WordPress 相同 正文按 <!--more--> 拆分，date_created_gmt 不带时区
+func (s *rpcServer) get(id, typ string) interface{} {
+	post, ok := s.posts[id]
+	if !ok || post["type"] != typ {
+		return &xmlrpc.Fault{Code: 404, String: "Invalid post ID."}
+	}
+	data := xmlrpc.Struct{"userid": "1", "mt_text_more": ""}
+	for k, v := range post {
+		data[k] = v
+	}
+	delete(data, "type")
+	if typ == "page" {
+		data["page_id"] = id
+	} else {
+		data["postid"] = id
+	}
+	if parts := strings.SplitN(str(post["description"]), moreTag, 2); len(parts) == 2 {
+		data["description"], data["mt_text_more"] = parts[0], parts[1]
+	}
+	if t, ok := post["dateCreated"].(time.Time); ok {
+		t = t.UTC()
+		data["date_created_gmt"] = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local)
+	}
+	return data
+}
+
+func (s *rpcServer) recent(typ string) interface{} {
+	ids := make([]int, 0)
+	for id, post := range s.posts {
+		if post["type"] == typ {
+			n, _ := strconv.Atoi(id)
+			ids = append(ids, n)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+	data := xmlrpc.Array{}
+	for _, id := range ids {
+		data = append(data, s.get(strconv.Itoa(id), typ))
+	}
+	return data
+}
+
+func (s *rpcServer) del(id, typ string) interface{} {
+	if post, ok := s.posts[id]; !ok || post["type"] != typ {
+		return &xmlrpc.Fault{Code: 404, String: "Invalid post ID."}
+	}
+	delete(s.posts, id)
+	return true
+}
+
+func (s *rpcServer) termList(taxonomy string) xmlrpc.Array {
+	data := xmlrpc.Array{}
+	for _, t := range s.terms {
+		if t["taxonomy"] == taxonomy {
+			data = append(data, t)
+		}
+	}
+	return data
+}
+
+func testLogin(t *testing.T, srv *rpcServer) *MWSession {
+	t.Helper()
+	api, err := LoginContext(context.Background(), "admin", "123456", base.ProgramBaseInfo{HomeURL: srv.URL + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return api.(*MWSession)
+}
+
+func TestConformance(t *testing.T) {
+	srv := newRPCServer(false)
+	defer srv.Close()
+	conformance.Run(t, conformance.Config{
+		Login:    LoginContext,
+		Server:   srv.Server,
+		Username: "admin",
+		Password: "123456",
+		Caps:     &Capabilities,
+	})
+	if caps := testLogin(t, srv).Capabilities(); !caps.Has(base.FeatureSetting) || !caps.Has(base.FeaturePage) || !caps.Has(base.FeatureTag) {
+		t.Fatal(caps)
+	}
+}
+
+func TestArticleFields(t *testing.T) {
+	srv := newRPCServer(false)
+	defer srv.Close()
+	s := testLogin(t, srv)
+	ctx := context.Background()
+	cate := &base.Category{Name: "news"}
+	if err := s.CategoryNew(ctx, cate); err != nil {
+		t.Fatal(err)
+	}
+	when := time.Date(2023, 5, 1, 8, 30, 0, 0, time.Local)
+	a := &base.Article{
+		Title: "hello", Content: "<p>hi</p>" + moreTag + "<p>more</p>", Intro: "summary", Tag: []string{"go", "wp"},
+		Cate: &base.Category{Union: base.Union{ID: cate.ID}}, Status: base.StatusAudit, IsTop: base.TopHome,
+		IsLock: base.LockClosed, PostTime: when,
+	}
+	if err := s.ArticleNew(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Permalink != srv.URL+"/?p="+a.ID {
+		t.Fatal(a.Permalink)
+	}
+	got := &base.Article{Union: base.Union{ID: a.ID}}
+	if err := s.ArticleGet(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != base.StatusAudit || got.IsTop != base.TopGlobal || got.IsLock != base.LockClosed || got.Intro != "summary" || got.Content != a.Content {
+		t.Fatal(got.Status, got.IsTop, got.IsLock, got.Intro, got.Content)
+	}
+	if got.Cate.ID != cate.ID || got.Cate.Name != "news" || strings.Join(got.Tag, ",") != "go,wp" || !got.PostTime.Equal(when) {
+		t.Fatal(got.Cate, got.Tag, got.PostTime)
+	}
+	list, total, err := s.ArticleList(ctx, &base.ListOption{CateID: cate.ID, Page: 2, PageSize: 1})
+	if err != nil || total != 1 || len(list) != 0 {
+		t.Fatal(list, total, err)
+	}
+}
+
+func TestEditKeepStatus(t *testing.T) {
+	srv := newRPCServer(false)
+	defer srv.Close()
+	s := testLogin(t, srv)
+	ctx := context.Background()
+	for _, st := range []base.Status{base.StatusDraft, base.StatusAudit, base.StatusPublic} {
+		a := &base.Article{Title: "status", Content: "<p>status</p>", Status: st}
+		if err := s.ArticleNew(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+		// 修改时未指定状态 保持原来的状态
+		edit := &base.Article{Union: base.Union{ID: a.ID}, Title: "status-updated", Content: "<p>updated</p>"}
+		if err := s.ArticleNew(ctx, edit); err != nil {
+			t.Fatal(err)
+		}
+		got := &base.Article{Union: base.Union{ID: a.ID}}
+		if err := s.ArticleGet(ctx, got); err != nil || got.Status != st || got.Title != "status-updated" {
+			t.Fatal(st, got.Status, got.Title, err)
+		}
+	}
+	// 新建时未指定状态为公开
+	a := &base.Article{Title: "new", Content: "<p>new</p>"}
+	if err := s.ArticleNew(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	got := &base.Article{Union: base.Union{ID: a.ID}}
+	if err := s.ArticleGet(ctx, got); err != nil || got.Status != base.StatusPublic {
+		t.Fatal(got.Status, err)
+	}
+}
+
+func TestPage(t *testing.T) {
+	srv := newRPCServer(false)
+	defer srv.Close()
+	s := testLogin(t, srv)
+	ctx := context.Background()
+	p := &base.Article{Title: "about", Content: "<p>about</p>", Template: "full.php"}
+	if err := s.PageNew(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+	got := &base.Article{Title: "about"}
+	if err := s.PageGet(ctx, got); err != nil || got.ID != p.ID || got.Template != "full.php" || got.Type != base.TypePage {
+		t.Fatal(got, err)
+	}
+	if list, total, err := s.ArticleList(ctx, nil); err != nil || total != 0 {
+		t.Fatal(list, err)
+	}
+	if err := s.PageDel(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PageGet(ctx, &base.Article{Union: base.Union{ID: p.ID}}); !errors.Is(err, base.PageGetErr) || !base.IsNotFound(err) {
+		t.Fatal(err)
+	}
+}
+
+func TestSiteSetting(t *testing.T) {
+	srv := newRPCServer(false)
+	defer srv.Close()
+	s := testLogin(t, srv)
+	ctx := context.Background()
+	off := true
+	ss := &base.SiteSetting{SiteName: "blog", CommentOff: &off, Extra: map[string]string{"date_format": "Y/m/d"}}
+	if err := s.SiteSetting(ctx, ss); err != nil {
+		t.Fatal(err)
+	}
+	got := &base.SiteSetting{}
+	if err := s.SiteSettingGet(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.SiteName != "blog" || got.SubSiteName != "Just another WordPress site" || !*got.CommentOff || got.Extra["date_format"] != "Y/m/d" {
+		t.Fatal(got)
+	}
+}
+
+func TestMediaUpload(t *testing.T) {
+	srv := newRPCServer(false)
+	defer srv.Close()
+	s := testLogin(t, srv)
+	m := &base.Media{Name: "a.png", MimeType: "image/png", Data: []byte{1, 2, 3}}
+	if err := s.MediaUpload(context.Background(), m); err != nil || m.ID == "" || m.URL != srv.URL+"/wp-content/uploads/a.png" {
+		t.Fatal(m, err)
+	}
+}
+
+// TestLegacy 只支持 MetaWeblog 的程序 文章可用，扩展功能返回 UnsupportedErr
+func TestLegacy(t *testing.T) {
+	srv := newRPCServer(true)
+	defer srv.Close()
+	s := testLogin(t, srv)
+	ctx := context.Background()
+	a := &base.Article{Title: "hello", Content: "<p>hi</p>"}
+	if err := s.ArticleNew(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ArticleGet(ctx, &base.Article{Title: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CategoryNew(ctx, &base.Category{Name: "news"}); !errors.Is(err, base.UnsupportedErr) {
+		t.Fatal(err)
+	}
+	if err := s.SiteSetting(ctx, &base.SiteSetting{SiteName: "blog"}); !errors.Is(err, base.UnsupportedErr) {
+		t.Fatal(err)
+	}
+	if err := s.PageNew(ctx, &base.Article{Title: "about", Content: "about"}); !errors.Is(err, base.UnsupportedErr) {
+		t.Fatal(err)
+	}
+	if err := s.TagGet(ctx, &base.Tag{Name: "go"}); !errors.Is(err, base.UnsupportedErr) {
+		t.Fatal(err)
+	}
+	m := &base.Media{Name: "a.png", Data: []byte{1}}
+	if err := s.MediaUpload(ctx, m); err != nil || m.URL == "" {
+		t.Fatal(m, err)
+	}
+	caps := base.SessionCapabilities("metaweblog", base.Chain(s, base.DryRun("")))
+	if !caps.Has(base.FeatureArticle) || caps.Has(base.FeatureSetting) || caps.Has(base.FeaturePage) || caps.Has(base.FeatureTag) {
+		t.Fatal(caps)
+	}
+	if caps = s.Capabilities(); len(caps.Features) != 4 {
+		t.Fatal(caps)
+	}
+}
@@ -0,0 +1,388 @@
+package metaweblog
+
+import (
+	"context"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"github.com/cgghui/bt_site_cluster_program_api/base/xmlrpc"
+	"strings"
+	"time"
+)
+
+// moreTag WordPress 以此分隔正文，getPost 分别返回 description 及 mt_text_more
+const moreTag = "<!--more-->"
+
+// postStatus 文章状态对应的 post_status
+var postStatus = map[base.Status]string{
+	base.StatusPublic: "publish",
+	base.StatusDraft:  "draft",
+	base.StatusAudit:  "pending",
+}
+
+// articleStatus post_status 对应的文章状态 定时发布视为公开，私密视为草稿
+var articleStatus = map[string]base.Status{
+	"publish": base.StatusPublic,
+	"future":  base.StatusPublic,
+	"draft":   base.StatusDraft,
+	"pending": base.StatusAudit,
+	"private": base.StatusDraft,
+}
+
+// pageMethod 判断服务端是否支持页面的方法 页面方法同属 wp.* 扩展，以 wp.getPages 判断
+const pageMethod = "wp.getPages"
+
+// postKind 文章与页面 页面使用 wp.*Page 方法，参数顺序与 MetaWeblog 不同
+type postKind struct {
+	typ    string
+	op     string // 操作名称前缀 Article Page
+	page   bool
+	newErr error
+	getErr error
+	delErr error
+}
+
+var articleKind = postKind{typ: base.TypeArticle, op: "Article", newErr: base.ArticleNewErr, getErr: base.ArticleGetErr, delErr: base.ArticleDelErr}
+var pageKind = postKind{typ: base.TypePage, op: "Page", page: true, newErr: base.PageNewErr, getErr: base.PageGetErr, delErr: base.PageDelErr}
+
+// ArticleNew 新建或修改文章 成功后填充 Article.ID 及 Article.Permalink
+// 分类以名称提交，只指定 Cate.ID 时先查找分类名称
+func (s *MWSession) ArticleNew(ctx context.Context, a *base.Article) error {
+	return s.postNew(ctx, articleKind, a)
+}
+
+// ArticleGet 获取文章 按 ID、标题（完全匹配）、别名的顺序查找 标题及别名只在最近的 RecentLimit 篇中查找
+func (s *MWSession) ArticleGet(ctx context.Context, a *base.Article) error {
+	return s.postFind(ctx, articleKind, a)
+}
+
+// ArticleDel 删除文章
+func (s *MWSession) ArticleDel(ctx context.Context, a *base.Article) error {
+	return s.postDel(ctx, articleKind, a)
+}
+
+// ArticleList 文章列表 只包含最近的 RecentLimit 篇
+func (s *MWSession) ArticleList(ctx context.Context, opt *base.ListOption) ([]base.Article, int, error) {
+	return s.postList(ctx, articleKind, opt)
+}
+
+// PageNew 新建或修改页面
+func (s *MWSession) PageNew(ctx context.Context, a *base.Article) error {
+	return s.postNew(ctx, pageKind, a)
+}
+
+// PageGet 获取页面
+func (s *MWSession) PageGet(ctx context.Context, a *base.Article) error {
+	return s.postFind(ctx, pageKind, a)
+}
+
+// PageDel 删除页面
+func (s *MWSession) PageDel(ctx context.Context, a *base.Article) error {
+	return s.postDel(ctx, pageKind, a)
+}
+
+// PageList 页面列表
+func (s *MWSession) PageList(ctx context.Context, opt *base.ListOption) ([]base.Article, int, error) {
+	return s.postList(ctx, pageKind, opt)
+}
+
+// content 文章的 content_struct
+func (s *MWSession) content(ctx context.Context, k postKind, a *base.Article) (xmlrpc.Struct, error) {
+	c := xmlrpc.Struct{
+		"title":       a.Title,
+		"description": a.Content,
+		"mt_excerpt":  a.Intro,
+	}
+	if a.Alias != "" {
+		c["wp_slug"] = a.Alias
+	}
+	if !a.PostTime.IsZero() {
+		c["dateCreated"] = a.PostTime
+	}
+	if st, ok := postStatus[a.Status]; ok {
+		// 页面为 page_status
+		if k.page {
+			c["page_status"] = st
+		} else {
+			c["post_status"] = st
+		}
+	}
+	if atoi(a.AuthorID) > 0 {
+		c["wp_author_id"] = a.AuthorID
+	}
+	switch a.IsLock {
+	case base.LockOpen:
+		c["mt_allow_comments"] = 1
+	case base.LockClosed:
+		c["mt_allow_comments"] = 0
+	}
+	if k.page {
+		if a.Template != "" {
+			c["wp_page_template"] = a.Template
+		}
+		return c, nil
+	}
+	c["mt_keywords"] = strings.Join(a.Tag, ",")
+	if a.IsTop != "" {
+		c["sticky"] = a.IsTop != base.TopNone
+	}
+	if a.Cate != nil {
+		name := a.Cate.Name
+		if name == "" && atoi(a.Cate.ID) > 0 {
+			cate := &base.Category{Union: base.Union{ID: a.Cate.ID}}
+			if err := s.CategoryGet(ctx, cate); err != nil {
+				return nil, err
+			}
+			name = cate.Name
+		}
+		if name != "" {
+			c["categories"] = []string{name}
+		}
+	}
+	return c, nil
+}
+
+func (s *MWSession) postNew(ctx context.Context, k postKind, a *base.Article) error {
+	op := k.op + "New"
+	if k.page && !s.has(pageMethod) {
+		return s.unsupported(op, pageMethod)
+	}
+	if err := a.Validate(); err != nil {
+		return s.cause(op, k.newErr, err)
+	}
+	c, err := s.content(ctx, k, a)
+	if err != nil {
+		return s.cause(op, k.newErr, err)
+	}
+	publish := a.Status == "" || a.Status == base.StatusPublic
+	if a.Status == "" && atoi(a.ID) > 0 {
+		// 修改时未指定状态 保持当前状态，否则 publish 会发布草稿
+		m, err := s.getPost(ctx, k, op, a.ID)
+		if err != nil {
+			return s.cause(op, k.newErr, err)
+		}
+		key := "post_status"
+		if k.page {
+			key = "page_status"
+		}
+		if st := str(m[key]); st != "" {
+			c[key] = st
+			publish = st == "publish"
+		}
+	}
+	var v interface{}
+	switch {
+	case k.page && atoi(a.ID) > 0:
+		_, err = s.call(ctx, op, "wp.editPage", s.blogID, a.ID, s.username, s.password, c, publish)
+	case k.page:
+		v, err = s.call(ctx, op, "wp.newPage", s.blogID, s.username, s.password, c, publish)
+	case atoi(a.ID) > 0:
+		_, err = s.call(ctx, op, "metaWeblog.editPost", a.ID, s.username, s.password, c, publish)
+	default:
+		v, err = s.call(ctx, op, "metaWeblog.newPost", s.blogID, s.username, s.password, c, publish)
+	}
+	if err != nil {
+		return s.cause(op, k.newErr, err)
+	}
+	if v != nil {
+		a.ID = str(v)
+	}
+	a.Type = k.typ
+	// 新建的返回值只有ID 链接需再次读取
+	if m, err := s.getPost(ctx, k, op, a.ID); err == nil {
+		a.Permalink = permalink(m)
+	}
+	return nil
+}
+
+func (s *MWSession) getPost(ctx context.Context, k postKind, op, id string) (xmlrpc.Struct, error) {
+	var v interface{}
+	var err error
+	if k.page {
+		v, err = s.call(ctx, op, "wp.getPage", s.blogID, id, s.username, s.password)
+	} else {
+		v, err = s.call(ctx, op, "metaWeblog.getPost", id, s.username, s.password)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toStruct(v), nil
+}
+
+func (s *MWSession) recent(ctx context.Context, k postKind, op string) (xmlrpc.Array, error) {
+	var v interface{}
+	var err error
+	if k.page {
+		v, err = s.call(ctx, op, "wp.getPages", s.blogID, s.username, s.password, RecentLimit)
+	} else {
+		v, err = s.call(ctx, op, "metaWeblog.getRecentPosts", s.blogID, s.username, s.password, RecentLimit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toArray(v), nil
+}
+
+func (s *MWSession) postFind(ctx context.Context, k postKind, a *base.Article) error {
+	op := k.op + "Get"
+	if k.page && !s.has(pageMethod) {
+		return s.unsupported(op, pageMethod)
+	}
+	if atoi(a.ID) > 0 {
+		m, err := s.getPost(ctx, k, op, a.ID)
+		if err != nil {
+			return s.cause(op, k.getErr, err)
+		}
+		s.fillCate(ctx, k, m, a)
+		return nil
+	}
+	if a.Title == "" && a.Alias == "" {
+		return s.notFound(op, k.getErr)
+	}
+	list, err := s.recent(ctx, k, op)
+	if err != nil {
+		return s.cause(op, k.getErr, err)
+	}
+	for _, v := range list {
+		m := toStruct(v)
+		if (a.Title != "" && str(m["title"]) == a.Title) || (a.Title == "" && str(m["wp_slug"]) == a.Alias) {
+			s.fillCate(ctx, k, m, a)
+			return nil
+		}
+	}
+	return s.notFound(op, k.getErr)
+}
+
+func (s *MWSession) postDel(ctx context.Context, k postKind, a *base.Article) error {
+	op := k.op + "Del"
+	if k.page && !s.has(pageMethod) {
+		return s.unsupported(op, pageMethod)
+	}
+	if atoi(a.ID) <= 0 {
+		return s.invalid(op, "请指定文章的id")
+	}
+	var err error
+	if k.page {
+		_, err = s.call(ctx, op, "wp.deletePage", s.blogID, s.username, s.password, a.ID)
+	} else {
+		_, err = s.call(ctx, op, "blogger.deletePost", "", a.ID, s.username, s.password, true)
+	}
+	if err != nil {
+		return s.cause(op, k.delErr, err)
+	}
+	return nil
+}
+
+// postList 最近的文章或页面 按条件过滤后分页 ListOption.Status 为文章状态
+func (s *MWSession) postList(ctx context.Context, k postKind, opt *base.ListOption) ([]base.Article, int, error) {
+	if opt == nil {
+		opt = &base.ListOption{}
+	}
+	if k.page && !s.has(pageMethod) {
+		return nil, 0, s.unsupported(k.op+"List", pageMethod)
+	}
+	list, err := s.recent(ctx, k, k.op+"List")
+	if err != nil {
+		return nil, 0, err
+	}
+	cate := ""
+	if opt.CateID != "" && !k.page {
+		c := &base.Category{Union: base.Union{ID: opt.CateID}}
+		if err = s.CategoryGet(ctx, c); err != nil {
+			return nil, 0, err
+		}
+		cate = c.Name
+	}
+	data := make([]base.Article, 0, len(list))
+	for _, v := range list {
+		a := base.Article{}
+		fill(k, toStruct(v), &a)
+		if opt.Search != "" && !strings.Contains(a.Title, opt.Search) {
+			continue
+		}
+		if opt.Status != "" && string(a.Status) != opt.Status {
+			continue
+		}
+		if cate != "" && a.Cate.Name != cate {
+			continue
+		}
+		data = append(data, a)
+	}
+	start, end := opt.Paginate(len(data))
+	return data[start:end], len(data), nil
+}
+
+// permalink 文章的链接 WordPress 为 permaLink，其它程序多为 link
+func permalink(m xmlrpc.Struct) string {
+	if v := str(m["permaLink"]); v != "" {
+		return v
+	}
+	return str(m["link"])
+}
+
+// fill 以 getPost 的返回值填充 a 分类只有名称
+func fill(k postKind, m xmlrpc.Struct, a *base.Article) {
+	a.ID = str(m["postid"])
+	if k.page {
+		a.ID = str(m["page_id"])
+	}
+	a.Type = k.typ
+	a.Title = str(m["title"])
+	a.Content = str(m["description"])
+	if more := str(m["mt_text_more"]); more != "" {
+		a.Content += moreTag + more
+	}
+	a.Intro = str(m["mt_excerpt"])
+	a.Alias = str(m["wp_slug"])
+	a.AuthorID = str(m["wp_author_id"])
+	if a.AuthorID == "" {
+		a.AuthorID = str(m["userid"])
+	}
+	a.Template = str(m["wp_page_template"])
+	a.Permalink = permalink(m)
+	a.PostTime = postTime(m)
+	a.Status = base.StatusPublic
+	status := str(m["post_status"])
+	if k.page {
+		status = str(m["page_status"])
+	}
+	if st, ok := articleStatus[status]; ok {
+		a.Status = st
+	}
+	a.IsLock = base.LockOpen
+	if v, ok := m["mt_allow_comments"]; ok && (str(v) == "0" || str(v) == "closed") {
+		a.IsLock = base.LockClosed
+	}
+	a.IsTop = base.TopNone
+	if sticky, _ := m["sticky"].(bool); sticky {
+		a.IsTop = base.TopGlobal
+	}
+	a.Tag = make([]string, 0)
+	for _, tag := range strings.Split(str(m["mt_keywords"]), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			a.Tag = append(a.Tag, tag)
+		}
+	}
+	a.Cate = &base.Category{}
+	if cates := toArray(m["categories"]); len(cates) > 0 && !k.page {
+		a.Cate.Name = str(cates[0])
+	}
+}
+
+// fillCate 填充 a 并按名称查找分类ID 查找失败时只有名称
+func (s *MWSession) fillCate(ctx context.Context, k postKind, m xmlrpc.Struct, a *base.Article) {
+	fill(k, m, a)
+	if a.Cate.Name != "" {
+		_ = s.CategoryGet(ctx, a.Cate)
+	}
+}
+
+// postTime 发布时间 优先使用 date_created_gmt，WordPress 返回的时间不带时区
+func postTime(m xmlrpc.Struct) time.Time {
+	if t, ok := m["date_created_gmt"].(time.Time); ok && !t.IsZero() {
+		if t.Location() == time.Local {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+		}
+		return t.Local()
+	}
+	t, _ := m["dateCreated"].(time.Time)
+	return t
+}
@@ -0,0 +1,71 @@
+package metaweblog
+
+import (
+	"context"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"github.com/cgghui/bt_site_cluster_program_api/base/xmlrpc"
+)
+
+// 站点设置对应的 wp.getOptions 选项 其它可写的选项以 SiteSetting.Extra 设置
+const (
+	optionTitle   = "blog_title"
+	optionTagline = "blog_tagline"
+	optionComment = "default_comment_status"
+)
+
+// SiteSettingGet 以 wp.getOptions 读取站点设置 未对应到字段的选项存入 SiteSetting.Extra
+func (s *MWSession) SiteSettingGet(ctx context.Context, ss *base.SiteSetting) error {
+	if !s.has("wp.getOptions") {
+		return s.unsupported("SiteSettingGet", "wp.getOptions")
+	}
+	v, err := s.call(ctx, "SiteSettingGet", "wp.getOptions", s.blogID, s.username, s.password)
+	if err != nil {
+		return s.cause("SiteSettingGet", base.SiteSettingErr, err)
+	}
+	ss.Extra = make(map[string]string)
+	for k, o := range toStruct(v) {
+		value := str(toStruct(o)["value"])
+		switch k {
+		case optionTitle:
+			ss.SiteName = value
+		case optionTagline:
+			ss.SubSiteName = value
+		case optionComment:
+			off := value == "closed"
+			ss.CommentOff = &off
+		default:
+			ss.Extra[k] = value
+		}
+	}
+	return nil
+}
+
+// SiteSetting 以 wp.setOptions 设置站点 只提交已指定的选项
+func (s *MWSession) SiteSetting(ctx context.Context, ss *base.SiteSetting) error {
+	options := make(xmlrpc.Struct)
+	for k, v := range ss.Extra {
+		options[k] = v
+	}
+	if ss.SiteName != "" {
+		options[optionTitle] = ss.SiteName
+	}
+	if ss.SubSiteName != "" {
+		options[optionTagline] = ss.SubSiteName
+	}
+	if ss.CommentOff != nil {
+		options[optionComment] = "open"
+		if *ss.CommentOff {
+			options[optionComment] = "closed"
+		}
+	}
+	if len(options) == 0 {
+		return nil
+	}
+	if !s.has("wp.setOptions") {
+		return s.unsupported("SiteSetting", "wp.setOptions")
+	}
+	if _, err := s.call(ctx, "SiteSetting", "wp.setOptions", s.blogID, s.username, s.password, options); err != nil {
+		return s.cause("SiteSetting", base.SiteSettingErr, err)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package metaweblog
+
+import (
+	"context"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"github.com/cgghui/bt_site_cluster_program_api/base/xmlrpc"
+	"strings"
+)
+
+// WordPress 的分类法
+const (
+	taxonomyCategory = "category"
+	taxonomyTag      = "post_tag"
+)
+
+// term 分类或标签
+type term struct {
+	id          string
+	name        string
+	slug        string
+	description string
+	parent      string
+}
+
+func (t *term) category() base.Category {
+	return base.Category{
+		Union:    base.Union{ID: t.id, Type: "0"},
+		Name:     t.name,
+		Alias:    t.slug,
+		ParentID: atoi(t.parent),
+		Intro:    t.description,
+	}
+}
+
+func (t *term) tag() base.Tag {
+	return base.Tag{
+		Union: base.Union{ID: t.id, Type: "0"},
+		Name:  t.name,
+		Alias: t.slug,
+		Intro: t.description,
+	}
+}
+
+// terms 分类或标签的全部项 优先使用 wp.getTerms，
+// 否则分类使用 metaWeblog.getCategories，标签使用 wp.getTags
+func (s *MWSession) terms(ctx context.Context, op, taxonomy string) ([]term, error) {
+	data := make([]term, 0)
+	if s.has("wp.getTerms") {
+		v, err := s.call(ctx, op, "wp.getTerms", s.blogID, s.username, s.password, taxonomy, xmlrpc.Struct{"hide_empty": false})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range toArray(v) {
+			m := toStruct(item)
+			data = append(data, term{id: str(m["term_id"]), name: str(m["name"]), slug: str(m["slug"]), description: str(m["description"]), parent: str(m["parent"])})
+		}
+		return data, nil
+	}
+	var v interface{}
+	var err error
+	switch {
+	case taxonomy == taxonomyCategory:
+		v, err = s.call(ctx, op, "metaWeblog.getCategories", s.blogID, s.username, s.password)
+	case s.has("wp.getTags"):
+		v, err = s.call(ctx, op, "wp.getTags", s.blogID, s.username, s.password)
+	default:
+		return nil, s.unsupported(op, "wp.getTags")
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range toArray(v) {
+		m := toStruct(item)
+		t := term{id: str(m["categoryId"]), name: str(m["categoryName"]), description: str(m["categoryDescription"]), parent: str(m["parentId"])}
+		if taxonomy == taxonomyTag {
+			t = term{id: str(m["tag_id"]), name: str(m["name"]), slug: str(m["slug"])}
+		}
+		if t.name == "" {
+			// 部分程序的 getCategories 只返回 title 及 description
+			t.name = str(m["title"])
+		}
+		data = append(data, t)
+	}
+	return data, nil
+}
+
+// termFind 按ID或名称查找 未找到时返回nil
+func (s *MWSession) termFind(ctx context.Context, op, taxonomy, id, name string) (*term, error) {
+	list, err := s.terms(ctx, op, taxonomy)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		if (id != "" && id != "0" && list[i].id == id) || ((id == "" || id == "0") && name != "" && list[i].name == name) {
+			return &list[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// termSave 以 wp.newTerm 或 wp.editTerm 保存 返回ID
+func (s *MWSession) termSave(ctx context.Context, op, taxonomy, id string, content xmlrpc.Struct) (string, error) {
+	content["taxonomy"] = taxonomy
+	if atoi(id) > 0 {
+		if !s.has("wp.editTerm") {
+			return "", s.unsupported(op, "wp.editTerm")
+		}
+		_, err := s.call(ctx, op, "wp.editTerm", s.blogID, s.username, s.password, id, content)
+		return id, err
+	}
+	if s.has("wp.newTerm") {
+		v, err := s.call(ctx, op, "wp.newTerm", s.blogID, s.username, s.password, content)
+		return str(v), err
+	}
+	if taxonomy == taxonomyCategory && s.has("wp.newCategory") {
+		v, err := s.call(ctx, op, "wp.newCategory", s.blogID, s.username, s.password, xmlrpc.Struct{
+			"name": content["name"], "slug": content["slug"], "description": content["description"], "parent_id": content["parent"],
+		})
+		return str(v), err
+	}
+	return "", s.unsupported(op, "wp.newTerm")
+}
+
+func (s *MWSession) termDel(ctx context.Context, op, taxonomy, id string) error {
+	if s.has("wp.deleteTerm") {
+		_, err := s.call(ctx, op, "wp.deleteTerm", s.blogID, s.username, s.password, taxonomy, id)
+		return err
+	}
+	if taxonomy == taxonomyCategory && s.has("wp.deleteCategory") {
+		_, err := s.call(ctx, op, "wp.deleteCategory", s.blogID, s.username, s.password, id)
+		return err
+	}
+	return s.unsupported(op, "wp.deleteTerm")
+}
+
+// CategoryNew 新建或修改分类 同名分类已存在时修改该分类
+func (s *MWSession) CategoryNew(ctx context.Context, c *base.Category) error {
+	id := c.ID
+	if atoi(id) <= 0 {
+		t, err := s.termFind(ctx, "CategoryNew", taxonomyCategory, "", c.Name)
+		if err != nil {
+			return s.cause("CategoryNew", base.CategoryNewErr, err)
+		}
+		if t != nil {
+			id = t.id
+		}
+	}
+	content := xmlrpc.Struct{"name": c.Name, "description": c.Intro, "parent": c.ParentID}
+	if c.Alias != "" {
+		content["slug"] = c.Alias
+	}
+	id, err := s.termSave(ctx, "CategoryNew", taxonomyCategory, id, content)
+	if err != nil {
+		return s.cause("CategoryNew", base.CategoryNewErr, err)
+	}
+	c.ID = id
+	return nil
+}
+
+// CategoryGet 按 ID 或名称查找分类
+func (s *MWSession) CategoryGet(ctx context.Context, c *base.Category) error {
+	t, err := s.termFind(ctx, "CategoryGet", taxonomyCategory, c.ID, c.Name)
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return s.notFound("CategoryGet", base.CategoryGetErr)
+	}
+	*c = t.category()
+	return nil
+}
+
+// CategoryDel 删除分类
+func (s *MWSession) CategoryDel(ctx context.Context, c *base.Category) error {
+	if atoi(c.ID) <= 0 {
+		return s.invalid("CategoryDel", "请指定分类的id")
+	}
+	if err := s.termDel(ctx, "CategoryDel", taxonomyCategory, c.ID); err != nil {
+		return s.cause("CategoryDel", base.CategoryDelErr, err)
+	}
+	return nil
+}
+
+// CategoryList 分类列表
+func (s *MWSession) CategoryList(ctx context.Context, opt *base.ListOption) ([]base.Category, int, error) {
+	list, err := s.terms(ctx, "CategoryList", taxonomyCategory)
+	if err != nil {
+		return nil, 0, err
+	}
+	data := make([]base.Category, 0, len(list))
+	for i := range list {
+		if opt != nil && opt.Search != "" && !strings.Contains(list[i].name, opt.Search) {
+			continue
+		}
+		data = append(data, list[i].category())
+	}
+	start, end := opt.Paginate(len(data))
+	return data[start:end], len(data), nil
+}
+
+// TagNew 新建或修改标签 同名标签已存在时视为成功，并填充 Tag.ID
+// 不支持 wp.newTerm 的程序只能在发布文章时创建标签
+func (s *MWSession) TagNew(ctx context.Context, t *base.Tag) error {
+	if atoi(t.ID) <= 0 {
+		found, err := s.termFind(ctx, "TagNew", taxonomyTag, "", t.Name)
+		if err != nil {
+			return s.cause("TagNew", base.TagNewErr, err)
+		}
+		if found != nil {
+			t.ID = found.id
+			return nil
+		}
+	}
+	content := xmlrpc.Struct{"name": t.Name, "description": t.Intro}
+	if t.Alias != "" {
+		content["slug"] = t.Alias
+	}
+	id, err := s.termSave(ctx, "TagNew", taxonomyTag, t.ID, content)
+	if err != nil {
+		return s.cause("TagNew", base.TagNewErr, err)
+	}
+	t.ID = id
+	return nil
+}
+
+// TagGet 按 ID 或名称查找标签
+func (s *MWSession) TagGet(ctx context.Context, t *base.Tag) error {
+	found, err := s.termFind(ctx, "TagGet", taxonomyTag, t.ID, t.Name)
+	if err != nil {
+		return err
+	}
+	if found == nil {
+		return s.notFound("TagGet", base.TagUndefinedErr)
+	}
+	*t = found.tag()
+	return nil
+}
+
+// TagDel 删除标签 未指定ID时按名称查找
+func (s *MWSession) TagDel(ctx context.Context, t *base.Tag) error {
+	if atoi(t.ID) <= 0 {
+		if err := s.TagGet(ctx, t); err != nil {
+			return err
+		}
+	}
+	if err := s.termDel(ctx, "TagDel", taxonomyTag, t.ID); err != nil {
+		return s.cause("TagDel", base.TagDelErr, err)
+	}
+	return nil
+}
+
+// TagList 标签列表
+func (s *MWSession) TagList(ctx context.Context, opt *base.ListOption) ([]base.Tag, int, error) {
+	list, err := s.terms(ctx, "TagList", taxonomyTag)
+	if err != nil {
+		return nil, 0, err
+	}
+	data := make([]base.Tag, 0, len(list))
+	for i := range list {
+		if opt != nil && opt.Search != "" && !strings.Contains(list[i].name, opt.Search) {
+			continue
+		}
+		data = append(data, list[i].tag())
+	}
+	start, end := opt.Paginate(len(data))
+	return data[start:end], len(data), nil
+}
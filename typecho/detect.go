@@ -0,0 +1,25 @@
+package typecho
+
+import (
+	"context"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"regexp"
+)
+
+// versionRegexp 匹配 generator 中的版本 如 Typecho 1.2.1
+var versionRegexp = regexp.MustCompile(`Typecho\s*([\d.]+)`)
+
+// Detect 识别 Typecho 依次检查 meta generator 及主题路径、页脚版权
+func Detect(ctx context.Context, home *base.Fingerprint) *base.Detection {
+	d := &base.Detection{Program: "typecho", BackstagePath: "admin/", LoginPath: "login.php"}
+	if m := versionRegexp.FindStringSubmatch(home.Generator()); m != nil {
+		d.Version = m[1]
+		d.Score = 100
+		return d
+	}
+	if home.Contains("usr/themes/") || home.Contains("Powered by Typecho") {
+		d.Score = 60
+		return d
+	}
+	return nil
+}
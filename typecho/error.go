@@ -0,0 +1,115 @@
+package typecho
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// wrap 包装请求过程中的错误
+func (s *TCSession) wrap(op string, req *http.Request, err error) error {
+	e := &base.Error{Op: op, Site: s.tc.HomeURL, Err: err}
+	if req != nil {
+		e.Endpoint = endpoint(req.Method, req.URL)
+	}
+	return e
+}
+
+// fail 根据响应构造错误 body 为已读取的响应内容，未传入时尝试从 resp.Body 读取
+func (s *TCSession) fail(op string, sentinel error, resp *http.Response, body ...[]byte) *base.Error {
+	e := &base.Error{Op: op, Site: s.tc.HomeURL, Sentinel: sentinel, Status: resp.StatusCode}
+	if resp.Request != nil {
+		e.Endpoint = endpoint(resp.Request.Method, resp.Request.URL)
+	}
+	var b []byte
+	if len(body) > 0 {
+		b = body[0]
+	} else {
+		b, _ = ioutil.ReadAll(io.LimitReader(resp.Body, base.ErrorBodyLimit))
+	}
+	if len(b) > base.ErrorBodyLimit {
+		b = b[:base.ErrorBodyLimit]
+	}
+	e.Body = string(b)
+	return e
+}
+
+// cause 以 sentinel 包装错误 同一操作中返回的错误直接补充 Sentinel
+func (s *TCSession) cause(op string, sentinel, err error) error {
+	var e *base.Error
+	if errors.As(err, &e) && e.Sentinel == nil && e.Op == op {
+		e.Sentinel = sentinel
+		return e
+	}
+	return &base.Error{Op: op, Site: s.tc.HomeURL, Sentinel: sentinel, Err: err}
+}
+
+// notFound 查找失败
+func (s *TCSession) notFound(op string, sentinel error) error {
+	return &base.Error{Op: op, Site: s.tc.HomeURL, Sentinel: sentinel}
+}
+
+// invalid 参数错误
+func (s *TCSession) invalid(op, msg string) error {
+	return &base.Error{Op: op, Site: s.tc.HomeURL, Kind: base.KindValidation, Err: errors.New(msg)}
+}
+
+// endpoint 去除安全令牌的请求地址
+func endpoint(method string, u *url.URL) string {
+	q := u.Query()
+	q.Del("_")
+	if len(q) == 0 {
+		return method + " " + u.Path
+	}
+	return method + " " + u.Path + "?" + q.Encode()
+}
+
+// notice 提交后 Typecho 以cookie返回的提示 cookie 名称可能带有站点前缀
+type notice struct {
+	typ       string   // success notice error
+	messages  []string // 提示内容
+	highlight string   // 新建或修改的对象 如 post-12 category-3
+	location  string   // 提交后跳转的地址
+}
+
+func readNotice(resp *http.Response) *notice {
+	n := &notice{location: resp.Header.Get("Location")}
+	for _, c := range resp.Cookies() {
+		value, err := url.QueryUnescape(c.Value)
+		if err != nil {
+			value = c.Value
+		}
+		switch {
+		case strings.HasSuffix(c.Name, "__typecho_notice_type"):
+			n.typ = value
+		case strings.HasSuffix(c.Name, "__typecho_notice_highlight"):
+			n.highlight = value
+		case strings.HasSuffix(c.Name, "__typecho_notice"):
+			if json.Unmarshal([]byte(value), &n.messages) != nil && value != "" {
+				n.messages = []string{value}
+			}
+		}
+	}
+	return n
+}
+
+// err 提示内容 没有提示时为nil
+func (n *notice) err() error {
+	if len(n.messages) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(n.messages, "; "))
+}
+
+// id 新建或修改的对象的ID 如 post-12 中的12
+func (n *notice) id(prefix string) string {
+	if strings.HasPrefix(n.highlight, prefix+"-") {
+		return n.highlight[len(prefix)+1:]
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package typecho
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// formOf 页面中提交到 widget 的表单
+func formOf(doc *goquery.Document, widget string) *goquery.Selection {
+	return doc.Find("form[action]").FilterFunction(func(_ int, f *goquery.Selection) bool {
+		u, err := url.Parse(f.AttrOr("action", ""))
+		return err == nil && strings.HasSuffix(u.Path, "/action/"+widget)
+	}).First()
+}
+
+// formValues 表单中各字段的当前值 与浏览器提交时相同，未选中的复选框及单选框不包含在内
+func formValues(form *goquery.Selection) url.Values {
+	values := url.Values{}
+	form.Find("input[name], select[name], textarea[name]").Each(func(_ int, field *goquery.Selection) {
+		name := field.AttrOr("name", "")
+		if _, disabled := field.Attr("disabled"); disabled {
+			return
+		}
+		switch goquery.NodeName(field) {
+		case "textarea":
+			values.Add(name, field.Text())
+		case "select":
+			opt := field.Find("option[selected]")
+			if opt.Length() == 0 {
+				opt = field.Find("option").First()
+			}
+			opt.Each(func(_ int, o *goquery.Selection) {
+				values.Add(name, o.AttrOr("value", o.Text()))
+			})
+		default:
+			switch field.AttrOr("type", "text") {
+			case "checkbox", "radio":
+				if _, checked := field.Attr("checked"); checked {
+					values.Add(name, field.AttrOr("value", "on"))
+				}
+			case "submit", "button", "file":
+			default:
+				values.Add(name, field.AttrOr("value", ""))
+			}
+		}
+	})
+	return values
+}
+
+// parseTime 解析后台显示的时间 失败返回零值
+func parseTime(s string, loc *time.Location) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02", "2006年01月02日", "2006年1月2日"} {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
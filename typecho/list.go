@@ -0,0 +1,173 @@
+package typecho
+
+import (
+	"context"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var pageRegexp = regexp.MustCompile(`[?&]page=(\d+)`)
+
+// walk 遍历后台列表页的所有分页 fn 接收每一页
+func (s *TCSession) walk(ctx context.Context, op, uri string, query url.Values, fn func(*adminPage)) error {
+	for page, last := 1, 1; page <= last; page++ {
+		q := url.Values{}
+		for k, v := range query {
+			if len(v) > 0 && v[0] != "" {
+				q[k] = v
+			}
+		}
+		if page > 1 {
+			q.Set("page", strconv.Itoa(page))
+		}
+		p, err := s.page(ctx, op, uri, q)
+		if err != nil {
+			return err
+		}
+		fn(p)
+		p.doc.Find(".typecho-pager a[href]").Each(func(_ int, a *goquery.Selection) {
+			m := pageRegexp.FindStringSubmatch(a.AttrOr("href", ""))
+			if m == nil {
+				return
+			}
+			if n, _ := strconv.Atoi(m[1]); n > last {
+				last = n
+			}
+		})
+	}
+	return nil
+}
+
+// ArticleList 文章列表 ListOption.Status 在读取后过滤
+func (s *TCSession) ArticleList(ctx context.Context, opt *base.ListOption) ([]base.Article, int, error) {
+	return s.postList(ctx, articleKind, opt)
+}
+
+// PageList 页面列表
+func (s *TCSession) PageList(ctx context.Context, opt *base.ListOption) ([]base.Article, int, error) {
+	return s.postList(ctx, pageKind, opt)
+}
+
+func (s *TCSession) postList(ctx context.Context, k postKind, opt *base.ListOption) ([]base.Article, int, error) {
+	if opt == nil {
+		opt = &base.ListOption{}
+	}
+	query := url.Values{}
+	query.Set("keywords", opt.Search)
+	if !k.page() {
+		query.Set("category", opt.CateID)
+	}
+	zone := s.location(ctx)
+	data := make([]base.Article, 0)
+	err := s.walk(ctx, k.op+"List", k.manage, query, func(p *adminPage) {
+		p.doc.Find(`tr[id^="` + k.prefix + `-"]`).Each(func(_ int, tr *goquery.Selection) {
+			a := postRow(k, tr, zone)
+			// 后台搜索同时匹配正文，这里只保留标题匹配的
+			if opt.Search != "" && !strings.Contains(a.Title, opt.Search) {
+				return
+			}
+			if opt.Status != "" && string(a.Status) != opt.Status {
+				return
+			}
+			data = append(data, a)
+		})
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	start, end := opt.Paginate(len(data))
+	return data[start:end], len(data), nil
+}
+
+// CategoryList 分类列表 包含各级子分类
+func (s *TCSession) CategoryList(ctx context.Context, opt *base.ListOption) ([]base.Category, int, error) {
+	if opt == nil {
+		opt = &base.ListOption{}
+	}
+	all, err := s.categories(ctx, "CategoryList")
+	if err != nil {
+		return nil, 0, err
+	}
+	data := make([]base.Category, 0, len(all))
+	for _, c := range all {
+		if opt.Search != "" && !strings.Contains(c.Name, opt.Search) {
+			continue
+		}
+		data = append(data, c)
+	}
+	start, end := opt.Paginate(len(data))
+	return data[start:end], len(data), nil
+}
+
+// categories 全部分类 后台每页只列出同一父级的分类，有子分类时逐级读取
+func (s *TCSession) categories(ctx context.Context, op string) ([]base.Category, error) {
+	data := make([]base.Category, 0)
+	parents := []int{0}
+	for len(parents) > 0 {
+		parent := parents[0]
+		parents = parents[1:]
+		query := url.Values{}
+		if parent > 0 {
+			query.Set("parent", strconv.Itoa(parent))
+		}
+		err := s.walk(ctx, op, "manage-categories.php", query, func(p *adminPage) {
+			p.doc.Find(`tr[id^="mid-category-"]`).Each(func(_ int, tr *goquery.Selection) {
+				c := base.Category{
+					Union:    base.Union{ID: tr.Find(`input[name="mid[]"]`).AttrOr("value", ""), Type: "0"},
+					Name:     strings.TrimSpace(tr.Find(`a[href*="category.php?mid="]`).First().Text()),
+					Alias:    strings.TrimSpace(tr.Find("td").Eq(3).Text()),
+					ParentID: parent,
+				}
+				data = append(data, c)
+				if tr.Find(`a[href*="manage-categories.php?parent="]`).Length() > 0 {
+					id, _ := strconv.Atoi(c.ID)
+					parents = append(parents, id)
+				}
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// TagList 标签列表
+func (s *TCSession) TagList(ctx context.Context, opt *base.ListOption) ([]base.Tag, int, error) {
+	if opt == nil {
+		opt = &base.ListOption{}
+	}
+	all, err := s.tags(ctx, "TagList")
+	if err != nil {
+		return nil, 0, err
+	}
+	data := make([]base.Tag, 0, len(all))
+	for _, t := range all {
+		if opt.Search != "" && !strings.Contains(t.Name, opt.Search) {
+			continue
+		}
+		data = append(data, t)
+	}
+	start, end := opt.Paginate(len(data))
+	return data[start:end], len(data), nil
+}
+
+// tags 全部标签 标签页不分页，列表中没有别名
+func (s *TCSession) tags(ctx context.Context, op string) ([]base.Tag, error) {
+	p, err := s.page(ctx, op, "manage-tags.php", nil)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]base.Tag, 0)
+	p.doc.Find(`li[id^="tag-"]`).Each(func(_ int, li *goquery.Selection) {
+		data = append(data, base.Tag{
+			Union: base.Union{ID: li.Find(`input[name="mid[]"]`).AttrOr("value", ""), Type: "0"},
+			Name:  strings.TrimSpace(li.Find("span").First().Text()),
+		})
+	})
+	return data, nil
+}
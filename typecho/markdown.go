@@ -0,0 +1,298 @@
+package typecho
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// markdown 将 HTML 正文转换为 Typecho 使用的 Markdown
+// 只转换常用标签，表格等 Markdown 无法表达的内容保留为 HTML，<!--more--> 摘要分隔原样保留
+func markdown(content string) string {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(content), body)
+	if err != nil {
+		return content
+	}
+	for _, n := range nodes {
+		body.AppendChild(n)
+	}
+	m := &mdWriter{}
+	m.blocks(body, "")
+	return strings.TrimSpace(blankRegexp.ReplaceAllString(m.b.String(), "\n\n"))
+}
+
+var blankRegexp = regexp.MustCompile(`\n{3,}`)
+
+var spaceRegexp = regexp.MustCompile(`\s+`)
+
+// mdEscape Markdown 中有特殊含义的字符 < 与 & 按 HTML 转义
+var mdEscape = strings.NewReplacer(`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, `<`, `&lt;`, `&`, `&amp;`)
+
+// orderedRegexp 会被当作有序列表的行首
+var orderedRegexp = regexp.MustCompile(`^(\d+)([.)])(\s|$)`)
+
+// blockEscape 转义行首会被当作标题、列表、引用或分隔线的字符
+func blockEscape(line string) string {
+	switch {
+	case strings.HasPrefix(line, "#"), strings.HasPrefix(line, ">"):
+		return `\` + line
+	case strings.HasPrefix(line, "- "), strings.HasPrefix(line, "+ "), line == "-", line == "+":
+		return `\` + line
+	case strings.Trim(line, "-= ") == "" && line != "":
+		// 独立一行的 --- 或 === 是分隔线或上一行的标题
+		return `\` + line
+	}
+	return orderedRegexp.ReplaceAllString(line, `$1\$2$3`)
+}
+
+type mdWriter struct {
+	b strings.Builder
+}
+
+// para 以 prefix 为每行前缀写入一个段落
+func (m *mdWriter) para(text, prefix string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(prefix+strings.TrimSpace(line), " ")
+		if i < len(lines)-1 && strings.HasSuffix(line, "  ") {
+			// <br> 转换的硬换行
+			lines[i] += "  "
+		}
+	}
+	m.b.WriteString(strings.Join(lines, "\n"))
+	m.b.WriteString("\n" + strings.TrimRight(prefix, " ") + "\n")
+}
+
+// text 以 prefix 为每行前缀写入正文段落 行首的标记字符加上转义
+func (m *mdWriter) text(text, prefix string) {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		lines[i] = blockEscape(strings.TrimLeft(line, " "))
+	}
+	m.para(strings.Join(lines, "\n"), prefix)
+}
+
+// block 块级元素 连续的行内内容合并为一个段落
+func (m *mdWriter) block(n *html.Node, prefix string) {
+	switch n.Type {
+	case html.TextNode:
+		m.text(inline(n), prefix)
+		return
+	case html.CommentNode:
+		if strings.TrimSpace(n.Data) == "more" {
+			m.b.WriteString(prefix + "<!--more-->\n\n")
+		}
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+	switch n.DataAtom {
+	case atom.Script, atom.Style:
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		m.para(strings.Repeat("#", level)+" "+strings.TrimSpace(children(n)), prefix)
+	case atom.P:
+		m.text(children(n), prefix)
+	case atom.Hr:
+		m.para("---", prefix)
+	case atom.Blockquote:
+		m.blocks(n, prefix+"> ")
+		// 去掉引用中最后一个段落后的空引用行
+		out := strings.TrimSuffix(m.b.String(), strings.TrimRight(prefix+"> ", " ")+"\n")
+		m.b.Reset()
+		m.b.WriteString(out + "\n")
+	case atom.Pre:
+		m.pre(n, prefix)
+	case atom.Ul, atom.Ol:
+		m.list(n, prefix, 0)
+		m.b.WriteString(strings.TrimRight(prefix, " ") + "\n")
+	case atom.Table:
+		var b strings.Builder
+		_ = html.Render(&b, n)
+		m.para(b.String(), prefix)
+	case atom.Div, atom.Section, atom.Article, atom.Figure, atom.Header, atom.Footer, atom.Main, atom.Aside:
+		m.blocks(n, prefix)
+	default:
+		m.text(inline(n), prefix)
+	}
+}
+
+// blocks 子节点 相邻的行内节点合并为一个段落
+func (m *mdWriter) blocks(n *html.Node, prefix string) {
+	var run strings.Builder
+	flush := func() {
+		m.text(run.String(), prefix)
+		run.Reset()
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if isBlock(c) {
+			flush()
+			m.block(c, prefix)
+			continue
+		}
+		run.WriteString(inline(c))
+	}
+	flush()
+}
+
+// pre 代码块 class 为 language-xxx 时标注语言
+func (m *mdWriter) pre(n *html.Node, prefix string) {
+	lang := ""
+	code := n
+	if c := n.FirstChild; c != nil && c.DataAtom == atom.Code && c.NextSibling == nil {
+		code = c
+	}
+	for _, class := range strings.Fields(attr(code, "class")) {
+		if strings.HasPrefix(class, "language-") {
+			lang = strings.TrimPrefix(class, "language-")
+		}
+	}
+	text := strings.TrimRight(strings.TrimPrefix(textOf(code), "\n"), "\n")
+	lines := []string{prefix + "```" + lang}
+	for _, line := range strings.Split(text, "\n") {
+		lines = append(lines, prefix+line)
+	}
+	lines = append(lines, prefix+"```")
+	m.b.WriteString(strings.Join(lines, "\n") + "\n\n")
+}
+
+// list 列表 嵌套的列表缩进四个空格
+func (m *mdWriter) list(n *html.Node, prefix string, depth int) {
+	index := 0
+	indent := prefix + strings.Repeat("    ", depth)
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.DataAtom != atom.Li {
+			continue
+		}
+		index++
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(index) + ". "
+		}
+		var text strings.Builder
+		var nested []*html.Node
+		for c := li.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && (c.DataAtom == atom.Ul || c.DataAtom == atom.Ol) {
+				nested = append(nested, c)
+				continue
+			}
+			if c.Type == html.ElementNode && c.DataAtom == atom.P {
+				text.WriteString(children(c) + " ")
+				continue
+			}
+			text.WriteString(inline(c))
+		}
+		m.b.WriteString(indent + marker + blockEscape(strings.TrimSpace(text.String())) + "\n")
+		for _, c := range nested {
+			m.list(c, prefix, depth+1)
+		}
+	}
+}
+
+// isBlock 是否块级节点
+func isBlock(n *html.Node) bool {
+	if n.Type == html.CommentNode {
+		return strings.TrimSpace(n.Data) == "more"
+	}
+	if n.Type != html.ElementNode {
+		return false
+	}
+	switch n.DataAtom {
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Figure, atom.Header, atom.Footer, atom.Main, atom.Aside,
+		atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Ul, atom.Ol, atom.Blockquote, atom.Pre, atom.Hr,
+		atom.Table, atom.Script, atom.Style:
+		return true
+	}
+	return false
+}
+
+// children 子节点的行内内容
+func children(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(inline(c))
+	}
+	return b.String()
+}
+
+// inline 行内节点 文本中的连续空白合并为一个空格
+func inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return mdEscape.Replace(spaceRegexp.ReplaceAllString(n.Data, " "))
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	switch n.DataAtom {
+	case atom.Script, atom.Style:
+		return ""
+	case atom.Br:
+		return "  \n"
+	case atom.Strong, atom.B:
+		return wrapText("**", children(n))
+	case atom.Em, atom.I:
+		return wrapText("*", children(n))
+	case atom.Del, atom.S:
+		return wrapText("~~", children(n))
+	case atom.Code:
+		return "`" + textOf(n) + "`"
+	case atom.A:
+		text := strings.TrimSpace(children(n))
+		href := attr(n, "href")
+		if href == "" {
+			return text
+		}
+		if title := attr(n, "title"); title != "" {
+			return "[" + text + "](" + href + ` "` + title + `")`
+		}
+		return "[" + text + "](" + href + ")"
+	case atom.Img:
+		return "![" + attr(n, "alt") + "](" + attr(n, "src") + ")"
+	}
+	return children(n)
+}
+
+// wrapText 以 mark 包围文本 首尾空白移到标记外
+func wrapText(mark, text string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	left := text[:strings.Index(text, trimmed)]
+	right := text[len(left)+len(trimmed):]
+	return left + mark + trimmed + mark + right
+}
+
+// textOf 节点的原始文本 用于代码
+func textOf(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.DataAtom == atom.Br {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(textOf(c))
+	}
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
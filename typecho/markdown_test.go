@@ -0,0 +1,34 @@
+package typecho
+
+import "testing"
+
+func TestMarkdown(t *testing.T) {
+	cases := []struct {
+		html string
+		want string
+	}{
+		{`<p>hello <b>world</b></p><p>second</p>`, "hello **world**\n\nsecond"},
+		{`plain <em>text</em> with *star*`, `plain *text* with \*star\*`},
+		{`<h3>title</h3><hr><p>a<br>b</p>`, "### title\n\n---\n\na  \nb"},
+		{`<p><a href="/x" title="t">link</a> <img src="/a.png" alt="pic"></p>`, `[link](/x "t") ![pic](/a.png)`},
+		{`<ul><li>one<ul><li>two</li></ul></li><li>three</li></ul><ol><li>first</li><li>second</li></ol>`, "- one\n    - two\n- three\n\n1. first\n2. second"},
+		{`<blockquote><p>quote</p><p>more</p></blockquote><p>after</p>`, "> quote\n>\n> more\n\nafter"},
+		{`<pre><code class="language-go">if a < b {
+	return
+}</code></pre>`, "```go\nif a < b {\n\treturn\n}\n```"},
+		{`<p>intro</p><!--more--><p>body</p>`, "intro\n\n<!--more-->\n\nbody"},
+		{`<div><p>in div</p><script>alert(1)</script></div><p>x &lt; y &amp; <code>a_b</code></p>`, "in div\n\nx &lt; y &amp; `a_b`"},
+		{`<table><tr><td>1</td></tr></table>`, "<table><tbody><tr><td>1</td></tr></tbody></table>"},
+		{`<p>#话题# 正文</p>`, `\#话题# 正文`},
+		{`<p>- 不是列表</p><p>+ 也不是</p><p>-5度</p>`, "\\- 不是列表\n\n\\+ 也不是\n\n-5度"},
+		{`<p>> 不是引用</p><p>1. 不是有序列表</p><p>2) 也不是</p><p>2020.5 年</p>`, "\\> 不是引用\n\n1\\. 不是有序列表\n\n2\\) 也不是\n\n2020.5 年"},
+		{`<p>标题<br>===</p><p>---</p>`, "标题  \n\\===\n\n\\---"},
+		{`<p>第一行<br># 第二行</p><blockquote><p>#引用</p></blockquote>`, "第一行  \n\\# 第二行\n\n> \\#引用"},
+		{`<ul><li># 项目</li></ul>`, "- \\# 项目"},
+	}
+	for _, c := range cases {
+		if got := markdown(c.html); got != c.want {
+			t.Errorf("%s\n得到 %q\n期望 %q", c.html, got, c.want)
+		}
+	}
+}
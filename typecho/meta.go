@@ -0,0 +1,211 @@
+package typecho
+
+import (
+	"context"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"net/url"
+	"strconv"
+)
+
+// CategoryNew 新建或修改分类 未指定ID且同名分类已存在时修改该分类，成功后填充 Category.ID
+// Typecho 的分类只有名称、缩略名、父级及描述，排序及模板不提交
+func (s *TCSession) CategoryNew(ctx context.Context, c *base.Category) error {
+	id := c.ID
+	if !validID(id) {
+		all, err := s.categories(ctx, "CategoryNew")
+		if err != nil {
+			return s.cause("CategoryNew", base.CategoryNewErr, err)
+		}
+		for _, cate := range all {
+			if cate.Name == c.Name {
+				id = cate.ID
+				break
+			}
+		}
+	}
+	query := url.Values{}
+	if validID(id) {
+		query.Set("mid", id)
+	}
+	n, err := s.submit(ctx, "CategoryNew", "category.php", query, "metas-category-edit", nil, func(p *adminPage) (url.Values, error) {
+		param := formValues(formOf(p.doc, "metas-category-edit"))
+		param.Set("name", c.Name)
+		if c.Alias != "" || !validID(id) {
+			param.Set("slug", c.Alias)
+		}
+		param.Set("parent", strconv.Itoa(c.ParentID))
+		param.Set("description", c.Intro)
+		if validID(id) {
+			param.Set("do", "update")
+			param.Set("mid", id)
+		} else {
+			param.Set("do", "insert")
+			param.Del("mid")
+		}
+		return param, nil
+	})
+	if err != nil {
+		return s.cause("CategoryNew", base.CategoryNewErr, err)
+	}
+	if v := n.id("category"); v != "" {
+		id = v
+	}
+	if !validID(id) {
+		got := &base.Category{Name: c.Name}
+		if err = s.CategoryGet(ctx, got); err != nil {
+			return s.cause("CategoryNew", base.CategoryNewErr, err)
+		}
+		id = got.ID
+	}
+	c.ID = id
+	return nil
+}
+
+// CategoryGet 按 ID 或名称查找分类 找到后读取编辑页填充全部字段
+func (s *TCSession) CategoryGet(ctx context.Context, c *base.Category) error {
+	id := c.ID
+	if !validID(id) {
+		all, err := s.categories(ctx, "CategoryGet")
+		if err != nil {
+			return s.cause("CategoryGet", base.CategoryGetErr, err)
+		}
+		for _, cate := range all {
+			if cate.Name == c.Name {
+				id = cate.ID
+				break
+			}
+		}
+		if !validID(id) {
+			return s.notFound("CategoryGet", base.CategoryGetErr)
+		}
+	}
+	p, err := s.page(ctx, "CategoryGet", "category.php", url.Values{"mid": {id}})
+	if err != nil {
+		return s.cause("CategoryGet", base.CategoryGetErr, err)
+	}
+	v := formValues(formOf(p.doc, "metas-category-edit"))
+	if v.Get("mid") != id {
+		return s.notFound("CategoryGet", base.CategoryGetErr)
+	}
+	c.ID = id
+	c.Type = "0"
+	c.Name = v.Get("name")
+	c.Alias = v.Get("slug")
+	c.ParentID, _ = strconv.Atoi(v.Get("parent"))
+	c.Intro = v.Get("description")
+	return nil
+}
+
+// CategoryDel 删除分类 默认分类无法删除
+func (s *TCSession) CategoryDel(ctx context.Context, c *base.Category) error {
+	if !validID(c.ID) {
+		return s.invalid("CategoryDel", "请指定分类的id")
+	}
+	_, err := s.submit(ctx, "CategoryDel", "manage-categories.php", nil, "metas-category-edit", url.Values{"do": {"delete"}}, func(*adminPage) (url.Values, error) {
+		return url.Values{"mid[]": {c.ID}}, nil
+	})
+	if err != nil {
+		return s.cause("CategoryDel", base.CategoryDelErr, err)
+	}
+	return nil
+}
+
+// TagNew 新建或修改标签 未指定ID且同名标签已存在时视为成功，并填充 Tag.ID
+func (s *TCSession) TagNew(ctx context.Context, t *base.Tag) error {
+	edit := validID(t.ID)
+	if !edit {
+		all, err := s.tags(ctx, "TagNew")
+		if err != nil {
+			return s.cause("TagNew", base.TagNewErr, err)
+		}
+		for _, tag := range all {
+			if tag.Name == t.Name {
+				t.ID = tag.ID
+				return nil
+			}
+		}
+	}
+	query := url.Values{}
+	if edit {
+		query.Set("mid", t.ID)
+	}
+	n, err := s.submit(ctx, "TagNew", "manage-tags.php", query, "metas-tag-edit", nil, func(p *adminPage) (url.Values, error) {
+		param := formValues(formOf(p.doc, "metas-tag-edit"))
+		param.Set("name", t.Name)
+		if t.Alias != "" || !edit {
+			param.Set("slug", t.Alias)
+		}
+		if edit {
+			param.Set("do", "update")
+			param.Set("mid", t.ID)
+		} else {
+			param.Set("do", "insert")
+			param.Del("mid")
+		}
+		return param, nil
+	})
+	if err != nil {
+		return s.cause("TagNew", base.TagNewErr, err)
+	}
+	if id := n.id("tag"); id != "" {
+		t.ID = id
+		return nil
+	}
+	if !edit {
+		got := &base.Tag{Name: t.Name}
+		if err = s.TagGet(ctx, got); err != nil {
+			return s.cause("TagNew", base.TagNewErr, err)
+		}
+		t.ID = got.ID
+	}
+	return nil
+}
+
+// TagGet 按 ID 或名称查找标签 找到后读取编辑表单填充别名
+func (s *TCSession) TagGet(ctx context.Context, t *base.Tag) error {
+	id := t.ID
+	if !validID(id) {
+		all, err := s.tags(ctx, "TagGet")
+		if err != nil {
+			return s.cause("TagGet", base.TagUndefinedErr, err)
+		}
+		for _, tag := range all {
+			if tag.Name == t.Name {
+				id = tag.ID
+				break
+			}
+		}
+		if !validID(id) {
+			return s.notFound("TagGet", base.TagUndefinedErr)
+		}
+	}
+	p, err := s.page(ctx, "TagGet", "manage-tags.php", url.Values{"mid": {id}})
+	if err != nil {
+		return s.cause("TagGet", base.TagUndefinedErr, err)
+	}
+	v := formValues(formOf(p.doc, "metas-tag-edit"))
+	if v.Get("mid") != id {
+		return s.notFound("TagGet", base.TagUndefinedErr)
+	}
+	t.ID = id
+	t.Type = "0"
+	t.Name = v.Get("name")
+	t.Alias = v.Get("slug")
+	return nil
+}
+
+// TagDel 删除标签 未指定ID时按名称查找
+func (s *TCSession) TagDel(ctx context.Context, t *base.Tag) error {
+	if !validID(t.ID) {
+		if err := s.TagGet(ctx, t); err != nil {
+			return err
+		}
+	}
+	_, err := s.submit(ctx, "TagDel", "manage-tags.php", nil, "metas-tag-edit", url.Values{"do": {"delete"}}, func(*adminPage) (url.Values, error) {
+		return url.Values{"mid[]": {t.ID}}, nil
+	})
+	if err != nil {
+		return s.cause("TagDel", base.TagDelErr, err)
+	}
+	return nil
+}
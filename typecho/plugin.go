@@ -0,0 +1,61 @@
+package typecho
+
+import (
+	"context"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"net/url"
+	"strings"
+)
+
+// PluginList 已安装的插件 Plugin.ID 为插件目录名 如 HelloWorld
+func (s *TCSession) PluginList(ctx context.Context) ([]base.Plugin, error) {
+	p, err := s.page(ctx, "PluginList", "plugins.php", nil)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]base.Plugin, 0)
+	p.doc.Find(`tr[id^="plugin-"]`).Each(func(_ int, tr *goquery.Selection) {
+		td := tr.Find("td")
+		data = append(data, base.Plugin{
+			ID:      strings.TrimPrefix(tr.AttrOr("id", ""), "plugin-"),
+			Name:    strings.TrimSpace(td.First().Text()),
+			Version: strings.TrimSpace(td.Eq(2).Text()),
+			Enabled: tr.Find(`a[href*="deactivate="]`).Length() > 0,
+		})
+	})
+	return data, nil
+}
+
+// findPlugin 按目录名或名称查找插件
+func findPlugin(list []base.Plugin, id string) *base.Plugin {
+	for i := range list {
+		if list[i].ID == id || list[i].Name == id {
+			return &list[i]
+		}
+	}
+	return nil
+}
+
+// pluginStatus 启用或停用插件 action 为 activate 或 deactivate
+func (s *TCSession) pluginStatus(ctx context.Context, op string, sentinel error, p *base.Plugin, action string) error {
+	if p.ID == "" {
+		return s.invalid(op, "请指定插件的id")
+	}
+	_, err := s.submit(ctx, op, "plugins.php", nil, "plugins-edit", url.Values{action: {p.ID}}, nil)
+	if err != nil {
+		return s.cause(op, sentinel, err)
+	}
+	p.Enabled = action == "activate"
+	return nil
+}
+
+// PluginEnable 启用插件
+func (s *TCSession) PluginEnable(ctx context.Context, p *base.Plugin) error {
+	return s.pluginStatus(ctx, "PluginEnable", base.PluginEnableErr, p, "activate")
+}
+
+// PluginDisable 停用插件
+func (s *TCSession) PluginDisable(ctx context.Context, p *base.Plugin) error {
+	return s.pluginStatus(ctx, "PluginDisable", base.PluginDisableErr, p, "deactivate")
+}
@@ -0,0 +1,329 @@
+package typecho
+
+import (
+	"context"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var cidRegexp = regexp.MustCompile(`[?&]cid=(\d+)`)
+
+// postKind 文章与页面 后台页面及提交地址不同
+type postKind struct {
+	typ    string
+	op     string // 操作名称前缀 Article Page
+	prefix string // 列表行及提示中对象的前缀 post page
+	write  string // 编辑页
+	manage string // 列表页
+	widget string // 提交地址
+	newErr error
+	getErr error
+	delErr error
+}
+
+var articleKind = postKind{
+	typ: base.TypeArticle, op: "Article", prefix: "post", write: "write-post.php", manage: "manage-posts.php", widget: "contents-post-edit",
+	newErr: base.ArticleNewErr, getErr: base.ArticleGetErr, delErr: base.ArticleDelErr,
+}
+
+var pageKind = postKind{
+	typ: base.TypePage, op: "Page", prefix: "page", write: "write-page.php", manage: "manage-pages.php", widget: "contents-page-edit",
+	newErr: base.PageNewErr, getErr: base.PageGetErr, delErr: base.PageDelErr,
+}
+
+func (k postKind) page() bool {
+	return k.prefix == "page"
+}
+
+// ArticleNew 新建或修改文章 成功后填充 Article.ID 及 Article.Permalink
+// 修改时未指定的字段保持编辑页上的当前值；Typecho 没有置顶、作者及摘要字段，IsTop AuthorID Intro 不提交
+func (s *TCSession) ArticleNew(ctx context.Context, a *base.Article) error {
+	return s.postNew(ctx, articleKind, a)
+}
+
+// ArticleGet 获取文章 按 ID、标题（完全匹配）、别名的顺序查找，找到后读取编辑页填充全部字段
+// 正文为编辑器中的内容，站点开启 Markdown 时为 Markdown
+func (s *TCSession) ArticleGet(ctx context.Context, a *base.Article) error {
+	return s.postFind(ctx, articleKind, a)
+}
+
+// ArticleDel 删除文章
+func (s *TCSession) ArticleDel(ctx context.Context, a *base.Article) error {
+	return s.postDel(ctx, articleKind, a)
+}
+
+// PageNew 新建或修改页面 页面没有待审核状态，StatusAudit 按草稿保存
+func (s *TCSession) PageNew(ctx context.Context, a *base.Article) error {
+	return s.postNew(ctx, pageKind, a)
+}
+
+// PageGet 获取页面
+func (s *TCSession) PageGet(ctx context.Context, a *base.Article) error {
+	return s.postFind(ctx, pageKind, a)
+}
+
+// PageDel 删除页面
+func (s *TCSession) PageDel(ctx context.Context, a *base.Article) error {
+	return s.postDel(ctx, pageKind, a)
+}
+
+func validID(id string) bool {
+	n, _ := strconv.Atoi(id)
+	return n > 0
+}
+
+func (s *TCSession) postNew(ctx context.Context, k postKind, a *base.Article) error {
+	op := k.op + "New"
+	if err := a.Validate(); err != nil {
+		return s.cause(op, k.newErr, err)
+	}
+	var cateID string
+	if !k.page() && a.Cate != nil {
+		cateID = a.Cate.ID
+		if !validID(cateID) && a.Cate.Name != "" {
+			c := &base.Category{Name: a.Cate.Name}
+			if err := s.CategoryGet(ctx, c); err != nil {
+				return s.cause(op, k.newErr, err)
+			}
+			cateID = c.ID
+		}
+	}
+	edit := validID(a.ID)
+	query := url.Values{}
+	if edit {
+		query.Set("cid", a.ID)
+	}
+	n, err := s.submit(ctx, op, k.write, query, k.widget, nil, func(p *adminPage) (url.Values, error) {
+		param := formValues(formOf(p.doc, k.widget))
+		param.Set("title", a.Title)
+		param.Set("text", a.Content)
+		if param.Get("markdown") == "1" {
+			param.Set("text", markdown(a.Content))
+		}
+		if a.Alias != "" || !edit {
+			param.Set("slug", a.Alias)
+		}
+		if !a.PostTime.IsZero() {
+			// created 优先于 date，不受站点时区影响
+			param.Set("created", strconv.FormatInt(a.PostTime.Unix(), 10))
+		}
+		switch a.IsLock {
+		case base.LockOpen:
+			param.Set("allowComment", "1")
+		case base.LockClosed:
+			param.Del("allowComment")
+		}
+		if k.page() {
+			if a.Template != "" {
+				param.Set("template", a.Template)
+			}
+		} else {
+			if a.Tag != nil || !edit {
+				param.Set("tags", strings.Join(a.Tag, ","))
+			}
+			if validID(cateID) {
+				param.Set("category[]", cateID)
+			}
+		}
+		switch {
+		case a.Status == base.StatusDraft || (a.Status == base.StatusAudit && k.page()):
+			param.Set("do", "save")
+		case a.Status == base.StatusAudit:
+			param.Set("do", "publish")
+			param.Set("visibility", "waiting")
+		case a.Status == base.StatusPublic:
+			param.Set("do", "publish")
+			param.Set("visibility", "publish")
+		case edit && p.doc.Find("cite.edit-draft-notice").Length() > 0:
+			// 修改时未指定状态 草稿仍保存为草稿
+			param.Set("do", "save")
+		default:
+			// 已发布的文章沿用表单中的 visibility
+			param.Set("do", "publish")
+		}
+		return param, nil
+	})
+	if err != nil {
+		return s.cause(op, k.newErr, err)
+	}
+	id := n.id(k.prefix)
+	if m := cidRegexp.FindStringSubmatch(n.location); id == "" && m != nil {
+		id = m[1]
+	}
+	if id == "" && edit {
+		id = a.ID
+	}
+	a.Type = k.typ
+	s.postLocate(ctx, k, id, a)
+	return nil
+}
+
+// postLocate 发布后从列表查找文章的ID及链接 id 为空时取标题完全相同且ID最大的一篇，查找失败时只填充 id
+func (s *TCSession) postLocate(ctx context.Context, k postKind, id string, a *base.Article) {
+	if id != "" {
+		a.ID = id
+	}
+	list, _, err := s.postList(ctx, k, &base.ListOption{Search: a.Title})
+	if err != nil {
+		return
+	}
+	var found *base.Article
+	for i, art := range list {
+		if id != "" {
+			if art.ID == id {
+				found = &list[i]
+				break
+			}
+			continue
+		}
+		if art.Title == a.Title && (found == nil || idLess(found.ID, art.ID)) {
+			found = &list[i]
+		}
+	}
+	if found != nil {
+		a.ID = found.ID
+		a.Permalink = found.Permalink
+	}
+}
+
+func idLess(a, b string) bool {
+	x, _ := strconv.Atoi(a)
+	y, _ := strconv.Atoi(b)
+	return x < y
+}
+
+func (s *TCSession) postFind(ctx context.Context, k postKind, a *base.Article) error {
+	op := k.op + "Get"
+	if validID(a.ID) {
+		return s.postEdit(ctx, k, a.ID, a)
+	}
+	if a.Title != "" {
+		list, _, err := s.postList(ctx, k, &base.ListOption{Search: a.Title})
+		if err != nil {
+			return s.cause(op, k.getErr, err)
+		}
+		for _, art := range list {
+			if art.Title == a.Title {
+				a.Permalink = art.Permalink
+				return s.postEdit(ctx, k, art.ID, a)
+			}
+		}
+		return s.notFound(op, k.getErr)
+	}
+	if a.Alias != "" {
+		list, _, err := s.postList(ctx, k, nil)
+		if err != nil {
+			return s.cause(op, k.getErr, err)
+		}
+		for _, art := range list {
+			if err = s.postEdit(ctx, k, art.ID, &art); err != nil {
+				return err
+			}
+			if art.Alias == a.Alias {
+				*a = art
+				return nil
+			}
+		}
+	}
+	return s.notFound(op, k.getErr)
+}
+
+// postEdit 读取文章或页面的编辑页 文章不存在时 Typecho 返回404
+func (s *TCSession) postEdit(ctx context.Context, k postKind, id string, a *base.Article) error {
+	op := k.op + "Get"
+	p, err := s.page(ctx, op, k.write, url.Values{"cid": {id}})
+	if err != nil {
+		return s.cause(op, k.getErr, err)
+	}
+	form := formOf(p.doc, k.widget)
+	v := formValues(form)
+	if v.Get("cid") != id {
+		return s.notFound(op, k.getErr)
+	}
+	a.ID = id
+	a.Type = k.typ
+	a.Title = v.Get("title")
+	a.Content = v.Get("text")
+	a.Alias = v.Get("slug")
+	a.Template = v.Get("template")
+	a.PostTime = parseTime(v.Get("date"), s.location(ctx))
+	a.IsTop = base.TopNone
+	a.IsLock = base.LockClosed
+	if v.Get("allowComment") == "1" {
+		a.IsLock = base.LockOpen
+	}
+	// 未发布的草稿在编辑页上有提示
+	a.Status = base.StatusPublic
+	switch {
+	case p.doc.Find("cite.edit-draft-notice").Length() > 0:
+		a.Status = base.StatusDraft
+	case v.Get("visibility") == "waiting":
+		a.Status = base.StatusAudit
+	case v.Get("visibility") == "hidden" || v.Get("visibility") == "private":
+		a.Status = base.StatusDraft
+	}
+	a.Tag = make([]string, 0)
+	for _, tag := range strings.Split(v.Get("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			a.Tag = append(a.Tag, tag)
+		}
+	}
+	a.Cate = &base.Category{}
+	if cid := v.Get("category[]"); cid != "" {
+		a.Cate.ID = cid
+		a.Cate.Type = "0"
+		input := form.Find(`input[name="category[]"][value="` + cid + `"]`)
+		a.Cate.Name = strings.TrimSpace(form.Find(`label[for="` + input.AttrOr("id", "") + `"]`).Text())
+	}
+	return nil
+}
+
+func (s *TCSession) postDel(ctx context.Context, k postKind, a *base.Article) error {
+	op := k.op + "Del"
+	if !validID(a.ID) {
+		return s.invalid(op, "请指定文章的id")
+	}
+	_, err := s.submit(ctx, op, k.manage, nil, k.widget, url.Values{"do": {"delete"}}, func(*adminPage) (url.Values, error) {
+		return url.Values{"cid[]": {a.ID}}, nil
+	})
+	if err != nil {
+		return s.cause(op, k.delErr, err)
+	}
+	return nil
+}
+
+// postStatus 列表中的状态提示 没有提示时为公开
+var postStatus = map[string]base.Status{
+	"草稿":  base.StatusDraft,
+	"待审核": base.StatusAudit,
+	"隐藏":  base.StatusDraft,
+	"私密":  base.StatusDraft,
+}
+
+// postRow 列表中的一行
+func postRow(k postKind, tr *goquery.Selection, zone *time.Location) base.Article {
+	a := base.Article{
+		Union:     base.Union{ID: tr.Find(`input[name="cid[]"]`).AttrOr("value", ""), Type: k.typ},
+		Title:     strings.TrimSpace(tr.Find(`a[href*="` + k.write + `"]`).First().Text()),
+		Permalink: tr.Find("a:has(i.i-exlink)").AttrOr("href", ""),
+		Status:    base.StatusPublic,
+		IsTop:     base.TopNone,
+		Cate:      &base.Category{},
+		PostTime:  parseTime(tr.Find("td").Last().Text(), zone),
+	}
+	if st, ok := postStatus[strings.TrimSpace(tr.Find("em.status").First().Text())]; ok {
+		a.Status = st
+	}
+	if c := tr.Find(`a[href*="category="]`).First(); c.Length() > 0 {
+		a.Cate.Name = strings.TrimSpace(c.Text())
+		if u, err := url.Parse(c.AttrOr("href", "")); err == nil {
+			a.Cate.ID = u.Query().Get("category")
+		}
+	}
+	return a
+}
@@ -0,0 +1,76 @@
+package typecho
+
+import (
+	"context"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"net/http"
+	"time"
+)
+
+// sessionLifetime cookie 未声明过期时间时会话的有效期
+const sessionLifetime = 24 * time.Hour
+
+// reloginInterval 该时间内重复的失效请求共用同一次重新登录
+const reloginInterval = 5 * time.Second
+
+// Session 导出当前会话 Typecho 的安全令牌随页面变化，不保存
+func (s *TCSession) Session() *base.Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expire := time.Now().Add(sessionLifetime)
+	for _, c := range s.cookies {
+		if !c.Expires.IsZero() && c.Expires.Before(expire) {
+			expire = c.Expires
+		}
+	}
+	return &base.Session{
+		Program:  "typecho",
+		Site:     s.tc.HomeURL,
+		Username: s.username,
+		Cookies:  s.cookies,
+		Expire:   expire,
+	}
+}
+
+// Probe 请求后台首页检查会话是否有效 未登录时后台会跳转到登录页
+func (s *TCSession) Probe(ctx context.Context) error {
+	req, err := s.newRequest(ctx, http.MethodGet, s.adminURL("index.php"), nil, true)
+	if err != nil {
+		return err
+	}
+	var resp *http.Response
+	if resp, err = s.send("Probe", req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != 200 {
+		return s.fail("Probe", base.SessionExpiredErr, resp)
+	}
+	return nil
+}
+
+// Resume 以保存的会话恢复登录
+func Resume(_ context.Context, ss *base.Session, username, password string, tc base.ProgramBaseInfo) (base.ProgramAPIContext, error) {
+	if len(ss.Cookies) == 0 {
+		return nil, base.SessionExpiredErr
+	}
+	s := newSession(username, password, tc)
+	s.cookies = ss.Cookies
+	return s, nil
+}
+
+// OnRelogin 设置重新登录成功后的回调 用于保存新的会话
+func (s *TCSession) OnRelogin(fn func()) {
+	s.mu.Lock()
+	s.onRelogin = fn
+	s.mu.Unlock()
+}
+
+// Relogins 会话失效后重新登录的次数
+func (s *TCSession) Relogins() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.relogins
+}
@@ -0,0 +1,208 @@
+package typecho
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// settingPages 站点设置分布在基本、阅读及评论三个设置页
+var settingPages = []struct {
+	uri    string
+	widget string
+}{
+	{"options-general.php", "options-general"},
+	{"options-reading.php", "options-reading"},
+	{"options-discussion.php", "options-discussion"},
+}
+
+// settingField base.SiteSetting 中字符串字段对应的设置项
+// Typecho 没有副标题、版权及备案号，由主题设置提供时以 SiteSetting.Extra 设置
+var settingField = []struct {
+	key   string
+	field func(*base.SiteSetting) *string
+}{
+	{"title", func(ss *base.SiteSetting) *string { return &ss.SiteName }},
+	{"description", func(ss *base.SiteSetting) *string { return &ss.SiteDescription }},
+	{"keywords", func(ss *base.SiteSetting) *string { return &ss.SiteKeywords }},
+	{"lang", func(ss *base.SiteSetting) *string { return &ss.Language }},
+}
+
+const (
+	settingTimezone = "timezone" // 以秒表示的时差
+	settingPageSize = "pageSize"
+	settingComment  = "commentsPost[]"
+	commentAudit    = "commentsRequireModeration"
+)
+
+// errUnchanged 设置页没有需要修改的设置项 不提交
+var errUnchanged = errors.New("设置未修改")
+
+// settingForms 读取各设置页的完整表单
+func (s *TCSession) settingForms(ctx context.Context, op string) ([]url.Values, error) {
+	forms := make([]url.Values, 0, len(settingPages))
+	for _, sp := range settingPages {
+		p, err := s.page(ctx, op, sp.uri, nil)
+		if err != nil {
+			return nil, s.cause(op, base.SiteSettingErr, err)
+		}
+		form := formValues(formOf(p.doc, sp.widget))
+		if len(form) == 0 {
+			return nil, s.cause(op, base.SiteSettingErr, fmt.Errorf("%s 没有设置表单", sp.uri))
+		}
+		forms = append(forms, form)
+	}
+	return forms, nil
+}
+
+// SiteSettingGet 读取站点设置 未对应到字段的设置项存入 SiteSetting.Extra，多选项以逗号连接
+// Typecho 没有关闭全站评论的设置，CommentOff 为nil
+func (s *TCSession) SiteSettingGet(ctx context.Context, ss *base.SiteSetting) error {
+	forms, err := s.settingForms(ctx, "SiteSettingGet")
+	if err != nil {
+		return err
+	}
+	all := url.Values{}
+	for _, form := range forms {
+		for k, v := range form {
+			all[k] = v
+		}
+	}
+	for _, f := range settingField {
+		*f.field(ss) = all.Get(f.key)
+		all.Del(f.key)
+	}
+	offset, _ := strconv.Atoi(all.Get(settingTimezone))
+	ss.TimeZone = zoneName(offset)
+	ss.PageSize, _ = strconv.Atoi(all.Get(settingPageSize))
+	audit := false
+	for _, v := range all[settingComment] {
+		audit = audit || v == commentAudit
+	}
+	ss.CommentOff, ss.CommentAudit = nil, &audit
+	all.Del(settingPageSize)
+	all.Del(settingComment)
+	ss.Extra = make(map[string]string, len(all))
+	for k, v := range all {
+		if k == "do" {
+			continue
+		}
+		ss.Extra[k] = strings.Join(v, ",")
+	}
+	return nil
+}
+
+// SiteSetting 站点设置 先读取当前设置，只提交有修改的设置页，避免未指定的设置项被重置
+// TimeZone 为 IANA 时区名，按当前时差保存；Extra 中以 [] 结尾的多选项以逗号分隔
+func (s *TCSession) SiteSetting(ctx context.Context, ss *base.SiteSetting) error {
+	set := url.Values{}
+	for _, f := range settingField {
+		if v := *f.field(ss); v != "" {
+			set.Set(f.key, v)
+		}
+	}
+	if ss.TimeZone != "" {
+		loc, err := time.LoadLocation(ss.TimeZone)
+		if err != nil {
+			return s.cause("SiteSetting", base.SiteSettingErr, err)
+		}
+		_, offset := time.Now().In(loc).Zone()
+		set.Set(settingTimezone, strconv.Itoa(offset))
+	}
+	if ss.PageSize > 0 {
+		set.Set(settingPageSize, strconv.Itoa(ss.PageSize))
+	}
+	for k, v := range ss.Extra {
+		if strings.HasSuffix(k, "[]") {
+			set[k] = strings.Split(v, ",")
+			continue
+		}
+		set.Set(k, v)
+	}
+	for _, sp := range settingPages {
+		_, err := s.submit(ctx, "SiteSetting", sp.uri, nil, sp.widget, nil, func(p *adminPage) (url.Values, error) {
+			fields := formOf(p.doc, sp.widget)
+			form := formValues(fields)
+			changed := false
+			for k, v := range set {
+				if fields.Find(`[name="`+k+`"]`).Length() > 0 {
+					form[k] = v
+					changed = true
+				}
+			}
+			if sp.widget == "options-discussion" && ss.CommentAudit != nil {
+				form[settingComment] = commentPost(form[settingComment], *ss.CommentAudit)
+				changed = true
+			}
+			if !changed {
+				return nil, errUnchanged
+			}
+			return form, nil
+		})
+		if err == errUnchanged {
+			continue
+		}
+		if err != nil {
+			return s.cause("SiteSetting", base.SiteSettingErr, err)
+		}
+		if sp.widget == "options-general" && set.Get(settingTimezone) != "" {
+			s.mu.Lock()
+			s.zone = nil
+			s.mu.Unlock()
+		}
+	}
+	return nil
+}
+
+// commentPost 设置评论需审核 保留其它评论提交选项
+func commentPost(values []string, audit bool) []string {
+	data := make([]string, 0, len(values)+1)
+	for _, v := range values {
+		if v != commentAudit {
+			data = append(data, v)
+		}
+	}
+	if audit {
+		data = append(data, commentAudit)
+	}
+	return data
+}
+
+// location 站点时区 后台显示的时间按此解析，读取失败时使用本地时区
+func (s *TCSession) location(ctx context.Context) *time.Location {
+	s.mu.Lock()
+	zone := s.zone
+	s.mu.Unlock()
+	if zone != nil {
+		return zone
+	}
+	p, err := s.page(ctx, "SiteSettingGet", "options-general.php", nil)
+	if err != nil {
+		return time.Local
+	}
+	offset, err := strconv.Atoi(formValues(formOf(p.doc, "options-general")).Get(settingTimezone))
+	if err != nil {
+		return time.Local
+	}
+	zone = time.FixedZone(zoneName(offset), offset)
+	s.mu.Lock()
+	s.zone = zone
+	s.mu.Unlock()
+	return zone
+}
+
+// zoneName 时差对应的时区名 整点时差为 Etc/GMT-8 形式（符号与时差相反），否则为空
+func zoneName(offset int) string {
+	if offset == 0 {
+		return "UTC"
+	}
+	if offset%3600 != 0 {
+		return ""
+	}
+	return fmt.Sprintf("Etc/GMT%+d", -offset/3600)
+}
@@ -0,0 +1,381 @@
+// Package typecho Typecho 程序接口
+//
+// 通过后台表单登录及提交。后台的提交地址（index.php/action/...）带有安全令牌 _，
+// 令牌由登录状态及所在页面的地址计算，提交时须以该页面为 Referer，
+// 因此每次提交前先读取对应的后台页面，从中取得提交地址。
+// 站点开启 Markdown 时，文章及页面的 HTML 正文转换为 Markdown 后提交。
+package typecho
+
+import (
+	"context"
+	"errors"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Capabilities typecho 支持的功能
+var Capabilities = base.Capabilities{
+	Features: []base.Feature{
+		base.FeatureArticle, base.FeatureCategory, base.FeatureTag, base.FeatureSetting,
+		base.FeatureList, base.FeaturePage, base.FeaturePlugin,
+	},
+	Formats: []string{base.FormatHTML},
+}
+
+func init() {
+	base.RegisterProgramContext("typecho", LoginContext, Capabilities)
+	base.RegisterResume("typecho", Resume)
+	base.RegisterDetector("typecho", Detect)
+}
+
+type TCSession struct {
+	base.UnsupportedAPI
+	tc        base.ProgramBaseInfo
+	username  string
+	password  string
+	mu        sync.Mutex
+	loginMu   sync.Mutex // 重新登录互斥
+	cookies   []*http.Cookie
+	relogins  int
+	reloginAt time.Time
+	onRelogin func()
+	zone      *time.Location // 站点时区 首次使用时从基本设置读取
+}
+
+var Client = &http.Client{
+	Timeout: 10 * time.Second,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// Login 登录
+func Login(username, password string, tc base.ProgramBaseInfo) (base.ProgramAPI, error) {
+	s, err := LoginContext(context.Background(), username, password, tc)
+	if err != nil {
+		return nil, err
+	}
+	return base.Legacy(s), nil
+}
+
+// LoginContext 带上下文的登录 BackstagePath 为空时使用 admin/，LoginPath 为空时使用 login.php
+func LoginContext(ctx context.Context, username, password string, tc base.ProgramBaseInfo) (base.ProgramAPIContext, error) {
+	s := newSession(username, password, tc)
+	if err := s.login(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func newSession(username, password string, tc base.ProgramBaseInfo) *TCSession {
+	if tc.BackstagePath == "" {
+		tc.BackstagePath = "admin/"
+	}
+	if tc.LoginPath == "" {
+		tc.LoginPath = "login.php"
+	}
+	return &TCSession{tc: tc, username: username, password: password}
+}
+
+// login 读取登录页的表单地址（含安全令牌）并提交 成功后替换当前的cookie
+func (s *TCSession) login(ctx context.Context) error {
+	page := s.adminURL(s.tc.LoginPath)
+	req, err := s.newRequest(ctx, http.MethodGet, page, nil, false)
+	if err != nil {
+		return err
+	}
+	var resp *http.Response
+	if resp, err = s.send("Login", req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != 200 {
+		return s.fail("Login", base.LoginFailErr, resp)
+	}
+	var doc *goquery.Document
+	if doc, err = goquery.NewDocumentFromReader(resp.Body); err != nil {
+		return err
+	}
+	action := actionURL(doc, page, "login")
+	if action == "" {
+		return s.fail("Login", base.LoginFailErr, resp, []byte("登录页没有登录表单"))
+	}
+	param := url.Values{}
+	param.Set("name", s.username)
+	param.Set("password", s.password)
+	param.Set("referer", "")
+	if req, err = s.newRequest(ctx, http.MethodPost, action, param, false); err != nil {
+		return err
+	}
+	req.Header.Set("Referer", page)
+	var result *http.Response
+	if result, err = s.send("Login", req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = result.Body.Close()
+	}()
+	cookies := make([]*http.Cookie, 0)
+	logged := false
+	for _, c := range result.Cookies() {
+		if c.Value == "" || c.Value == "deleted" || strings.Contains(c.Name, "__typecho_notice") {
+			continue
+		}
+		if strings.HasSuffix(c.Name, "__typecho_authCode") {
+			logged = true
+		}
+		cookies = append(cookies, c)
+	}
+	if !logged {
+		n := readNotice(result)
+		return &base.Error{Op: "Login", Site: s.tc.HomeURL, Status: result.StatusCode, Endpoint: endpoint(req.Method, req.URL), Sentinel: base.LoginFailErr, Err: n.err()}
+	}
+	s.mu.Lock()
+	s.cookies = cookies
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *TCSession) adminURL(uri string) string {
+	return s.tc.HomeURL + s.tc.BackstagePath + uri
+}
+
+// newRequest 创建请求 param 不为nil时以表单提交
+func (s *TCSession) newRequest(ctx context.Context, method, u string, param url.Values, cookie bool) (*http.Request, error) {
+	var body io.Reader
+	if param != nil {
+		body = strings.NewReader(param.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", base.UserAgent)
+	if param != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if cookie {
+		s.mu.Lock()
+		for _, c := range s.cookies {
+			req.AddCookie(&http.Cookie{Name: c.Name, Value: c.Value})
+		}
+		s.mu.Unlock()
+	}
+	return req, nil
+}
+
+// send 发送请求 网络错误包装为 *base.Error
+func (s *TCSession) send(op string, req *http.Request) (*http.Response, error) {
+	resp, err := Client.Do(req)
+	if err != nil {
+		return nil, s.wrap(op, req, err)
+	}
+	return resp, nil
+}
+
+// adminPage 后台页面 url 为请求地址，提交该页面上的表单时作为 Referer
+type adminPage struct {
+	url string
+	doc *goquery.Document
+}
+
+// page 读取后台页面 未登录时（跳转到登录页）重新登录后重试一次
+func (s *TCSession) page(ctx context.Context, op, uri string, query url.Values) (*adminPage, error) {
+	u := s.adminURL(uri)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	for retry := 0; ; retry++ {
+		req, err := s.newRequest(ctx, http.MethodGet, u, nil, true)
+		if err != nil {
+			return nil, err
+		}
+		var resp *http.Response
+		if resp, err = s.send(op, req); err != nil {
+			return nil, err
+		}
+		if loginRedirect(resp) && retry == 0 {
+			_ = resp.Body.Close()
+			if err = s.relogin(ctx); err != nil {
+				return nil, s.cause(op, base.SessionExpiredErr, err)
+			}
+			continue
+		}
+		if resp.StatusCode != 200 {
+			var sentinel error
+			if loginRedirect(resp) {
+				sentinel = base.SessionExpiredErr
+			}
+			e := s.fail(op, sentinel, resp)
+			_ = resp.Body.Close()
+			return nil, e
+		}
+		doc, err := goquery.NewDocumentFromReader(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		return &adminPage{url: u, doc: doc}, nil
+	}
+}
+
+// submit 读取后台页面 由 form 根据页面生成表单，提交到页面上 widget 的地址，query 为附加的地址参数
+// 提交被拒绝（403，登录已失效）时重新登录并从读取页面开始重试一次
+// 提交结果以 Typecho 的提示判断，类型不为 success 时返回错误
+func (s *TCSession) submit(ctx context.Context, op, uri string, pageQuery url.Values, widget string, query url.Values, form func(*adminPage) (url.Values, error)) (*notice, error) {
+	for retry := 0; ; retry++ {
+		p, err := s.page(ctx, op, uri, pageQuery)
+		if err != nil {
+			return nil, err
+		}
+		action := actionURL(p.doc, p.url, widget)
+		if action == "" {
+			return nil, &base.Error{Op: op, Site: s.tc.HomeURL, Endpoint: endpoint(http.MethodGet, mustParse(p.url)), Err: errors.New("页面中没有 " + widget + " 的提交地址")}
+		}
+		if len(query) > 0 {
+			action += "&" + query.Encode()
+		}
+		param := url.Values{}
+		if form != nil {
+			if param, err = form(p); err != nil {
+				return nil, err
+			}
+		}
+		var req *http.Request
+		if req, err = s.newRequest(ctx, http.MethodPost, action, param, true); err != nil {
+			return nil, err
+		}
+		req.Header.Set("Referer", p.url)
+		var resp *http.Response
+		if resp, err = s.send(op, req); err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == 403 && retry == 0 {
+			_ = resp.Body.Close()
+			if err = s.relogin(ctx); err != nil {
+				return nil, s.cause(op, base.SessionExpiredErr, err)
+			}
+			continue
+		}
+		n := readNotice(resp)
+		if resp.StatusCode != 302 || n.typ != "success" {
+			e := s.fail(op, nil, resp)
+			_ = resp.Body.Close()
+			if e.Err = n.err(); e.Err == nil && resp.Header.Get("Location") == p.url {
+				// 安全令牌校验失败时直接返回来源页面，没有提示
+				e.Err = InvalidTokenErr
+			}
+			return nil, e
+		}
+		_ = resp.Body.Close()
+		return n, nil
+	}
+}
+
+var InvalidTokenErr = errors.New("安全令牌校验失败")
+
+// loginRedirect 未登录时后台页面跳转到登录页
+func loginRedirect(resp *http.Response) bool {
+	return resp.StatusCode == 302 && strings.Contains(resp.Header.Get("Location"), "login.php")
+}
+
+// relogin 以保存的用户名密码重新登录 短时间内并发的失效请求只登录一次
+func (s *TCSession) relogin(ctx context.Context) error {
+	// 登录期间持有 loginMu 等待中的请求在登录完成后直接使用新会话
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	s.mu.Lock()
+	recent := time.Since(s.reloginAt) < reloginInterval
+	s.mu.Unlock()
+	if recent {
+		return nil
+	}
+	if s.username == "" {
+		return base.LoginFailErr
+	}
+	if err := s.login(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.reloginAt = time.Now()
+	s.relogins++
+	n, fn := s.relogins, s.onRelogin
+	s.mu.Unlock()
+	log.Printf("【%s】typecho 会话失效 已重新登录 累计%d次", s.tc.HomeURL, n)
+	if fn != nil {
+		fn()
+	}
+	return nil
+}
+
+// actionURL 页面中提交到 widget 的地址 如 contents-post-edit，只保留安全令牌参数
+func actionURL(doc *goquery.Document, pageURL, widget string) string {
+	var found string
+	doc.Find("form[action], a[href]").EachWithBreak(func(_ int, sel *goquery.Selection) bool {
+		v := sel.AttrOr("action", sel.AttrOr("href", ""))
+		u, err := url.Parse(v)
+		if err != nil || !strings.HasSuffix(u.Path, "/action/"+widget) {
+			return true
+		}
+		if ref, err := url.Parse(pageURL); err == nil {
+			u = ref.ResolveReference(u)
+		}
+		token := u.Query().Get("_")
+		u.RawQuery = url.Values{"_": {token}}.Encode()
+		found = u.String()
+		return false
+	})
+	return found
+}
+
+func mustParse(u string) *url.URL {
+	p, err := url.Parse(u)
+	if err != nil {
+		return &url.URL{Path: u}
+	}
+	return p
+}
+
+// Init 初始化 启用 ProgramBaseInfo.Plugins 中的插件
+// 仅有部分插件失败时返回 *base.PluginError
+func (s *TCSession) Init(ctx context.Context) error {
+	if len(s.tc.Plugins) == 0 {
+		return nil
+	}
+	list, err := s.PluginList(ctx)
+	if err != nil {
+		return err
+	}
+	failed := make(map[string]error)
+	for _, id := range s.tc.Plugins {
+		p := findPlugin(list, id)
+		if p == nil {
+			failed[id] = base.PluginUndefinedErr
+			continue
+		}
+		if p.Enabled {
+			continue
+		}
+		if err = s.PluginEnable(ctx, p); err != nil {
+			failed[id] = err
+		}
+	}
+	if len(failed) > 0 {
+		return &base.PluginError{Failed: failed}
+	}
+	return nil
+}
+
+// NavbarNew Typecho 没有导航管理，导航由主题输出（默认主题为独立页面）
+func (s *TCSession) NavbarNew(context.Context, *base.Navbar) error {
+	return &base.Error{Op: "NavbarNew", Site: s.tc.HomeURL, Sentinel: base.UnsupportedErr}
+}
@@ -0,0 +1,893 @@
+package typecho
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"github.com/cgghui/bt_site_cluster_program_api/base/conformance"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	tcUser     = "admin"
+	tcPassword = "123456"
+	tcPrefix   = "8c6e3a" // cookie 名称的站点前缀
+)
+
+// tcServer 内存中的 Typecho 后台 只实现适配器用到的页面及提交地址
+type tcServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	authCode string // 为空时所有登录都已失效
+	markdown bool   // 是否开启 Markdown
+	seq      int
+	posts    map[int]*tcPost
+	metas    map[int]*tcMeta
+	options  map[string][]string
+	plugins  []*tcPlugin
+}
+
+type tcPost struct {
+	cid          int
+	typ          string // post page
+	title        string
+	text         string
+	slug         string
+	template     string
+	status       string // publish draft waiting hidden private
+	created      int64
+	allowComment bool
+	tags         []string
+	category     int
+	markdown     bool
+}
+
+type tcMeta struct {
+	mid         int
+	typ         string // category tag
+	name        string
+	slug        string
+	description string
+	parent      int
+}
+
+type tcPlugin struct {
+	id      string
+	name    string
+	version string
+	enabled bool
+}
+
+// tcField 设置页上的字段
+type tcField struct {
+	name    string
+	kind    string // text select radio checkbox
+	choices []string
+}
+
+var tcOptionPages = map[string][]tcField{
+	"options-general": {
+		{name: "title", kind: "text"},
+		{name: "description", kind: "text"},
+		{name: "keywords", kind: "text"},
+		{name: "lang", kind: "select", choices: []string{"zh_CN", "en_US"}},
+		{name: "timezone", kind: "select", choices: []string{"0", "28800", "-18000", "19800"}},
+		{name: "allowRegister", kind: "radio", choices: []string{"0", "1"}},
+		{name: "attachmentTypes[]", kind: "checkbox", choices: []string{"@image@", "@media@", "@doc@"}},
+	},
+	"options-reading": {
+		{name: "pageSize", kind: "text"},
+		{name: "postsListSize", kind: "text"},
+		{name: "frontPage", kind: "radio", choices: []string{"recent", "page"}},
+	},
+	"options-discussion": {
+		{name: "commentsPost[]", kind: "checkbox", choices: []string{commentAudit, "commentsRequireMail", "commentsRequireURL"}},
+		{name: "commentsPageSize", kind: "text"},
+	},
+}
+
+func newTCServer() *tcServer {
+	s := &tcServer{
+		seq:   1,
+		posts: make(map[int]*tcPost),
+		metas: map[int]*tcMeta{1: {mid: 1, typ: "category", name: "默认分类", slug: "default"}},
+		options: map[string][]string{
+			"title": {"Hello World"}, "description": {"Just So So ..."}, "keywords": {"typecho,php,blog"},
+			"lang": {"zh_CN"}, "timezone": {"28800"}, "allowRegister": {"0"}, "attachmentTypes[]": {"@image@"},
+			"pageSize": {"5"}, "postsListSize": {"10"}, "frontPage": {"recent"},
+			"commentsPost[]": {"commentsRequireMail"}, "commentsPageSize": {"20"},
+		},
+		plugins: []*tcPlugin{{id: "HelloWorld", name: "Hello World", version: "1.0.0"}, {id: "Sitemap", name: "Sitemap", version: "1.0.4", enabled: true}},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+func (s *tcServer) info() base.ProgramBaseInfo {
+	return base.ProgramBaseInfo{HomeURL: s.URL + "/"}
+}
+
+// expire 使已登录的会话失效
+func (s *tcServer) expire() {
+	s.mu.Lock()
+	s.authCode = ""
+	s.mu.Unlock()
+}
+
+func (s *tcServer) logged(r *http.Request) bool {
+	c, err := r.Cookie(tcPrefix + "__typecho_authCode")
+	return err == nil && s.authCode != "" && c.Value == s.authCode
+}
+
+// token 安全令牌 由站点密钥、登录状态及来源页面计算
+func (s *tcServer) token(r *http.Request, page string) string {
+	secret := "secret"
+	if s.logged(r) {
+		secret += "&" + s.authCode + "&1"
+	}
+	sum := md5.Sum([]byte(secret + "&" + page))
+	return hex.EncodeToString(sum[:])
+}
+
+func pageURL(r *http.Request) string {
+	return "http://" + r.Host + r.URL.RequestURI()
+}
+
+// action 当前页面上提交到 widget 的地址
+func (s *tcServer) action(r *http.Request, widget, query string) string {
+	if query != "" {
+		query += "&"
+	}
+	return html.EscapeString("/index.php/action/" + widget + "?" + query + "_=" + s.token(r, pageURL(r)))
+}
+
+func setNotice(w http.ResponseWriter, typ, highlight string, msg ...string) {
+	b, _ := json.Marshal(msg)
+	http.SetCookie(w, &http.Cookie{Name: tcPrefix + "__typecho_notice", Value: url.QueryEscape(string(b)), Path: "/"})
+	http.SetCookie(w, &http.Cookie{Name: tcPrefix + "__typecho_notice_type", Value: typ, Path: "/"})
+	if highlight != "" {
+		http.SetCookie(w, &http.Cookie{Name: tcPrefix + "__typecho_notice_highlight", Value: highlight, Path: "/"})
+	}
+}
+
+func redirect(w http.ResponseWriter, location string) {
+	w.Header().Set("Location", location)
+	w.WriteHeader(302)
+}
+
+func (s *tcServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := r.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/index.php/action/"):
+		s.submit(w, r, strings.TrimPrefix(path, "/index.php/action/"))
+	case path == "/admin/login.php":
+		_, _ = fmt.Fprintf(w, `<form action="%s" method="post"><input name="name"><input type="password" name="password"><input type="hidden" name="referer" value=""><button type="submit">登录</button></form>`, s.action(r, "login", ""))
+	case strings.HasPrefix(path, "/admin/"):
+		if !s.logged(r) {
+			redirect(w, "http://"+r.Host+"/admin/login.php?referer="+url.QueryEscape(pageURL(r)))
+			return
+		}
+		s.admin(w, r, strings.TrimPrefix(path, "/admin/"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *tcServer) admin(w http.ResponseWriter, r *http.Request, uri string) {
+	switch uri {
+	case "index.php":
+		_, _ = fmt.Fprint(w, "<h2>网站概要</h2>")
+	case "manage-posts.php":
+		s.managePosts(w, r, "post")
+	case "manage-pages.php":
+		s.managePosts(w, r, "page")
+	case "write-post.php":
+		s.writePost(w, r, "post")
+	case "write-page.php":
+		s.writePost(w, r, "page")
+	case "manage-categories.php":
+		s.manageCategories(w, r)
+	case "category.php":
+		s.category(w, r)
+	case "manage-tags.php":
+		s.manageTags(w, r)
+	case "options-general.php", "options-reading.php", "options-discussion.php":
+		s.optionPage(w, r, strings.TrimSuffix(uri, ".php"))
+	case "plugins.php":
+		s.pluginPage(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+var tcStatusText = map[string]string{"draft": "草稿", "waiting": "待审核", "hidden": "隐藏", "private": "私密"}
+
+func (s *tcServer) zone() *time.Location {
+	offset, _ := strconv.Atoi(s.options["timezone"][0])
+	return time.FixedZone("", offset)
+}
+
+func (s *tcServer) managePosts(w http.ResponseWriter, r *http.Request, typ string) {
+	q := r.URL.Query()
+	list := make([]*tcPost, 0)
+	for _, p := range s.posts {
+		if p.typ != typ {
+			continue
+		}
+		if kw := q.Get("keywords"); kw != "" && !strings.Contains(p.title, kw) && !strings.Contains(p.text, kw) {
+			continue
+		}
+		if c := q.Get("category"); c != "" && strconv.Itoa(p.category) != c {
+			continue
+		}
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].cid > list[j].cid })
+	const size = 3
+	page, _ := strconv.Atoi(q.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	var b strings.Builder
+	b.WriteString(`<table>`)
+	for i := (page - 1) * size; i < len(list) && i < page*size; i++ {
+		p := list[i]
+		fmt.Fprintf(&b, `<tr id="%s-%d"><td><input type="checkbox" name="cid[]" value="%d"></td>`, typ, p.cid, p.cid)
+		fmt.Fprintf(&b, `<td><a href="write-%s.php?cid=%d">%s</a>`, typ, p.cid, html.EscapeString(p.title))
+		if st, ok := tcStatusText[p.status]; ok {
+			fmt.Fprintf(&b, ` <em class="status">%s</em>`, st)
+		}
+		fmt.Fprintf(&b, ` <a href="%sindex.php/archives/%d/"><i class="i-exlink"></i></a></td><td>admin</td>`, s.URL+"/", p.cid)
+		if c, ok := s.metas[p.category]; ok && typ == "post" {
+			fmt.Fprintf(&b, `<td><a href="manage-posts.php?category=%d">%s</a></td>`, c.mid, html.EscapeString(c.name))
+		}
+		fmt.Fprintf(&b, `<td>%s</td></tr>`, time.Unix(p.created, 0).In(s.zone()).Format("2006年01月02日"))
+	}
+	b.WriteString(`</table><ul class="typecho-pager">`)
+	for i := 1; (i-1)*size < len(list); i++ {
+		fmt.Fprintf(&b, `<li><a href="manage-%ss.php?page=%d">%d</a></li>`, typ, i, i)
+	}
+	fmt.Fprintf(&b, `</ul><a href="%s">删除</a>`, s.action(r, "contents-"+typ+"-edit", "do=delete"))
+	_, _ = fmt.Fprint(w, b.String())
+}
+
+func (s *tcServer) writePost(w http.ResponseWriter, r *http.Request, typ string) {
+	p := &tcPost{typ: typ, status: "publish", allowComment: true}
+	if cid := r.URL.Query().Get("cid"); cid != "" {
+		id, _ := strconv.Atoi(cid)
+		if p = s.posts[id]; p == nil || p.typ != typ {
+			http.Error(w, "文章不存在", 404)
+			return
+		}
+	}
+	var b strings.Builder
+	if p.status == "draft" {
+		b.WriteString(`<cite class="edit-draft-notice">当前正在编辑的是保存于草稿</cite>`)
+	}
+	fmt.Fprintf(&b, `<form action="%s" method="post" name="write_post">`, s.action(r, "contents-"+typ+"-edit", ""))
+	fmt.Fprintf(&b, `<input type="text" name="title" value="%s"><textarea name="text">%s</textarea>`, html.EscapeString(p.title), html.EscapeString(p.text))
+	fmt.Fprintf(&b, `<input type="text" name="slug" value="%s">`, html.EscapeString(p.slug))
+	cid, date := "", ""
+	if p.cid > 0 {
+		cid = strconv.Itoa(p.cid)
+		date = time.Unix(p.created, 0).In(s.zone()).Format("2006-01-02 15:04")
+	}
+	fmt.Fprintf(&b, `<input type="hidden" name="cid" value="%s"><input type="text" name="date" value="%s">`, cid, date)
+	b.WriteString(`<select name="visibility">`)
+	for _, v := range []string{"publish", "hidden", "private", "waiting"} {
+		selected := ""
+		if v == p.status {
+			selected = " selected"
+		}
+		fmt.Fprintf(&b, `<option value="%s"%s>%s</option>`, v, selected, v)
+	}
+	b.WriteString(`</select>`)
+	checked := ""
+	if p.allowComment {
+		checked = " checked"
+	}
+	fmt.Fprintf(&b, `<input type="checkbox" name="allowComment" value="1"%s>`, checked)
+	if typ == "post" {
+		fmt.Fprintf(&b, `<input type="text" name="tags" value="%s"><ul>`, html.EscapeString(strings.Join(p.tags, ",")))
+		for _, c := range s.sortedMetas("category") {
+			checked = ""
+			if c.mid == p.category {
+				checked = " checked"
+			}
+			fmt.Fprintf(&b, `<li><input type="checkbox" id="category-%d" name="category[]" value="%d"%s><label for="category-%d">%s</label></li>`, c.mid, c.mid, checked, c.mid, html.EscapeString(c.name))
+		}
+		b.WriteString(`</ul>`)
+	} else {
+		b.WriteString(`<select name="template"><option value="">不选择</option>`)
+		for _, v := range []string{"page-links.php", "page-archives.php"} {
+			selected := ""
+			if v == p.template {
+				selected = " selected"
+			}
+			fmt.Fprintf(&b, `<option value="%s"%s>%s</option>`, v, selected, v)
+		}
+		b.WriteString(`</select>`)
+	}
+	if s.markdown {
+		b.WriteString(`<input type="hidden" name="markdown" value="1">`)
+	}
+	b.WriteString(`<button type="submit" name="do" value="save">保存草稿</button><button type="submit" name="do" value="publish">发布</button></form>`)
+	_, _ = fmt.Fprint(w, b.String())
+}
+
+func (s *tcServer) sortedMetas(typ string) []*tcMeta {
+	list := make([]*tcMeta, 0)
+	for _, m := range s.metas {
+		if m.typ == typ {
+			list = append(list, m)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].mid < list[j].mid })
+	return list
+}
+
+func (s *tcServer) findMeta(typ, name string) *tcMeta {
+	for _, m := range s.metas {
+		if m.typ == typ && m.name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+func (s *tcServer) manageCategories(w http.ResponseWriter, r *http.Request) {
+	parent, _ := strconv.Atoi(r.URL.Query().Get("parent"))
+	var b strings.Builder
+	b.WriteString(`<table>`)
+	for _, c := range s.sortedMetas("category") {
+		if c.parent != parent {
+			continue
+		}
+		children := 0
+		for _, m := range s.metas {
+			if m.typ == "category" && m.parent == c.mid {
+				children++
+			}
+		}
+		fmt.Fprintf(&b, `<tr id="mid-category-%d"><td><input type="checkbox" name="mid[]" value="%d"></td><td><a href="category.php?mid=%d">%s</a></td>`, c.mid, c.mid, c.mid, html.EscapeString(c.name))
+		if children > 0 {
+			fmt.Fprintf(&b, `<td><a href="manage-categories.php?parent=%d">%d个分类</a></td>`, c.mid, children)
+		} else {
+			fmt.Fprintf(&b, `<td><a href="category.php?parent=%d">新增</a></td>`, c.mid)
+		}
+		fmt.Fprintf(&b, `<td>%s</td><td>0</td></tr>`, html.EscapeString(c.slug))
+	}
+	fmt.Fprintf(&b, `</table><a href="%s">删除</a>`, s.action(r, "metas-category-edit", "do=delete"))
+	_, _ = fmt.Fprint(w, b.String())
+}
+
+func (s *tcServer) category(w http.ResponseWriter, r *http.Request) {
+	c, do, mid := &tcMeta{}, "insert", ""
+	if v := r.URL.Query().Get("mid"); v != "" {
+		id, _ := strconv.Atoi(v)
+		if c = s.metas[id]; c == nil || c.typ != "category" {
+			http.Error(w, "分类不存在", 404)
+			return
+		}
+		do, mid = "update", v
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, `<form action="%s" method="post">`, s.action(r, "metas-category-edit", ""))
+	fmt.Fprintf(&b, `<input name="name" value="%s"><input name="slug" value="%s"><select name="parent"><option value="0">不选择</option>`, html.EscapeString(c.name), html.EscapeString(c.slug))
+	for _, m := range s.sortedMetas("category") {
+		selected := ""
+		if m.mid == c.parent {
+			selected = " selected"
+		}
+		fmt.Fprintf(&b, `<option value="%d"%s>%s</option>`, m.mid, selected, html.EscapeString(m.name))
+	}
+	fmt.Fprintf(&b, `</select><textarea name="description">%s</textarea><input type="hidden" name="do" value="%s"><input type="hidden" name="mid" value="%s"><button type="submit">保存</button></form>`, html.EscapeString(c.description), do, mid)
+	_, _ = fmt.Fprint(w, b.String())
+}
+
+func (s *tcServer) manageTags(w http.ResponseWriter, r *http.Request) {
+	t, do, mid := &tcMeta{}, "insert", ""
+	if v := r.URL.Query().Get("mid"); v != "" {
+		id, _ := strconv.Atoi(v)
+		if t = s.metas[id]; t == nil || t.typ != "tag" {
+			http.Error(w, "标签不存在", 404)
+			return
+		}
+		do, mid = "update", v
+	}
+	var b strings.Builder
+	b.WriteString(`<ul class="typecho-list-notable tag-list">`)
+	for _, m := range s.sortedMetas("tag") {
+		fmt.Fprintf(&b, `<li id="tag-%d"><input type="checkbox" name="mid[]" value="%d"><span rel="manage-tags.php?mid=%d">%s</span></li>`, m.mid, m.mid, m.mid, html.EscapeString(m.name))
+	}
+	fmt.Fprintf(&b, `</ul><a href="%s">删除</a>`, s.action(r, "metas-tag-edit", "do=delete"))
+	fmt.Fprintf(&b, `<form action="%s" method="post"><input name="name" value="%s"><input name="slug" value="%s">`, s.action(r, "metas-tag-edit", ""), html.EscapeString(t.name), html.EscapeString(t.slug))
+	fmt.Fprintf(&b, `<input type="hidden" name="do" value="%s"><input type="hidden" name="mid" value="%s"><button type="submit">保存</button></form>`, do, mid)
+	_, _ = fmt.Fprint(w, b.String())
+}
+
+func (s *tcServer) optionPage(w http.ResponseWriter, r *http.Request, widget string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<form action="%s" method="post">`, s.action(r, widget, ""))
+	for _, f := range tcOptionPages[widget] {
+		value := s.options[f.name]
+		has := func(v string) bool {
+			for _, x := range value {
+				if x == v {
+					return true
+				}
+			}
+			return false
+		}
+		switch f.kind {
+		case "text":
+			fmt.Fprintf(&b, `<input type="text" name="%s" value="%s">`, f.name, html.EscapeString(strings.Join(value, "")))
+		case "select":
+			fmt.Fprintf(&b, `<select name="%s">`, f.name)
+			for _, c := range f.choices {
+				selected := ""
+				if has(c) {
+					selected = " selected"
+				}
+				fmt.Fprintf(&b, `<option value="%s"%s>%s</option>`, c, selected, c)
+			}
+			b.WriteString(`</select>`)
+		default:
+			for _, c := range f.choices {
+				checked := ""
+				if has(c) {
+					checked = " checked"
+				}
+				fmt.Fprintf(&b, `<input type="%s" name="%s" value="%s"%s>`, f.kind, f.name, c, checked)
+			}
+		}
+	}
+	b.WriteString(`<button type="submit">保存设置</button></form>`)
+	_, _ = fmt.Fprint(w, b.String())
+}
+
+func (s *tcServer) pluginPage(w http.ResponseWriter, r *http.Request) {
+	var b strings.Builder
+	b.WriteString(`<table>`)
+	for _, p := range s.plugins {
+		fmt.Fprintf(&b, `<tr id="plugin-%s"><td>%s</td><td>插件描述</td><td>%s</td><td>作者</td><td>`, p.id, html.EscapeString(p.name), p.version)
+		if p.enabled {
+			fmt.Fprintf(&b, `<a href="%s">禁用</a>`, s.action(r, "plugins-edit", "deactivate="+p.id))
+		} else {
+			fmt.Fprintf(&b, `<a href="%s">启用</a>`, s.action(r, "plugins-edit", "activate="+p.id))
+		}
+		b.WriteString(`</td></tr>`)
+	}
+	b.WriteString(`</table>`)
+	_, _ = fmt.Fprint(w, b.String())
+}
+
+// submit 提交地址 登录外的操作需已登录，安全令牌须与来源页面一致
+func (s *tcServer) submit(w http.ResponseWriter, r *http.Request, widget string) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	if widget != "login" && !s.logged(r) {
+		http.Error(w, "禁止访问", 403)
+		return
+	}
+	if r.URL.Query().Get("_") != s.token(r, r.Referer()) {
+		redirect(w, r.Referer())
+		return
+	}
+	switch widget {
+	case "login":
+		if r.PostForm.Get("name") != tcUser || r.PostForm.Get("password") != tcPassword {
+			setNotice(w, "error", "", "用户名或密码无效")
+			redirect(w, r.Referer())
+			return
+		}
+		s.seq++
+		s.authCode = "auth" + strconv.Itoa(s.seq)
+		http.SetCookie(w, &http.Cookie{Name: tcPrefix + "__typecho_uid", Value: "1", Path: "/"})
+		http.SetCookie(w, &http.Cookie{Name: tcPrefix + "__typecho_authCode", Value: s.authCode, Path: "/"})
+		redirect(w, "http://"+r.Host+"/admin/")
+	case "contents-post-edit":
+		s.savePost(w, r, "post")
+	case "contents-page-edit":
+		s.savePost(w, r, "page")
+	case "metas-category-edit", "metas-tag-edit":
+		s.saveMeta(w, r, strings.TrimSuffix(strings.TrimPrefix(widget, "metas-"), "-edit"))
+	case "options-general", "options-reading", "options-discussion":
+		for _, f := range tcOptionPages[widget] {
+			s.options[f.name] = r.PostForm[f.name]
+		}
+		setNotice(w, "success", "", "设置已经保存")
+		redirect(w, r.Referer())
+	case "plugins-edit":
+		id, enable := r.URL.Query().Get("activate"), true
+		if id == "" {
+			id, enable = r.URL.Query().Get("deactivate"), false
+		}
+		for _, p := range s.plugins {
+			if p.id == id {
+				p.enabled = enable
+				setNotice(w, "success", "", "插件设置已经保存")
+				redirect(w, r.Referer())
+				return
+			}
+		}
+		setNotice(w, "error", "", "插件不存在")
+		redirect(w, r.Referer())
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *tcServer) savePost(w http.ResponseWriter, r *http.Request, typ string) {
+	form := r.PostForm
+	if r.Form.Get("do") == "delete" {
+		for _, v := range form["cid[]"] {
+			id, _ := strconv.Atoi(v)
+			if p := s.posts[id]; p != nil && p.typ == typ {
+				delete(s.posts, id)
+			}
+		}
+		setNotice(w, "success", "", "文章已经被删除")
+		redirect(w, r.Referer())
+		return
+	}
+	id, _ := strconv.Atoi(form.Get("cid"))
+	p := s.posts[id]
+	if p == nil || p.typ != typ {
+		s.seq++
+		p = &tcPost{cid: s.seq, typ: typ}
+	}
+	p.title, p.text, p.slug, p.template = form.Get("title"), form.Get("text"), form.Get("slug"), form.Get("template")
+	if p.slug == "" {
+		p.slug = strconv.Itoa(p.cid)
+	}
+	p.allowComment = form.Get("allowComment") == "1"
+	p.markdown = s.markdown && form.Get("markdown") == "1"
+	switch {
+	case form.Get("created") != "":
+		p.created, _ = strconv.ParseInt(form.Get("created"), 10, 64)
+	case form.Get("date") != "":
+		t, _ := time.ParseInLocation("2006-01-02 15:04", form.Get("date"), s.zone())
+		p.created = t.Unix()
+	default:
+		p.created = time.Now().Unix()
+	}
+	p.tags = nil
+	for _, name := range strings.Split(form.Get("tags"), ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if s.findMeta("tag", name) == nil {
+			s.seq++
+			s.metas[s.seq] = &tcMeta{mid: s.seq, typ: "tag", name: name, slug: name}
+		}
+		p.tags = append(p.tags, name)
+	}
+	p.category = 0
+	if typ == "post" {
+		p.category = 1
+		cate, _ := strconv.Atoi(form.Get("category[]"))
+		if c := s.metas[cate]; c != nil && c.typ == "category" {
+			p.category = cate
+		}
+	}
+	s.posts[p.cid] = p
+	if r.Form.Get("do") == "save" {
+		p.status = "draft"
+		setNotice(w, "success", typ+"_draft-"+strconv.Itoa(p.cid), "已经被保存为草稿")
+		redirect(w, fmt.Sprintf("http://%s/admin/write-%s.php?cid=%d", r.Host, typ, p.cid))
+		return
+	}
+	p.status = "publish"
+	if v := form.Get("visibility"); v != "" {
+		p.status = v
+	}
+	setNotice(w, "success", typ+"-"+strconv.Itoa(p.cid), "已经发布")
+	redirect(w, fmt.Sprintf("http://%s/admin/manage-%ss.php", r.Host, typ))
+}
+
+func (s *tcServer) saveMeta(w http.ResponseWriter, r *http.Request, typ string) {
+	form := r.PostForm
+	manage := fmt.Sprintf("http://%s/admin/manage-%s.php", r.Host, map[string]string{"category": "categories", "tag": "tags"}[typ])
+	switch r.Form.Get("do") {
+	case "delete":
+		for _, v := range form["mid[]"] {
+			id, _ := strconv.Atoi(v)
+			if m := s.metas[id]; m != nil && m.typ == typ && id != 1 {
+				delete(s.metas, id)
+			}
+		}
+		setNotice(w, "success", "", "已经删除")
+		redirect(w, r.Referer())
+		return
+	case "insert", "update":
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	id, _ := strconv.Atoi(form.Get("mid"))
+	m := s.metas[id]
+	if r.Form.Get("do") == "insert" || m == nil || m.typ != typ {
+		s.seq++
+		m = &tcMeta{mid: s.seq, typ: typ}
+	}
+	name := form.Get("name")
+	if other := s.findMeta(typ, name); name == "" || (other != nil && other.mid != m.mid) {
+		setNotice(w, "error", "", "名称已经存在")
+		redirect(w, r.Referer())
+		return
+	}
+	m.name, m.slug, m.description = name, form.Get("slug"), form.Get("description")
+	if m.slug == "" {
+		m.slug = name
+	}
+	m.parent, _ = strconv.Atoi(form.Get("parent"))
+	s.metas[m.mid] = m
+	setNotice(w, "success", typ+"-"+strconv.Itoa(m.mid), "已经保存")
+	redirect(w, manage)
+}
+
+func testLogin(t *testing.T, srv *tcServer) *TCSession {
+	t.Helper()
+	api, err := LoginContext(context.Background(), tcUser, tcPassword, srv.info())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return api.(*TCSession)
+}
+
+func TestConformance(t *testing.T) {
+	srv := newTCServer()
+	defer srv.Close()
+	conformance.Run(t, conformance.Config{
+		Login:    LoginContext,
+		Server:   srv.Server,
+		Username: tcUser,
+		Password: tcPassword,
+		Caps:     &Capabilities,
+		Expire:   srv.expire,
+	})
+}
+
+func TestArticleFields(t *testing.T) {
+	srv := newTCServer()
+	defer srv.Close()
+	s := testLogin(t, srv)
+	ctx := context.Background()
+	cate := &base.Category{Name: "news", Alias: "news"}
+	if err := s.CategoryNew(ctx, cate); err != nil {
+		t.Fatal(err)
+	}
+	when := time.Date(2023, 5, 1, 8, 30, 0, 0, time.Local)
+	a := &base.Article{
+		Title: "hello", Content: "<p>hi</p>", Tag: []string{"go", "typecho"}, Alias: "hello-world",
+		Cate: &base.Category{Name: "news"}, Status: base.StatusAudit, IsLock: base.LockClosed, PostTime: when,
+	}
+	if err := s.ArticleNew(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Permalink != srv.URL+"/index.php/archives/"+a.ID+"/" {
+		t.Fatal(a.ID, a.Permalink)
+	}
+	got := &base.Article{Alias: "hello-world"}
+	if err := s.ArticleGet(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != a.ID || got.Status != base.StatusAudit || got.IsLock != base.LockClosed || got.Content != "<p>hi</p>" {
+		t.Fatal(got.ID, got.Status, got.IsLock, got.Content)
+	}
+	if got.Cate.ID != cate.ID || got.Cate.Name != "news" || strings.Join(got.Tag, ",") != "go,typecho" || !got.PostTime.Equal(when) {
+		t.Fatal(got.Cate, got.Tag, got.PostTime)
+	}
+	draft := &base.Article{Title: "draft", Content: "<p>draft</p>", Status: base.StatusDraft}
+	if err := s.ArticleNew(ctx, draft); err != nil || draft.ID == "" {
+		t.Fatal(draft.ID, err)
+	}
+	if err := s.ArticleGet(ctx, draft); err != nil || draft.Status != base.StatusDraft {
+		t.Fatal(draft.Status, err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := s.ArticleNew(ctx, &base.Article{Title: "more " + strconv.Itoa(i), Content: "<p>more</p>"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list, total, err := s.ArticleList(ctx, &base.ListOption{Page: 2, PageSize: 4})
+	if err != nil || total != 5 || len(list) != 1 || list[0].ID != a.ID || list[0].Status != base.StatusAudit {
+		t.Fatal(list, total, err)
+	}
+	list, total, err = s.ArticleList(ctx, &base.ListOption{Status: string(base.StatusDraft)})
+	if err != nil || total != 1 || list[0].Title != "draft" {
+		t.Fatal(list, total, err)
+	}
+	if err = s.ArticleGet(ctx, &base.Article{Union: base.Union{ID: "999"}}); !errors.Is(err, base.ArticleGetErr) || !base.IsNotFound(err) {
+		t.Fatal(err)
+	}
+}
+
+func TestEditKeepStatus(t *testing.T) {
+	srv := newTCServer()
+	defer srv.Close()
+	s := testLogin(t, srv)
+	ctx := context.Background()
+	for _, st := range []base.Status{base.StatusDraft, base.StatusAudit, base.StatusPublic} {
+		a := &base.Article{Title: "status", Content: "<p>status</p>", Status: st}
+		if err := s.ArticleNew(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+		// 修改时未指定状态 保持编辑页上的当前状态
+		edit := &base.Article{Union: base.Union{ID: a.ID}, Title: "status-updated", Content: "<p>updated</p>"}
+		if err := s.ArticleNew(ctx, edit); err != nil {
+			t.Fatal(err)
+		}
+		got := &base.Article{Union: base.Union{ID: a.ID}}
+		if err := s.ArticleGet(ctx, got); err != nil || got.Status != st || got.Title != "status-updated" {
+			t.Fatal(st, got.Status, got.Title, err)
+		}
+	}
+}
+
+func TestPage(t *testing.T) {
+	srv := newTCServer()
+	defer srv.Close()
+	s := testLogin(t, srv)
+	ctx := context.Background()
+	p := &base.Article{Title: "about", Content: "<p>about</p>", Alias: "about", Template: "page-links.php", Status: base.StatusPublic}
+	if err := s.PageNew(ctx, p); err != nil || p.ID == "" {
+		t.Fatal(p.ID, err)
+	}
+	got := &base.Article{Title: "about"}
+	if err := s.PageGet(ctx, got); err != nil || got.ID != p.ID || got.Template != "page-links.php" || got.Type != base.TypePage {
+		t.Fatal(got, err)
+	}
+	if list, total, err := s.PageList(ctx, nil); err != nil || total != 1 || list[0].ID != p.ID {
+		t.Fatal(list, total, err)
+	}
+	if err := s.PageDel(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PageGet(ctx, &base.Article{Title: "about"}); !errors.Is(err, base.PageGetErr) {
+		t.Fatal(err)
+	}
+}
+
+func TestMarkdownSite(t *testing.T) {
+	srv := newTCServer()
+	srv.markdown = true
+	defer srv.Close()
+	s := testLogin(t, srv)
+	a := &base.Article{Title: "md", Content: "<h2>标题</h2><p>a <strong>b</strong></p>"}
+	if err := s.ArticleNew(context.Background(), a); err != nil {
+		t.Fatal(err)
+	}
+	id, _ := strconv.Atoi(a.ID)
+	if p := srv.posts[id]; !p.markdown || p.text != "## 标题\n\na **b**" {
+		t.Fatalf("%v %q", p.markdown, p.text)
+	}
+}
+
+func TestSiteSetting(t *testing.T) {
+	srv := newTCServer()
+	defer srv.Close()
+	s := testLogin(t, srv)
+	ctx := context.Background()
+	audit := true
+	ss := &base.SiteSetting{
+		SiteName: "blog", TimeZone: "UTC", PageSize: 20, CommentAudit: &audit,
+		Extra: map[string]string{"allowRegister": "1", "attachmentTypes[]": "@image@,@doc@"},
+	}
+	if err := s.SiteSetting(ctx, ss); err != nil {
+		t.Fatal(err)
+	}
+	got := &base.SiteSetting{}
+	if err := s.SiteSettingGet(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.SiteName != "blog" || got.SiteDescription != "Just So So ..." || got.TimeZone != "UTC" || got.PageSize != 20 || !*got.CommentAudit || got.CommentOff != nil {
+		t.Fatal(got)
+	}
+	if got.Extra["allowRegister"] != "1" || got.Extra["attachmentTypes[]"] != "@image@,@doc@" || got.Extra["commentsPageSize"] != "20" {
+		t.Fatal(got.Extra)
+	}
+	if v := srv.options["commentsPost[]"]; strings.Join(v, ",") != "commentsRequireMail,"+commentAudit {
+		t.Fatal(v)
+	}
+	if err := s.SiteSetting(ctx, &base.SiteSetting{TimeZone: "Asia/Shanghai"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SiteSettingGet(ctx, got); err != nil || got.TimeZone != "Etc/GMT-8" || got.SiteName != "blog" {
+		t.Fatal(got.TimeZone, got.SiteName, err)
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	srv := newTCServer()
+	defer srv.Close()
+	info := srv.info()
+	info.Plugins = []string{"HelloWorld", "Sitemap", "Missing"}
+	api, err := LoginContext(context.Background(), tcUser, tcPassword, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = api.Init(context.Background())
+	var pe *base.PluginError
+	if !errors.As(err, &pe) || len(pe.Failed) != 1 || !errors.Is(pe.Failed["Missing"], base.PluginUndefinedErr) {
+		t.Fatal(err)
+	}
+	list, err := api.PluginList(context.Background())
+	if err != nil || len(list) != 2 || !list[0].Enabled || list[0].Name != "Hello World" || list[0].Version != "1.0.0" {
+		t.Fatal(list, err)
+	}
+	if err = api.PluginDisable(context.Background(), &list[1]); err != nil || list[1].Enabled || srv.plugins[1].enabled {
+		t.Fatal(err)
+	}
+	if err = api.PluginEnable(context.Background(), &base.Plugin{ID: "Missing"}); !errors.Is(err, base.PluginEnableErr) {
+		t.Fatal(err)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	srv := newTCServer()
+	defer srv.Close()
+	s := testLogin(t, srv)
+	if err := s.Probe(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	srv.expire()
+	if err := s.Probe(context.Background()); !errors.Is(err, base.SessionExpiredErr) {
+		t.Fatal(err)
+	}
+}
+
+func TestResume(t *testing.T) {
+	srv := newTCServer()
+	defer srv.Close()
+	ss := testLogin(t, srv).Session()
+	api, err := Resume(context.Background(), ss, "", "", srv.info())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list, total, err := api.CategoryList(context.Background(), nil); err != nil || total != 1 || list[0].Name != "默认分类" {
+		t.Fatal(list, total, err)
+	}
+}
+
+func TestLoginFail(t *testing.T) {
+	srv := newTCServer()
+	defer srv.Close()
+	_, err := LoginContext(context.Background(), tcUser, "wrong", srv.info())
+	if !errors.Is(err, base.LoginFailErr) || !base.IsAuth(err) || !strings.Contains(err.Error(), "用户名或密码无效") {
+		t.Fatal(err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	home := &base.Fingerprint{Header: http.Header{}, Body: []byte(`<meta name="generator" content="Typecho 1.2.1" />`)}
+	if d := Detect(context.Background(), home); d == nil || d.Version != "1.2.1" || d.Score != 100 || d.LoginPath != "login.php" {
+		t.Fatal(d)
+	}
+	home = &base.Fingerprint{Header: http.Header{}, Body: []byte(`<link rel="stylesheet" href="/usr/themes/default/style.css">`)}
+	if d := Detect(context.Background(), home); d == nil || d.Score != 60 {
+		t.Fatal(d)
+	}
+	home = &base.Fingerprint{Header: http.Header{}, Body: []byte(`<a href="/wp-content/">`)}
+	if d := Detect(context.Background(), home); d != nil {
+		t.Fatal(d)
+	}
+}
@@ -0,0 +1,30 @@
+package wordpress
+
+import (
+	"context"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"regexp"
+	"strings"
+)
+
+// versionRegexp 匹配 generator 中的版本 如 WordPress 6.4.2
+var versionRegexp = regexp.MustCompile(`WordPress\s*([\d.]+)`)
+
+// Detect 识别 WordPress 依次检查 meta generator、REST API 的 Link 头及 wp-content 路径
+func Detect(ctx context.Context, home *base.Fingerprint) *base.Detection {
+	d := &base.Detection{Program: "wordpress", BackstagePath: "wp-admin/", LoginPath: "wp-login.php"}
+	if m := versionRegexp.FindStringSubmatch(home.Generator()); m != nil {
+		d.Version = m[1]
+		d.Score = 100
+		return d
+	}
+	if strings.Contains(home.Header.Get("Link"), "api.w.org") {
+		d.Score = 90
+		return d
+	}
+	if home.Contains("wp-content/") || home.Contains("wp-includes/") {
+		d.Score = 60
+		return d
+	}
+	return nil
+}
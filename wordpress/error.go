@@ -0,0 +1,86 @@
+package wordpress
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"net/http"
+	"net/url"
+)
+
+// apiError REST 接口返回的错误 如 {"code":"term_exists","message":"...","data":{"status":400,"term_id":5}}
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		Status int `json:"status"`
+		TermID int `json:"term_id"`
+	} `json:"data"`
+}
+
+func (e *apiError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// errorCode 错误中 REST 接口返回的 code
+func errorCode(err error) (*apiError, string) {
+	var ae *apiError
+	if errors.As(err, &ae) {
+		return ae, ae.Code
+	}
+	return nil, ""
+}
+
+// wrap 包装请求过程中的错误
+func (s *WPSession) wrap(op string, req *http.Request, err error) error {
+	e := &base.Error{Op: op, Site: s.wp.HomeURL, Err: err}
+	if req != nil {
+		e.Endpoint = endpoint(req.Method, req.URL)
+	}
+	return e
+}
+
+// fail 根据响应构造错误 body 为已读取的响应内容
+func (s *WPSession) fail(op string, sentinel error, resp *http.Response, body []byte) error {
+	e := &base.Error{Op: op, Site: s.wp.HomeURL, Status: resp.StatusCode, Sentinel: sentinel}
+	if resp.Request != nil {
+		e.Endpoint = endpoint(resp.Request.Method, resp.Request.URL)
+	}
+	ae := &apiError{}
+	if json.Unmarshal(body, ae) == nil && ae.Code != "" {
+		e.Err = ae
+	}
+	if len(body) > base.ErrorBodyLimit {
+		body = body[:base.ErrorBodyLimit]
+	}
+	e.Body = string(body)
+	return e
+}
+
+// cause 以 sentinel 包装错误 同一操作中 call 返回的错误直接补充 Sentinel
+func (s *WPSession) cause(op string, sentinel, err error) error {
+	var e *base.Error
+	if errors.As(err, &e) && e.Sentinel == nil && e.Op == op {
+		e.Sentinel = sentinel
+		return e
+	}
+	return &base.Error{Op: op, Site: s.wp.HomeURL, Sentinel: sentinel, Err: err}
+}
+
+// notFound 查找失败
+func (s *WPSession) notFound(op string, sentinel error) error {
+	return &base.Error{Op: op, Site: s.wp.HomeURL, Sentinel: sentinel}
+}
+
+// invalid 参数错误
+func (s *WPSession) invalid(op, msg string) error {
+	return &base.Error{Op: op, Site: s.wp.HomeURL, Kind: base.KindValidation, Err: errors.New(msg)}
+}
+
+// endpoint 请求地址 rest_route 保留在地址中
+func endpoint(method string, u *url.URL) string {
+	if u.RawQuery == "" {
+		return method + " " + u.Path
+	}
+	return method + " " + u.Path + "?" + u.RawQuery
+}
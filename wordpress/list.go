@@ -0,0 +1,120 @@
+package wordpress
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// maxPageSize 接口允许的每页最大数量
+const maxPageSize = 100
+
+// fetch 读取列表 opt.Page 为0时读取全部分页，add 解析每一页的内容，返回 X-WP-Total 中的总数
+func (s *WPSession) fetch(ctx context.Context, op, route string, query url.Values, opt *base.ListOption, add func(raw []byte) error) (int, error) {
+	if opt != nil && opt.Page > 0 {
+		size := opt.PageSize
+		if size <= 0 {
+			size = base.DefaultPageSize
+		}
+		if size > maxPageSize {
+			size = maxPageSize
+		}
+		query.Set("page", strconv.Itoa(opt.Page))
+		query.Set("per_page", strconv.Itoa(size))
+		var raw json.RawMessage
+		resp, err := s.call(ctx, op, http.MethodGet, route, query, nil, &raw)
+		if err != nil {
+			// 页码超出范围时文章接口返回400 只读取总数
+			if _, code := errorCode(err); code != "rest_post_invalid_page_number" {
+				return 0, err
+			}
+			query.Set("page", "1")
+			query.Set("per_page", "1")
+			if resp, err = s.call(ctx, op, http.MethodGet, route, query, nil, nil); err != nil {
+				return 0, err
+			}
+			return total(resp), nil
+		}
+		if err = add(raw); err != nil {
+			return 0, err
+		}
+		return total(resp), nil
+	}
+	n := 0
+	query.Set("per_page", strconv.Itoa(maxPageSize))
+	for page, last := 1, 1; page <= last; page++ {
+		query.Set("page", strconv.Itoa(page))
+		var raw json.RawMessage
+		resp, err := s.call(ctx, op, http.MethodGet, route, query, nil, &raw)
+		if err != nil {
+			return 0, err
+		}
+		if err = add(raw); err != nil {
+			return 0, err
+		}
+		last, _ = strconv.Atoi(resp.Header.Get("X-WP-TotalPages"))
+		n = total(resp)
+	}
+	return n, nil
+}
+
+func total(resp *http.Response) int {
+	n, _ := strconv.Atoi(resp.Header.Get("X-WP-Total"))
+	return n
+}
+
+// ArticleList 文章列表 列表中的分类只有ID
+func (s *WPSession) ArticleList(ctx context.Context, opt *base.ListOption) ([]base.Article, int, error) {
+	return s.postList(ctx, articleKind, opt)
+}
+
+// PageList 页面列表
+func (s *WPSession) PageList(ctx context.Context, opt *base.ListOption) ([]base.Article, int, error) {
+	return s.postList(ctx, pageKind, opt)
+}
+
+// CategoryList 分类列表
+func (s *WPSession) CategoryList(ctx context.Context, opt *base.ListOption) ([]base.Category, int, error) {
+	data := make([]base.Category, 0)
+	n, err := s.termList(ctx, "CategoryList", "wp/v2/categories", opt, func(t *term) {
+		data = append(data, t.category())
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, n, nil
+}
+
+// TagList 标签列表
+func (s *WPSession) TagList(ctx context.Context, opt *base.ListOption) ([]base.Tag, int, error) {
+	data := make([]base.Tag, 0)
+	n, err := s.termList(ctx, "TagList", "wp/v2/tags", opt, func(t *term) {
+		data = append(data, t.tag())
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, n, nil
+}
+
+func (s *WPSession) termList(ctx context.Context, op, route string, opt *base.ListOption, fn func(t *term)) (int, error) {
+	query := url.Values{}
+	query.Set("context", "edit")
+	query.Set("hide_empty", "false")
+	if opt != nil && opt.Search != "" {
+		query.Set("search", opt.Search)
+	}
+	return s.fetch(ctx, op, route, query, opt, func(raw []byte) error {
+		var list []term
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return err
+		}
+		for i := range list {
+			fn(&list[i])
+		}
+		return nil
+	})
+}
@@ -0,0 +1,51 @@
+package wordpress
+
+import (
+	"bytes"
+	"context"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// MediaUpload 上传附件到媒体库 成功后填充 Media.ID 及 Media.URL
+func (s *WPSession) MediaUpload(ctx context.Context, m *base.Media) error {
+	if m.Name == "" || len(m.Data) == 0 {
+		return s.invalid("MediaUpload", "请指定文件名及文件内容")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint("wp/v2/media", nil), bytes.NewReader(m.Data))
+	if err != nil {
+		return err
+	}
+	mt := m.MimeType
+	if mt == "" {
+		mt = "application/octet-stream"
+	}
+	req.Header.Set("Content-Type", mt)
+	req.Header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": m.Name}))
+	var out struct {
+		ID        int    `json:"id"`
+		SourceURL string `json:"source_url"`
+	}
+	if _, err = s.send("MediaUpload", req, &out); err != nil {
+		return s.cause("MediaUpload", base.MediaUploadErr, err)
+	}
+	m.ID = strconv.Itoa(out.ID)
+	m.URL = out.SourceURL
+	return nil
+}
+
+// MediaDel 彻底删除媒体库中的附件
+func (s *WPSession) MediaDel(ctx context.Context, m *base.Media) error {
+	if m.ID == "0" || m.ID == "" {
+		return s.invalid("MediaDel", "请指定附件的id")
+	}
+	query := url.Values{}
+	query.Set("force", "true")
+	if _, err := s.call(ctx, "MediaDel", http.MethodDelete, "wp/v2/media/"+m.ID, query, nil, nil); err != nil {
+		return s.cause("MediaDel", base.MediaDelErr, err)
+	}
+	return nil
+}
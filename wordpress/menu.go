@@ -0,0 +1,133 @@
+package wordpress
+
+import (
+	"context"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// navbarMenu 站点没有菜单时创建的菜单名称
+const navbarMenu = "导航栏"
+
+// menuItem 菜单项
+type menuItem struct {
+	ID        int      `json:"id"`
+	Title     rendered `json:"title"`
+	URL       string   `json:"url"`
+	AttrTitle string   `json:"attr_title"`
+	Target    string   `json:"target"`
+	Classes   []string `json:"classes"`
+}
+
+// menu 导航使用的菜单 优先使用已指定显示位置的菜单，没有菜单时创建 需 WordPress 5.9 及以上
+func (s *WPSession) menu(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	id := s.menuID
+	s.mu.Unlock()
+	if id > 0 {
+		return id, nil
+	}
+	query := url.Values{}
+	query.Set("context", "edit")
+	query.Set("per_page", strconv.Itoa(maxPageSize))
+	var list []struct {
+		ID        int      `json:"id"`
+		Locations []string `json:"locations"`
+	}
+	if _, err := s.call(ctx, "NavbarMenu", http.MethodGet, "wp/v2/menus", query, nil, &list); err != nil {
+		return 0, err
+	}
+	for _, m := range list {
+		if len(m.Locations) > 0 {
+			id = m.ID
+			break
+		}
+	}
+	if id == 0 && len(list) > 0 {
+		id = list[0].ID
+	}
+	if id == 0 {
+		var m struct {
+			ID int `json:"id"`
+		}
+		if _, err := s.call(ctx, "NavbarMenu", http.MethodPost, "wp/v2/menus", nil, map[string]interface{}{"name": navbarMenu}, &m); err != nil {
+			return 0, err
+		}
+		id = m.ID
+	}
+	s.mu.Lock()
+	s.menuID = id
+	s.mu.Unlock()
+	return id, nil
+}
+
+func (s *WPSession) menuItems(ctx context.Context, menu int) ([]menuItem, error) {
+	query := url.Values{}
+	query.Set("context", "edit")
+	query.Set("menus", strconv.Itoa(menu))
+	query.Set("per_page", strconv.Itoa(maxPageSize))
+	var list []menuItem
+	if _, err := s.call(ctx, "NavbarList", http.MethodGet, "wp/v2/menu-items", query, nil, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// NavbarList 导航 即导航菜单中的菜单项
+func (s *WPSession) NavbarList(ctx context.Context) ([]*base.Navbar, error) {
+	menu, err := s.menu(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var items []menuItem
+	if items, err = s.menuItems(ctx, menu); err != nil {
+		return nil, err
+	}
+	data := make([]*base.Navbar, 0, len(items))
+	for _, item := range items {
+		data = append(data, &base.Navbar{
+			Href:   item.URL,
+			Title:  item.AttrTitle,
+			Text:   item.Title.Raw,
+			Target: item.Target,
+			Ico:    strings.Join(item.Classes, " "),
+		})
+	}
+	return data, nil
+}
+
+// NavbarNew 创建导航 链接已存在时修改该菜单项 不支持二级导航，Navbar.Sub 被忽略
+func (s *WPSession) NavbarNew(ctx context.Context, n *base.Navbar) error {
+	menu, err := s.menu(ctx)
+	if err != nil {
+		return s.cause("NavbarNew", base.NavbarNewErr, err)
+	}
+	var items []menuItem
+	if items, err = s.menuItems(ctx, menu); err != nil {
+		return s.cause("NavbarNew", base.NavbarNewErr, err)
+	}
+	route := "wp/v2/menu-items"
+	for _, item := range items {
+		if item.URL == n.Href {
+			route += "/" + strconv.Itoa(item.ID)
+			break
+		}
+	}
+	body := map[string]interface{}{
+		"type":       "custom",
+		"status":     "publish",
+		"menus":      menu,
+		"title":      n.Text,
+		"url":        n.Href,
+		"attr_title": n.Title,
+		"target":     n.Target,
+		"classes":    strings.Fields(n.Ico),
+	}
+	if _, err = s.call(ctx, "NavbarNew", http.MethodPost, route, nil, body, nil); err != nil {
+		return s.cause("NavbarNew", base.NavbarNewErr, err)
+	}
+	return nil
+}
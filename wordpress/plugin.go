@@ -0,0 +1,64 @@
+package wordpress
+
+import (
+	"context"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// plugin 插件 Plugin 为插件文件去掉 .php 的路径 如 akismet/akismet
+type plugin struct {
+	Plugin  string `json:"plugin"`
+	Status  string `json:"status"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// PluginList 已安装的插件 Plugin.ID 为 akismet/akismet 形式的路径
+func (s *WPSession) PluginList(ctx context.Context) ([]base.Plugin, error) {
+	query := url.Values{}
+	query.Set("context", "edit")
+	var list []plugin
+	if _, err := s.call(ctx, "PluginList", http.MethodGet, "wp/v2/plugins", query, nil, &list); err != nil {
+		return nil, err
+	}
+	data := make([]base.Plugin, 0, len(list))
+	for _, p := range list {
+		data = append(data, base.Plugin{ID: p.Plugin, Name: p.Name, Version: p.Version, Enabled: p.Status != "inactive"})
+	}
+	return data, nil
+}
+
+// findPlugin 按路径或目录名查找插件 如 akismet/akismet 或 akismet
+func findPlugin(list []base.Plugin, id string) *base.Plugin {
+	for i := range list {
+		if list[i].ID == id || strings.HasPrefix(list[i].ID, id+"/") {
+			return &list[i]
+		}
+	}
+	return nil
+}
+
+func (s *WPSession) pluginStatus(ctx context.Context, op string, sentinel error, p *base.Plugin, status string) error {
+	if p.ID == "" {
+		return s.invalid(op, "请指定插件的id")
+	}
+	body := map[string]interface{}{"status": status}
+	if _, err := s.call(ctx, op, http.MethodPost, "wp/v2/plugins/"+p.ID, nil, body, nil); err != nil {
+		return s.cause(op, sentinel, err)
+	}
+	p.Enabled = status == "active"
+	return nil
+}
+
+// PluginEnable 启用插件
+func (s *WPSession) PluginEnable(ctx context.Context, p *base.Plugin) error {
+	return s.pluginStatus(ctx, "PluginEnable", base.PluginEnableErr, p, "active")
+}
+
+// PluginDisable 停用插件
+func (s *WPSession) PluginDisable(ctx context.Context, p *base.Plugin) error {
+	return s.pluginStatus(ctx, "PluginDisable", base.PluginDisableErr, p, "inactive")
+}
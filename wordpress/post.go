@@ -0,0 +1,306 @@
+package wordpress
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// rendered 文本字段 context=edit 时包含 raw
+type rendered struct {
+	Raw      string `json:"raw"`
+	Rendered string `json:"rendered"`
+}
+
+// post 文章及页面
+type post struct {
+	ID            int      `json:"id"`
+	DateGMT       string   `json:"date_gmt"`
+	Slug          string   `json:"slug"`
+	Status        string   `json:"status"`
+	Link          string   `json:"link"`
+	Title         rendered `json:"title"`
+	Content       rendered `json:"content"`
+	Excerpt       rendered `json:"excerpt"`
+	Author        int      `json:"author"`
+	CommentStatus string   `json:"comment_status"`
+	Template      string   `json:"template"`
+	Sticky        bool     `json:"sticky"`
+	Categories    []int    `json:"categories"`
+	Tags          []int    `json:"tags"`
+}
+
+// timeLayout date_gmt 的格式
+const timeLayout = "2006-01-02T15:04:05"
+
+// wpStatus 文章状态对应的 WordPress 状态
+var wpStatus = map[base.Status]string{
+	base.StatusPublic: "publish",
+	base.StatusDraft:  "draft",
+	base.StatusAudit:  "pending",
+}
+
+// articleStatus WordPress 状态对应的文章状态 定时发布视为公开，私密视为草稿
+var articleStatus = map[string]base.Status{
+	"publish": base.StatusPublic,
+	"future":  base.StatusPublic,
+	"draft":   base.StatusDraft,
+	"pending": base.StatusAudit,
+	"private": base.StatusDraft,
+}
+
+// editStatus 查找时包含的全部状态
+const editStatus = "publish,future,draft,pending,private"
+
+// postKind 文章与页面共用的接口
+type postKind struct {
+	route  string
+	typ    string
+	op     string // 操作名称前缀 Article Page
+	newErr error
+	getErr error
+	delErr error
+}
+
+var articleKind = postKind{route: "wp/v2/posts", typ: base.TypeArticle, op: "Article", newErr: base.ArticleNewErr, getErr: base.ArticleGetErr, delErr: base.ArticleDelErr}
+var pageKind = postKind{route: "wp/v2/pages", typ: base.TypePage, op: "Page", newErr: base.PageNewErr, getErr: base.PageGetErr, delErr: base.PageDelErr}
+
+// atoi 解析ID 无效时为0
+func atoi(id string) int {
+	n, _ := strconv.Atoi(id)
+	return n
+}
+
+// ArticleNew 新建或修改文章 成功后填充 Article.ID 及 Article.Permalink
+// 置顶的各级别均对应 sticky，标签按名称查找，不存在时创建
+func (s *WPSession) ArticleNew(ctx context.Context, a *base.Article) error {
+	return s.postNew(ctx, articleKind, a)
+}
+
+// ArticleGet 获取文章 按 ID、标题（完全匹配）、别名的顺序查找
+func (s *WPSession) ArticleGet(ctx context.Context, a *base.Article) error {
+	return s.postFind(ctx, articleKind, a)
+}
+
+// ArticleDel 删除文章 不经过回收站
+func (s *WPSession) ArticleDel(ctx context.Context, a *base.Article) error {
+	return s.postDel(ctx, articleKind, a)
+}
+
+// PageNew 新建或修改页面
+func (s *WPSession) PageNew(ctx context.Context, a *base.Article) error {
+	return s.postNew(ctx, pageKind, a)
+}
+
+// PageGet 获取页面
+func (s *WPSession) PageGet(ctx context.Context, a *base.Article) error {
+	return s.postFind(ctx, pageKind, a)
+}
+
+// PageDel 删除页面
+func (s *WPSession) PageDel(ctx context.Context, a *base.Article) error {
+	return s.postDel(ctx, pageKind, a)
+}
+
+func (s *WPSession) postNew(ctx context.Context, k postKind, a *base.Article) error {
+	op := k.op + "New"
+	if err := a.Validate(); err != nil {
+		return s.cause(op, k.newErr, err)
+	}
+	body := map[string]interface{}{
+		"title":   a.Title,
+		"content": a.Content,
+		"excerpt": a.Intro,
+	}
+	if a.Alias != "" {
+		body["slug"] = a.Alias
+	}
+	if st, ok := wpStatus[a.Status]; ok {
+		body["status"] = st
+	} else if a.Status == "" && atoi(a.ID) <= 0 {
+		// 与其它程序一致 新建时未指定状态即为公开，WordPress 默认会存为草稿
+		body["status"] = wpStatus[base.StatusPublic]
+	}
+	if a.Template != "" {
+		body["template"] = a.Template
+	}
+	if id := atoi(a.AuthorID); id > 0 {
+		body["author"] = id
+	}
+	if !a.PostTime.IsZero() {
+		body["date_gmt"] = a.PostTime.UTC().Format(timeLayout)
+	}
+	switch a.IsLock {
+	case base.LockOpen:
+		body["comment_status"] = "open"
+	case base.LockClosed:
+		body["comment_status"] = "closed"
+	}
+	if k.typ == base.TypeArticle {
+		if a.IsTop != "" {
+			body["sticky"] = a.IsTop != base.TopNone
+		}
+		cate, err := s.cateID(ctx, a.Cate)
+		if err != nil {
+			return s.cause(op, k.newErr, err)
+		}
+		if cate > 0 {
+			body["categories"] = []int{cate}
+		}
+		var tags []int
+		if tags, err = s.tagIDs(ctx, a.Tag); err != nil {
+			return s.cause(op, k.newErr, err)
+		}
+		body["tags"] = tags
+	}
+	route := k.route
+	if atoi(a.ID) > 0 {
+		route += "/" + a.ID
+	}
+	p := &post{}
+	if _, err := s.call(ctx, op, http.MethodPost, route, nil, body, p); err != nil {
+		return s.cause(op, k.newErr, err)
+	}
+	a.ID = strconv.Itoa(p.ID)
+	a.Type = k.typ
+	a.Permalink = p.Link
+	return nil
+}
+
+func (s *WPSession) postFind(ctx context.Context, k postKind, a *base.Article) error {
+	op := k.op + "Get"
+	query := url.Values{}
+	query.Set("context", "edit")
+	if atoi(a.ID) > 0 {
+		p := &post{}
+		if _, err := s.call(ctx, op, http.MethodGet, k.route+"/"+a.ID, query, nil, p); err != nil {
+			return s.cause(op, k.getErr, err)
+		}
+		s.postFill(ctx, k, p, a)
+		return nil
+	}
+	if a.Title == "" && a.Alias == "" {
+		return s.notFound(op, k.getErr)
+	}
+	query.Set("status", editStatus)
+	query.Set("per_page", "100")
+	if a.Title != "" {
+		query.Set("search", a.Title)
+	} else {
+		query.Set("slug", a.Alias)
+	}
+	var list []post
+	if _, err := s.call(ctx, op, http.MethodGet, k.route, query, nil, &list); err != nil {
+		return s.cause(op, k.getErr, err)
+	}
+	for i := range list {
+		if (a.Title != "" && list[i].Title.Raw == a.Title) || (a.Title == "" && list[i].Slug == a.Alias) {
+			s.postFill(ctx, k, &list[i], a)
+			return nil
+		}
+	}
+	return s.notFound(op, k.getErr)
+}
+
+// postFill 以接口返回的内容填充 a 文章的分类名称及标签名称需再次查询，失败时留空
+func (s *WPSession) postFill(ctx context.Context, k postKind, p *post, a *base.Article) {
+	a.ID = strconv.Itoa(p.ID)
+	a.Type = k.typ
+	a.Title = p.Title.Raw
+	a.Content = p.Content.Raw
+	a.Alias = p.Slug
+	a.Status = articleStatus[p.Status]
+	a.Template = p.Template
+	a.AuthorID = strconv.Itoa(p.Author)
+	a.Intro = p.Excerpt.Raw
+	a.Permalink = p.Link
+	if t, err := time.ParseInLocation(timeLayout, p.DateGMT, time.UTC); err == nil {
+		a.PostTime = t.Local()
+	}
+	a.IsLock = base.LockOpen
+	if p.CommentStatus == "closed" {
+		a.IsLock = base.LockClosed
+	}
+	a.IsTop = base.TopNone
+	a.Cate = &base.Category{}
+	a.Tag = make([]string, 0)
+	if k.typ != base.TypeArticle {
+		return
+	}
+	if p.Sticky {
+		a.IsTop = base.TopGlobal
+	}
+	if len(p.Categories) > 0 {
+		a.Cate = &base.Category{Union: base.Union{ID: strconv.Itoa(p.Categories[0]), Type: "0"}}
+		_ = s.CategoryGet(ctx, a.Cate)
+	}
+	if names, err := s.tagNames(ctx, p.Tags); err == nil {
+		a.Tag = names
+	}
+}
+
+func (s *WPSession) postDel(ctx context.Context, k postKind, a *base.Article) error {
+	op := k.op + "Del"
+	if atoi(a.ID) <= 0 {
+		return s.invalid(op, "请指定文章的id")
+	}
+	query := url.Values{}
+	query.Set("force", "true")
+	if _, err := s.call(ctx, op, http.MethodDelete, k.route+"/"+a.ID, query, nil, nil); err != nil {
+		return s.cause(op, k.delErr, err)
+	}
+	return nil
+}
+
+// postList 文章或页面列表 ListOption.Status 为文章状态
+func (s *WPSession) postList(ctx context.Context, k postKind, opt *base.ListOption) ([]base.Article, int, error) {
+	query := url.Values{}
+	query.Set("context", "edit")
+	query.Set("status", editStatus)
+	if opt != nil {
+		if st, ok := wpStatus[base.Status(opt.Status)]; ok {
+			query.Set("status", st)
+		}
+		if opt.Search != "" {
+			query.Set("search", opt.Search)
+		}
+		if opt.CateID != "" && k.typ == base.TypeArticle {
+			query.Set("categories", opt.CateID)
+		}
+	}
+	data := make([]base.Article, 0)
+	total, err := s.fetch(ctx, k.op+"List", k.route, query, opt, func(raw []byte) error {
+		var list []post
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return err
+		}
+		for i := range list {
+			p := &list[i]
+			a := base.Article{
+				Union:     base.Union{ID: strconv.Itoa(p.ID), Type: k.typ},
+				Title:     p.Title.Raw,
+				Alias:     p.Slug,
+				Status:    articleStatus[p.Status],
+				AuthorID:  strconv.Itoa(p.Author),
+				Permalink: p.Link,
+				Cate:      &base.Category{},
+			}
+			if t, err := time.ParseInLocation(timeLayout, p.DateGMT, time.UTC); err == nil {
+				a.PostTime = t.Local()
+			}
+			if len(p.Categories) > 0 {
+				a.Cate.ID = strconv.Itoa(p.Categories[0])
+			}
+			data = append(data, a)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, total, nil
+}
@@ -0,0 +1,113 @@
+package wordpress
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"net/http"
+	"strconv"
+)
+
+// settingField 站点设置对应的设置项
+// 关键词、描述、版权、备案号在 WordPress 中没有对应项，由主题或插件提供时以 SiteSetting.Extra 设置
+var settingField = []struct {
+	key   string
+	field func(*base.SiteSetting) *string
+}{
+	{"title", func(ss *base.SiteSetting) *string { return &ss.SiteName }},
+	{"description", func(ss *base.SiteSetting) *string { return &ss.SubSiteName }},
+	{"timezone", func(ss *base.SiteSetting) *string { return &ss.TimeZone }},
+	{"language", func(ss *base.SiteSetting) *string { return &ss.Language }},
+}
+
+const (
+	settingPageSize = "posts_per_page"
+	settingComment  = "default_comment_status"
+)
+
+func (s *WPSession) settings(ctx context.Context, op string) (map[string]json.RawMessage, error) {
+	data := make(map[string]json.RawMessage)
+	if _, err := s.call(ctx, op, http.MethodGet, "wp/v2/settings", nil, nil, &data); err != nil {
+		return nil, s.cause(op, base.SiteSettingErr, err)
+	}
+	return data, nil
+}
+
+// SiteSettingGet 读取站点设置 未对应到字段的设置项存入 SiteSetting.Extra，评论审核不在接口中，CommentAudit 为nil
+func (s *WPSession) SiteSettingGet(ctx context.Context, ss *base.SiteSetting) error {
+	data, err := s.settings(ctx, "SiteSettingGet")
+	if err != nil {
+		return err
+	}
+	for _, f := range settingField {
+		_ = json.Unmarshal(data[f.key], f.field(ss))
+		delete(data, f.key)
+	}
+	_ = json.Unmarshal(data[settingPageSize], &ss.PageSize)
+	var comment string
+	_ = json.Unmarshal(data[settingComment], &comment)
+	off := comment == "closed"
+	ss.CommentOff, ss.CommentAudit = &off, nil
+	delete(data, settingPageSize)
+	delete(data, settingComment)
+	ss.Extra = make(map[string]string, len(data))
+	for k, v := range data {
+		var str string
+		if json.Unmarshal(v, &str) == nil {
+			ss.Extra[k] = str
+			continue
+		}
+		ss.Extra[k] = string(v)
+	}
+	return nil
+}
+
+// SiteSetting 站点设置 只提交已指定的设置项，Extra 中的值按站点当前值的类型转换
+func (s *WPSession) SiteSetting(ctx context.Context, ss *base.SiteSetting) error {
+	body := make(map[string]interface{})
+	if len(ss.Extra) > 0 {
+		current, err := s.settings(ctx, "SiteSetting")
+		if err != nil {
+			return err
+		}
+		for k, v := range ss.Extra {
+			body[k] = settingValue(current[k], v)
+		}
+	}
+	for _, f := range settingField {
+		if v := *f.field(ss); v != "" {
+			body[f.key] = v
+		}
+	}
+	if ss.PageSize > 0 {
+		body[settingPageSize] = ss.PageSize
+	}
+	if ss.CommentOff != nil {
+		body[settingComment] = "open"
+		if *ss.CommentOff {
+			body[settingComment] = "closed"
+		}
+	}
+	if len(body) == 0 {
+		return nil
+	}
+	if _, err := s.call(ctx, "SiteSetting", http.MethodPost, "wp/v2/settings", nil, body, nil); err != nil {
+		return s.cause("SiteSetting", base.SiteSettingErr, err)
+	}
+	return nil
+}
+
+// settingValue 按当前值的类型转换 当前值为数字或布尔时 v 以JSON解析
+func settingValue(current json.RawMessage, v string) interface{} {
+	if len(current) == 0 || current[0] == '"' {
+		return v
+	}
+	if b, err := strconv.ParseBool(v); err == nil && (current[0] == 't' || current[0] == 'f') {
+		return b
+	}
+	var x interface{}
+	if json.Unmarshal([]byte(v), &x) == nil {
+		return x
+	}
+	return v
+}
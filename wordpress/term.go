@@ -0,0 +1,264 @@
+package wordpress
+
+import (
+	"context"
+	"errors"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// term 分类及标签
+type term struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+	Parent      int    `json:"parent"`
+	Link        string `json:"link"`
+}
+
+// termName 接口返回的名称经过 HTML 转义
+func (t *term) termName() string {
+	return html.UnescapeString(t.Name)
+}
+
+func (t *term) category() base.Category {
+	return base.Category{
+		Union:    base.Union{ID: strconv.Itoa(t.ID), Type: "0"},
+		Name:     t.termName(),
+		Alias:    t.Slug,
+		ParentID: t.Parent,
+		Intro:    t.Description,
+	}
+}
+
+func (t *term) tag() base.Tag {
+	return base.Tag{
+		Union: base.Union{ID: strconv.Itoa(t.ID), Type: "0"},
+		Name:  t.termName(),
+		Alias: t.Slug,
+		Intro: t.Description,
+	}
+}
+
+// termSave 新建或修改分类、标签 名称已存在时返回 term_exists 错误
+func (s *WPSession) termSave(ctx context.Context, op, route, id string, body map[string]interface{}) (*term, error) {
+	if atoi(id) > 0 {
+		route += "/" + id
+	}
+	t := &term{}
+	if _, err := s.call(ctx, op, http.MethodPost, route, nil, body, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// termFind 按ID或名称（完全匹配）查找 未找到时返回nil
+func (s *WPSession) termFind(ctx context.Context, op, route, id, name string) (*term, error) {
+	query := url.Values{}
+	query.Set("context", "edit")
+	if atoi(id) > 0 {
+		t := &term{}
+		if _, err := s.call(ctx, op, http.MethodGet, route+"/"+id, query, nil, t); err != nil {
+			if base.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return t, nil
+	}
+	if name == "" {
+		return nil, nil
+	}
+	query.Set("search", name)
+	query.Set("hide_empty", "false")
+	query.Set("per_page", "100")
+	var list []term
+	if _, err := s.call(ctx, op, http.MethodGet, route, query, nil, &list); err != nil {
+		return nil, err
+	}
+	for i := range list {
+		if list[i].termName() == name {
+			return &list[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *WPSession) termDel(ctx context.Context, op, route, id string) error {
+	query := url.Values{}
+	query.Set("force", "true")
+	_, err := s.call(ctx, op, http.MethodDelete, route+"/"+id, query, nil, nil)
+	return err
+}
+
+func categoryBody(c *base.Category) map[string]interface{} {
+	body := map[string]interface{}{
+		"name":        c.Name,
+		"description": c.Intro,
+		"parent":      c.ParentID,
+	}
+	if c.Alias != "" {
+		body["slug"] = c.Alias
+	}
+	return body
+}
+
+// CategoryNew 新建或修改分类 同名分类已存在时修改该分类
+// 排序及模板在 WordPress 中没有对应项，将被忽略
+func (s *WPSession) CategoryNew(ctx context.Context, c *base.Category) error {
+	body := categoryBody(c)
+	t, err := s.termSave(ctx, "CategoryNew", "wp/v2/categories", c.ID, body)
+	if ae, code := errorCode(err); code == "term_exists" && ae.Data.TermID > 0 {
+		t, err = s.termSave(ctx, "CategoryNew", "wp/v2/categories", strconv.Itoa(ae.Data.TermID), body)
+	}
+	if err != nil {
+		return s.cause("CategoryNew", base.CategoryNewErr, err)
+	}
+	c.ID = strconv.Itoa(t.ID)
+	return nil
+}
+
+// CategoryGet 按 ID 或名称查找分类
+func (s *WPSession) CategoryGet(ctx context.Context, c *base.Category) error {
+	t, err := s.termFind(ctx, "CategoryGet", "wp/v2/categories", c.ID, c.Name)
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return s.notFound("CategoryGet", base.CategoryGetErr)
+	}
+	*c = t.category()
+	return nil
+}
+
+// CategoryDel 删除分类
+func (s *WPSession) CategoryDel(ctx context.Context, c *base.Category) error {
+	if atoi(c.ID) <= 0 {
+		return s.invalid("CategoryDel", "请指定分类的id")
+	}
+	if err := s.termDel(ctx, "CategoryDel", "wp/v2/categories", c.ID); err != nil {
+		return s.cause("CategoryDel", base.CategoryDelErr, err)
+	}
+	return nil
+}
+
+// cateID 文章的分类ID 未指定ID时按名称查找，均未指定时为0
+func (s *WPSession) cateID(ctx context.Context, c *base.Category) (int, error) {
+	if c == nil {
+		return 0, nil
+	}
+	if id := atoi(c.ID); id > 0 {
+		return id, nil
+	}
+	if c.Name == "" {
+		return 0, nil
+	}
+	cate := &base.Category{Name: c.Name}
+	if err := s.CategoryGet(ctx, cate); err != nil {
+		return 0, err
+	}
+	return atoi(cate.ID), nil
+}
+
+// TagNew 新建或修改标签 同名标签已存在时视为成功，并填充 Tag.ID
+func (s *WPSession) TagNew(ctx context.Context, t *base.Tag) error {
+	body := map[string]interface{}{
+		"name":        t.Name,
+		"description": t.Intro,
+	}
+	if t.Alias != "" {
+		body["slug"] = t.Alias
+	}
+	tm, err := s.termSave(ctx, "TagNew", "wp/v2/tags", t.ID, body)
+	if ae, code := errorCode(err); code == "term_exists" && ae.Data.TermID > 0 {
+		t.ID = strconv.Itoa(ae.Data.TermID)
+		return nil
+	}
+	if err != nil {
+		return s.cause("TagNew", base.TagNewErr, err)
+	}
+	t.ID = strconv.Itoa(tm.ID)
+	return nil
+}
+
+// TagGet 按 ID 或名称查找标签
+func (s *WPSession) TagGet(ctx context.Context, t *base.Tag) error {
+	tm, err := s.termFind(ctx, "TagGet", "wp/v2/tags", t.ID, t.Name)
+	if err != nil {
+		return err
+	}
+	if tm == nil {
+		return s.notFound("TagGet", base.TagUndefinedErr)
+	}
+	*t = tm.tag()
+	return nil
+}
+
+// TagDel 删除标签 未指定ID时按名称查找
+func (s *WPSession) TagDel(ctx context.Context, t *base.Tag) error {
+	if atoi(t.ID) <= 0 {
+		if err := s.TagGet(ctx, t); err != nil {
+			return err
+		}
+	}
+	if err := s.termDel(ctx, "TagDel", "wp/v2/tags", t.ID); err != nil {
+		return s.cause("TagDel", base.TagDelErr, err)
+	}
+	return nil
+}
+
+// tagIDs 标签名称对应的ID 不存在的标签将被创建
+func (s *WPSession) tagIDs(ctx context.Context, names []string) ([]int, error) {
+	ids := make([]int, 0, len(names))
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		t := &base.Tag{Name: name}
+		err := s.TagGet(ctx, t)
+		if errors.Is(err, base.TagUndefinedErr) {
+			t = &base.Tag{Name: name}
+			err = s.TagNew(ctx, t)
+		}
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, atoi(t.ID))
+	}
+	return ids, nil
+}
+
+// tagNames 标签ID对应的名称 按ID的顺序
+func (s *WPSession) tagNames(ctx context.Context, ids []int) ([]string, error) {
+	names := make([]string, 0, len(ids))
+	if len(ids) == 0 {
+		return names, nil
+	}
+	include := make([]string, 0, len(ids))
+	for _, id := range ids {
+		include = append(include, strconv.Itoa(id))
+	}
+	query := url.Values{}
+	query.Set("include", strings.Join(include, ","))
+	query.Set("hide_empty", "false")
+	query.Set("per_page", strconv.Itoa(maxPageSize))
+	var list []term
+	if _, err := s.call(ctx, "TagGet", http.MethodGet, "wp/v2/tags", query, nil, &list); err != nil {
+		return nil, err
+	}
+	byID := make(map[int]string, len(list))
+	for i := range list {
+		byID[list[i].ID] = list[i].termName()
+	}
+	for _, id := range ids {
+		if name, ok := byID[id]; ok {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
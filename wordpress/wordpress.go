@@ -0,0 +1,170 @@
+// Package wordpress WordPress 程序接口 基于 REST API(/wp-json/wp/v2)，以应用程序密码认证
+//
+// 登录使用的密码为后台“用户 - 个人资料 - 应用程序密码”中生成的密码，而非登录密码。
+package wordpress
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Capabilities wordpress 支持的功能
+var Capabilities = base.Capabilities{
+	Features: []base.Feature{
+		base.FeatureArticle, base.FeatureCategory, base.FeatureTag, base.FeatureNavbar, base.FeatureSetting,
+		base.FeatureList, base.FeaturePage, base.FeatureMedia, base.FeaturePlugin,
+	},
+	Formats: []string{base.FormatHTML},
+}
+
+func init() {
+	base.RegisterProgramContext("wordpress", LoginContext, Capabilities)
+	base.RegisterDetector("wordpress", Detect)
+}
+
+type WPSession struct {
+	base.UnsupportedAPI
+	wp       base.ProgramBaseInfo
+	username string
+	password string
+	root     string // REST 根地址 如 http://example.com/wp-json/
+	mu       sync.Mutex
+	menuID   int
+}
+
+var Client = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+// Login 登录
+func Login(username, password string, wp base.ProgramBaseInfo) (base.ProgramAPI, error) {
+	s, err := LoginContext(context.Background(), username, password, wp)
+	if err != nil {
+		return nil, err
+	}
+	return base.Legacy(s), nil
+}
+
+// LoginContext 带上下文的登录 以 users/me 校验应用程序密码
+// 站点未开启固定链接时 /wp-json/ 不可用，改用 ?rest_route=
+func LoginContext(ctx context.Context, username, password string, wp base.ProgramBaseInfo) (base.ProgramAPIContext, error) {
+	s := &WPSession{wp: wp, username: username, password: password, root: wp.HomeURL + "wp-json/"}
+	err := s.login(ctx)
+	if err != nil && base.IsNotFound(err) {
+		s.root = wp.HomeURL + "?rest_route=/"
+		err = s.login(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *WPSession) login(ctx context.Context) error {
+	query := url.Values{}
+	query.Set("context", "edit")
+	_, err := s.call(ctx, "Login", http.MethodGet, "wp/v2/users/me", query, nil, nil)
+	if err != nil && base.IsAuth(err) {
+		return s.cause("Login", base.LoginFailErr, err)
+	}
+	return err
+}
+
+// endpoint REST 接口的完整地址
+func (s *WPSession) endpoint(route string, query url.Values) string {
+	u := s.root + route
+	if len(query) == 0 {
+		return u
+	}
+	if strings.Contains(s.root, "?") {
+		return u + "&" + query.Encode()
+	}
+	return u + "?" + query.Encode()
+}
+
+// call 请求 REST 接口 body 不为nil时以JSON提交，out 不为nil时解析响应内容
+// 状态码不为2xx时返回 *base.Error，其 Err 为 *apiError
+func (s *WPSession) call(ctx context.Context, op, method, route string, query url.Values, body, out interface{}) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.endpoint(route, query), r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return s.send(op, req, out)
+}
+
+// send 发送请求 添加认证信息并解析响应
+func (s *WPSession) send(op string, req *http.Request, out interface{}) (*http.Response, error) {
+	req.Header.Set("User-Agent", base.UserAgent)
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(s.username, s.password)
+	resp, err := Client.Do(req)
+	if err != nil {
+		return nil, s.wrap(op, req, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	var data []byte
+	if data, err = ioutil.ReadAll(resp.Body); err != nil {
+		return nil, s.wrap(op, req, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, s.fail(op, nil, resp, data)
+	}
+	if out != nil {
+		if err = json.Unmarshal(data, out); err != nil {
+			return nil, s.wrap(op, req, err)
+		}
+	}
+	return resp, nil
+}
+
+// Init 初始化 启用 ProgramBaseInfo.Plugins 中的插件
+// 链接结构不在 REST API 中，ProgramBaseInfo.Permalink 需在后台设置
+func (s *WPSession) Init(ctx context.Context) error {
+	if len(s.wp.Plugins) == 0 {
+		return nil
+	}
+	list, err := s.PluginList(ctx)
+	if err != nil {
+		return err
+	}
+	failed := make(map[string]error)
+	for _, id := range s.wp.Plugins {
+		p := findPlugin(list, id)
+		if p == nil {
+			failed[id] = base.PluginUndefinedErr
+			continue
+		}
+		if p.Enabled {
+			continue
+		}
+		if err = s.PluginEnable(ctx, p); err != nil {
+			failed[id] = err
+		}
+	}
+	if len(failed) > 0 {
+		return &base.PluginError{Failed: failed}
+	}
+	return nil
+}
@@ -0,0 +1,359 @@
+package wordpress
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"github.com/cgghui/bt_site_cluster_program_api/base/conformance"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// restServer 内存中的 REST API 只实现适配器用到的部分
+type restServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	seq      int
+	objects  map[string][]map[string]interface{} // 以路由为键 如 wp/v2/posts
+	settings map[string]interface{}
+}
+
+func newRESTServer() *restServer {
+	s := &restServer{
+		objects: make(map[string][]map[string]interface{}),
+		settings: map[string]interface{}{
+			"title": "WordPress", "description": "Just another WordPress site", "timezone": "",
+			"language": "zh_CN", "posts_per_page": 10, "default_comment_status": "open", "use_smilies": true,
+		},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func restError(w http.ResponseWriter, status int, code string, data map[string]interface{}) {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	data["status"] = status
+	writeJSON(w, status, map[string]interface{}{"code": code, "message": code, "data": data})
+}
+
+func (s *restServer) handle(w http.ResponseWriter, r *http.Request) {
+	if u, p, ok := r.BasicAuth(); !ok || u != "admin" || p != "abcd efgh ijkl" {
+		restError(w, 401, "rest_not_logged_in", nil)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	route := strings.TrimPrefix(r.URL.Path, "/wp-json/")
+	q := r.URL.Query()
+	switch route {
+	case "wp/v2/users/me":
+		writeJSON(w, 200, map[string]interface{}{"id": 1, "name": "admin"})
+		return
+	case "wp/v2/settings":
+		if r.Method == http.MethodPost {
+			_ = json.NewDecoder(r.Body).Decode(&s.settings)
+		}
+		writeJSON(w, 200, s.settings)
+		return
+	case "wp/v2/media":
+		if r.Method != http.MethodPost {
+			break
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		if len(data) == 0 || !strings.Contains(r.Header.Get("Content-Disposition"), "filename") {
+			restError(w, 400, "rest_upload_no_data", nil)
+			return
+		}
+		s.seq++
+		obj := map[string]interface{}{"id": s.seq, "source_url": s.URL + "/wp-content/uploads/" + strconv.Itoa(s.seq)}
+		s.objects[route] = append(s.objects[route], obj)
+		writeJSON(w, 201, obj)
+		return
+	}
+	collection, id := route, 0
+	if i := strings.LastIndex(route, "/"); i != -1 {
+		if n, err := strconv.Atoi(route[i+1:]); err == nil {
+			collection, id = route[:i], n
+		}
+	}
+	switch {
+	case id == 0 && r.Method == http.MethodGet:
+		s.list(w, collection, q)
+	case id == 0 && r.Method == http.MethodPost:
+		s.save(w, r, collection, nil)
+	case r.Method == http.MethodGet || r.Method == http.MethodPost || r.Method == http.MethodDelete:
+		for i, obj := range s.objects[collection] {
+			if obj["id"] != id {
+				continue
+			}
+			switch r.Method {
+			case http.MethodGet:
+				writeJSON(w, 200, obj)
+			case http.MethodPost:
+				s.save(w, r, collection, obj)
+			default:
+				s.objects[collection] = append(s.objects[collection][:i], s.objects[collection][i+1:]...)
+				writeJSON(w, 200, map[string]interface{}{"deleted": true, "previous": obj})
+			}
+			return
+		}
+		restError(w, 404, "rest_invalid_id", nil)
+	default:
+		restError(w, 404, "rest_no_route", nil)
+	}
+}
+
+func isTerm(collection string) bool {
+	return collection == "wp/v2/categories" || collection == "wp/v2/tags"
+}
+
+func (s *restServer) save(w http.ResponseWriter, r *http.Request, collection string, obj map[string]interface{}) {
+	body := make(map[string]interface{})
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		restError(w, 400, "rest_invalid_json", nil)
+		return
+	}
+	if isTerm(collection) {
+		for _, t := range s.objects[collection] {
+			if t["name"] == body["name"] && (obj == nil || t["id"] != obj["id"]) {
+				restError(w, 400, "term_exists", map[string]interface{}{"term_id": t["id"]})
+				return
+			}
+		}
+	}
+	status := 200
+	if obj == nil {
+		s.seq++
+		status = 201
+		obj = map[string]interface{}{"id": s.seq, "link": s.URL + "/?p=" + strconv.Itoa(s.seq)}
+		if !isTerm(collection) {
+			// 未指定状态时 WordPress 新建为草稿
+			obj["status"] = "draft"
+		}
+		s.objects[collection] = append(s.objects[collection], obj)
+	}
+	for k, v := range body {
+		if str, ok := v.(string); ok && (k == "title" || k == "content" || k == "excerpt") {
+			v = map[string]interface{}{"raw": str, "rendered": str}
+		}
+		obj[k] = v
+	}
+	if _, ok := obj["slug"]; !ok && isTerm(collection) {
+		obj["slug"] = body["name"]
+	}
+	writeJSON(w, status, obj)
+}
+
+func (s *restServer) list(w http.ResponseWriter, collection string, q map[string][]string) {
+	get := func(k string) string {
+		if v := q[k]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	data := make([]map[string]interface{}, 0)
+	for _, obj := range s.objects[collection] {
+		name, _ := obj["name"].(string)
+		if t, ok := obj["title"].(map[string]interface{}); ok {
+			name, _ = t["raw"].(string)
+		}
+		if v := get("search"); v != "" && !strings.Contains(name, v) {
+			continue
+		}
+		if v := get("slug"); v != "" && obj["slug"] != v {
+			continue
+		}
+		if v := get("status"); v != "" && obj["status"] != nil && !strings.Contains(v, obj["status"].(string)) {
+			continue
+		}
+		if v := get("menus"); v != "" && strconv.Itoa(int(obj["menus"].(float64))) != v {
+			continue
+		}
+		if v := get("include"); v != "" && !strings.Contains(","+v+",", ","+strconv.Itoa(obj["id"].(int))+",") {
+			continue
+		}
+		data = append(data, obj)
+	}
+	size, _ := strconv.Atoi(get("per_page"))
+	if size <= 0 {
+		size = 10
+	}
+	page, _ := strconv.Atoi(get("page"))
+	if page <= 0 {
+		page = 1
+	}
+	pages := (len(data) + size - 1) / size
+	w.Header().Set("X-WP-Total", strconv.Itoa(len(data)))
+	w.Header().Set("X-WP-TotalPages", strconv.Itoa(pages))
+	if page > 1 && page > pages && collection == "wp/v2/posts" {
+		restError(w, 400, "rest_post_invalid_page_number", nil)
+		return
+	}
+	start, end := (page-1)*size, page*size
+	if start > len(data) {
+		start = len(data)
+	}
+	if end > len(data) {
+		end = len(data)
+	}
+	writeJSON(w, 200, data[start:end])
+}
+
+func testLogin(t *testing.T, srv *restServer) *WPSession {
+	t.Helper()
+	api, err := LoginContext(context.Background(), "admin", "abcd efgh ijkl", base.ProgramBaseInfo{HomeURL: srv.URL + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return api.(*WPSession)
+}
+
+func TestConformance(t *testing.T) {
+	srv := newRESTServer()
+	defer srv.Close()
+	conformance.Run(t, conformance.Config{
+		Login:    LoginContext,
+		Server:   srv.Server,
+		Username: "admin",
+		Password: "abcd efgh ijkl",
+		Caps:     &Capabilities,
+		NavbarList: func(ctx context.Context, api base.ProgramAPIContext) ([]base.Navbar, error) {
+			list, err := base.Unwrap(api).(*WPSession).NavbarList(ctx)
+			if err != nil {
+				return nil, err
+			}
+			data := make([]base.Navbar, 0, len(list))
+			for _, n := range list {
+				data = append(data, *n)
+			}
+			return data, nil
+		},
+	})
+}
+
+func TestArticleFields(t *testing.T) {
+	srv := newRESTServer()
+	defer srv.Close()
+	s := testLogin(t, srv)
+	ctx := context.Background()
+	cate := &base.Category{Name: "news"}
+	if err := s.CategoryNew(ctx, cate); err != nil {
+		t.Fatal(err)
+	}
+	when := time.Date(2023, 5, 1, 8, 30, 0, 0, time.Local)
+	a := &base.Article{
+		Title: "hello", Content: "<p>hi</p>", Intro: "summary", Tag: []string{"go", "wp"},
+		Cate: &base.Category{Name: "news"}, Status: base.StatusAudit, IsTop: base.TopHome,
+		IsLock: base.LockClosed, PostTime: when,
+	}
+	if err := s.ArticleNew(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	got := &base.Article{Union: base.Union{ID: a.ID}}
+	if err := s.ArticleGet(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != base.StatusAudit || got.IsTop != base.TopGlobal || got.IsLock != base.LockClosed || got.Intro != "summary" {
+		t.Fatal(got.Status, got.IsTop, got.IsLock, got.Intro)
+	}
+	if got.Cate.ID != cate.ID || got.Cate.Name != "news" || strings.Join(got.Tag, ",") != "go,wp" || !got.PostTime.Equal(when) {
+		t.Fatal(got.Cate, got.Tag, got.PostTime)
+	}
+	list, total, err := s.ArticleList(ctx, &base.ListOption{Page: 2, PageSize: 1})
+	if err != nil || total != 1 || len(list) != 0 {
+		t.Fatal(list, total, err)
+	}
+}
+
+func TestArticleDefaultStatus(t *testing.T) {
+	srv := newRESTServer()
+	defer srv.Close()
+	s := testLogin(t, srv)
+	ctx := context.Background()
+	a := &base.Article{Title: "default", Content: "<p>default</p>"}
+	if err := s.ArticleNew(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	got := &base.Article{Union: base.Union{ID: a.ID}}
+	if err := s.ArticleGet(ctx, got); err != nil || got.Status != base.StatusPublic {
+		t.Fatal(got.Status, err)
+	}
+	// 修改时未指定状态则保持原状态
+	draft := &base.Article{Title: "draft", Content: "<p>draft</p>", Status: base.StatusDraft}
+	if err := s.ArticleNew(ctx, draft); err != nil {
+		t.Fatal(err)
+	}
+	draft.Status = ""
+	draft.Content = "<p>still draft</p>"
+	if err := s.ArticleNew(ctx, draft); err != nil {
+		t.Fatal(err)
+	}
+	got = &base.Article{Union: base.Union{ID: draft.ID}}
+	if err := s.ArticleGet(ctx, got); err != nil || got.Status != base.StatusDraft {
+		t.Fatal(got.Status, err)
+	}
+}
+
+func TestSiteSetting(t *testing.T) {
+	srv := newRESTServer()
+	defer srv.Close()
+	s := testLogin(t, srv)
+	ctx := context.Background()
+	off := true
+	ss := &base.SiteSetting{SiteName: "blog", PageSize: 20, CommentOff: &off, Extra: map[string]string{"use_smilies": "false"}}
+	if err := s.SiteSetting(ctx, ss); err != nil {
+		t.Fatal(err)
+	}
+	got := &base.SiteSetting{}
+	if err := s.SiteSettingGet(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.SiteName != "blog" || got.SubSiteName != "Just another WordPress site" || got.PageSize != 20 || !*got.CommentOff {
+		t.Fatal(got)
+	}
+	if srv.settings["use_smilies"] != false {
+		t.Fatal(srv.settings["use_smilies"])
+	}
+}
+
+func TestMediaUpload(t *testing.T) {
+	srv := newRESTServer()
+	defer srv.Close()
+	s := testLogin(t, srv)
+	m := &base.Media{Name: "图片.png", MimeType: "image/png", Data: []byte{1, 2, 3}}
+	if err := s.MediaUpload(context.Background(), m); err != nil || m.ID == "" || m.URL == "" {
+		t.Fatal(m, err)
+	}
+	if err := s.MediaDel(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.MediaDel(context.Background(), m); !errors.Is(err, base.MediaDelErr) {
+		t.Fatal(err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	home := &base.Fingerprint{Header: http.Header{}, Body: []byte(`<meta name="generator" content="WordPress 6.4.2" />`)}
+	if d := Detect(context.Background(), home); d == nil || d.Version != "6.4.2" || d.LoginPath != "wp-login.php" {
+		t.Fatal(d)
+	}
+	home = &base.Fingerprint{Header: http.Header{}, Body: []byte(`<a href="/zb_users/">`)}
+	if d := Detect(context.Background(), home); d != nil {
+		t.Fatal(d)
+	}
+}
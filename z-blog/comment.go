@@ -0,0 +1,158 @@
+package z_blog
+
+import (
+	"context"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CommentList 评论列表
+func (s *ZBlogSession) CommentList(ctx context.Context, opt *base.ListOption) ([]base.Comment, int, error) {
+	if opt == nil {
+		opt = &base.ListOption{}
+	}
+	param := url.Values{}
+	param.Set("search", opt.Search)
+	checking := opt.Status == base.CommentChecking
+	if checking {
+		param.Set("ischecking", "1")
+	}
+	rows, total, err := s.listAdmin(ctx, "CommentMng", param, opt, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	data := make([]base.Comment, 0, len(rows))
+	for _, r := range rows {
+		data = append(data, base.Comment{
+			Union:      base.Union{ID: r.text("id"), Type: "0"},
+			ParentID:   r.text("parent"),
+			Name:       r.text("name"),
+			Content:    r.text("content"),
+			LogID:      r.text("log"),
+			PostTime:   parseTime(r.text("date")),
+			IsChecking: checking,
+		})
+	}
+	return data, total, nil
+}
+
+// CommentApprove 通过评论
+func (s *ZBlogSession) CommentApprove(ctx context.Context, c *base.Comment) error {
+	if err := s.commentChk(ctx, c, "0"); err != nil {
+		return err
+	}
+	c.IsChecking = false
+	return nil
+}
+
+// CommentUnapprove 评论转为待审核
+func (s *ZBlogSession) CommentUnapprove(ctx context.Context, c *base.Comment) error {
+	if err := s.commentChk(ctx, c, "1"); err != nil {
+		return err
+	}
+	c.IsChecking = true
+	return nil
+}
+
+func (s *ZBlogSession) commentChk(ctx context.Context, c *base.Comment, checking string) error {
+	if c.ID == "0" || c.ID == "" {
+		return s.invalid("CommentChk", "请指定评论的id")
+	}
+	param := url.Values{}
+	param.Set("id", c.ID)
+	param.Set("ischecking", checking)
+	req, err := s.NewRequest(ctx, http.MethodGet, s.ParamCSRF(ctx, "cmd.php", "CommentChk", param), nil)
+	if err != nil {
+		return err
+	}
+	var resp *http.Response
+	if resp, err = s.do("CommentChk", req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode == 302 {
+		return nil
+	}
+	return s.fail("CommentChk", base.CommentChkErr, resp)
+}
+
+// CommentReply 回复评论 通过文章页的评论表单以当前登录的用户提交
+// 提交成功时跳转回文章页，评论被拒绝时返回 200 的错误页
+func (s *ZBlogSession) CommentReply(ctx context.Context, parent *base.Comment, reply *base.Comment) error {
+	if parent.ID == "0" || parent.ID == "" || parent.LogID == "" {
+		return s.invalid("CommentReply", "请指定评论的id及文章id")
+	}
+	param := url.Values{}
+	param.Set("id", parent.LogID)
+	req, err := s.NewRequestHome(ctx, http.MethodGet, "index.php?"+param.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	var resp *http.Response
+	if resp, err = s.do("CommentReply", req); err != nil {
+		return err
+	}
+	var doc *goquery.Document
+	doc, err = goquery.NewDocumentFromReader(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	action := doc.Find("#frmSumbit").AttrOr("action", "")
+	if action == "" {
+		return s.fail("CommentReply", base.CommentReplyErr, resp)
+	}
+	// 表单地址为完整的URL
+	action = strings.TrimPrefix(action, s.zb.HomeURL)
+	form := url.Values{}
+	form.Set("inpId", parent.LogID)
+	form.Set("inpRevID", parent.ID)
+	form.Set("inpName", reply.Name)
+	form.Set("inpEmail", reply.Email)
+	form.Set("inpHomePage", reply.HomePage)
+	form.Set("txaArticle", reply.Content)
+	if req, err = s.NewRequestHome(ctx, http.MethodPost, action, form); err != nil {
+		return err
+	}
+	if resp, err = s.do("CommentReply", req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode == 302 {
+		reply.LogID = parent.LogID
+		reply.ParentID = parent.ID
+		return nil
+	}
+	return s.fail("CommentReply", base.CommentReplyErr, resp)
+}
+
+// CommentDel 删除评论
+func (s *ZBlogSession) CommentDel(ctx context.Context, c *base.Comment) error {
+	if c.ID == "0" || c.ID == "" {
+		return s.invalid("CommentDel", "请指定评论的id")
+	}
+	param := url.Values{}
+	param.Set("id", c.ID)
+	req, err := s.NewRequest(ctx, http.MethodGet, s.ParamCSRF(ctx, "cmd.php", "CommentDel", param), nil)
+	if err != nil {
+		return err
+	}
+	var resp *http.Response
+	if resp, err = s.do("CommentDel", req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode == 302 {
+		return nil
+	}
+	return s.fail("CommentDel", base.CommentDelErr, resp)
+}
@@ -0,0 +1,42 @@
+package z_blog
+
+import (
+	"context"
+	"errors"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCommentReply(t *testing.T) {
+	reject := false
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`<form id="frmSumbit" method="post" action="` + srv.URL + `/zb_system/cmd.php?act=cmt&postid=1&key=k"></form>`))
+			return
+		}
+		if reject {
+			// 评论被拒绝时 Z-BlogPHP 以 200 返回错误页
+			_, _ = w.Write([]byte(`<div class="login loginw"><form id="frmLogin"><div class="content lessinfo"><p>名称不能为空</p></div></form></div>`))
+			return
+		}
+		http.Redirect(w, r, "/?id=1#cmt2", http.StatusFound)
+	}))
+	defer srv.Close()
+	s := &ZBlogSession{zb: base.ProgramBaseInfo{HomeURL: srv.URL + "/"}}
+	ctx := context.Background()
+	parent := &base.Comment{Union: base.Union{ID: "1"}, LogID: "1"}
+	reply := &base.Comment{Name: "admin", Content: "reply"}
+	if err := s.CommentReply(ctx, parent, reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.ParentID != "1" || reply.LogID != "1" {
+		t.Fatal(reply)
+	}
+	reject = true
+	if err := s.CommentReply(ctx, parent, &base.Comment{Content: "reply"}); !errors.Is(err, base.CommentReplyErr) {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,37 @@
+package z_blog
+
+import (
+	"context"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"regexp"
+)
+
+// versionRegexp 匹配 generator 或 Product 头中的版本 如 Z-BlogPHP 1.7.3
+var versionRegexp = regexp.MustCompile(`Z-BlogPHP\s*([\d.]+)`)
+
+// Detect 识别 Z-BlogPHP 依次检查 Product 头、meta generator、zb_users 路径及后台登录页
+func Detect(ctx context.Context, home *base.Fingerprint) *base.Detection {
+	d := &base.Detection{Program: "z-blog", BackstagePath: "zb_system/", LoginPath: "cmd.php?act=verify"}
+	for _, s := range []string{home.Header.Get("Product"), home.Generator()} {
+		if m := versionRegexp.FindStringSubmatch(s); m != nil {
+			d.Version = m[1]
+			d.Score = 100
+			return d
+		}
+	}
+	if !home.Contains("zb_users/") && !home.Contains("zb_system/") {
+		return nil
+	}
+	d.Score = 60
+	// 首页模板未输出版本时 从后台登录页读取
+	if login, err := home.Fetch(ctx, "zb_system/login.php"); err == nil && login.Status == 200 {
+		for _, s := range []string{login.Header.Get("Product"), login.Generator()} {
+			if m := versionRegexp.FindStringSubmatch(s); m != nil {
+				d.Version = m[1]
+				d.Score = 90
+				break
+			}
+		}
+	}
+	return d
+}
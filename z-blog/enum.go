@@ -0,0 +1,27 @@
+package z_blog
+
+import "github.com/cgghui/bt_site_cluster_program_api/base"
+
+// Z-Blog 的文章状态、置顶与 base 中的取值一致，评论开关 IsLock 在后台为布尔值
+
+// lockValue 评论开关对应的表单值
+func lockValue(l base.LockState) string {
+	switch l {
+	case "":
+		return ""
+	case base.LockClosed:
+		return "1"
+	}
+	return "0"
+}
+
+// lockState 表单值对应的评论开关
+func lockState(v string) base.LockState {
+	switch v {
+	case "":
+		return ""
+	case "0":
+		return base.LockOpen
+	}
+	return base.LockClosed
+}
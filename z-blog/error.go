@@ -0,0 +1,62 @@
+package z_blog
+
+import (
+	"errors"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// wrap 包装请求过程中的错误
+func (s *ZBlogSession) wrap(op string, req *http.Request, err error) error {
+	e := &base.Error{Op: op, Site: s.zb.HomeURL, Err: err}
+	if req != nil {
+		e.Endpoint = endpoint(req.Method, req.URL)
+	}
+	return e
+}
+
+// fail 根据响应构造错误 resp 可为nil，body 为已读取的响应内容，未传入时尝试从 resp.Body 读取
+func (s *ZBlogSession) fail(op string, sentinel error, resp *http.Response, body ...[]byte) error {
+	e := &base.Error{Op: op, Site: s.zb.HomeURL, Sentinel: sentinel}
+	if resp == nil {
+		return e
+	}
+	e.Status = resp.StatusCode
+	if resp.Request != nil {
+		e.Endpoint = endpoint(resp.Request.Method, resp.Request.URL)
+	}
+	var b []byte
+	if len(body) > 0 {
+		b = body[0]
+	} else {
+		b, _ = ioutil.ReadAll(io.LimitReader(resp.Body, base.ErrorBodyLimit))
+	}
+	if len(b) > base.ErrorBodyLimit {
+		b = b[:base.ErrorBodyLimit]
+	}
+	e.Body = string(b)
+	return e
+}
+
+// cause 以 sentinel 包装其它操作返回的错误
+func (s *ZBlogSession) cause(op string, sentinel, err error) error {
+	return &base.Error{Op: op, Site: s.zb.HomeURL, Sentinel: sentinel, Err: err}
+}
+
+// invalid 参数错误
+func (s *ZBlogSession) invalid(op, msg string) error {
+	return &base.Error{Op: op, Site: s.zb.HomeURL, Kind: base.KindValidation, Err: errors.New(msg)}
+}
+
+// endpoint 去除 csrfToken 的请求地址
+func endpoint(method string, u *url.URL) string {
+	q := u.Query()
+	q.Del("csrfToken")
+	if len(q) == 0 {
+		return method + " " + u.Path
+	}
+	return method + " " + u.Path + "?" + q.Encode()
+}
@@ -0,0 +1,247 @@
+package z_blog
+
+import (
+	"context"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var pageRegexp = regexp.MustCompile(`[?&]page=(\d+)`)
+
+// articleStatus 后台状态文本对应的值
+var articleStatus = map[string]base.Status{
+	"公开": base.StatusPublic,
+	"草稿": base.StatusDraft,
+	"审核": base.StatusAudit,
+}
+
+// AdminDoc 获取后台管理页 page 从1开始 param 不为nil时以POST提交
+func (s *ZBlogSession) AdminDoc(ctx context.Context, act string, page int, param url.Values) (*goquery.Document, error) {
+	query := url.Values{}
+	if page > 1 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	method := http.MethodGet
+	if param != nil {
+		method = http.MethodPost
+	}
+	req, err := s.NewRequest(ctx, method, s.ParamCSRF(ctx, "admin/index.php", act, query), param)
+	if err != nil {
+		return nil, err
+	}
+	var resp *http.Response
+	if resp, err = s.do(act, req); err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != 200 {
+		return nil, s.fail(act, StatusCodeNot200Err, resp)
+	}
+	return goquery.NewDocumentFromReader(resp.Body)
+}
+
+// adminPage 读取后台管理页的一页 返回数据行及最后一页的页码
+func (s *ZBlogSession) adminPage(ctx context.Context, act string, page int, param url.Values) ([]row, int, error) {
+	p, err := s.profile(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	doc, err := s.AdminDoc(ctx, act, page, param)
+	if err != nil {
+		return nil, 0, err
+	}
+	rows := make([]row, 0)
+	err = s.eachRow(ctx, act, doc, func(r row) bool {
+		rows = append(rows, r)
+		return true
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	last := page
+	doc.Find(p.pagebar).Each(func(_ int, a *goquery.Selection) {
+		m := pageRegexp.FindStringSubmatch(a.AttrOr("href", ""))
+		if m == nil {
+			return
+		}
+		if n, _ := strconv.Atoi(m[1]); n > last {
+			last = n
+		}
+	})
+	return rows, last, nil
+}
+
+// walkAdmin 依次遍历后台管理页的分页 fn 返回 false 时停止
+func (s *ZBlogSession) walkAdmin(ctx context.Context, act string, param url.Values, fn func(r row) bool) error {
+	for page, last := 1, 1; page <= last; page++ {
+		rows, n, err := s.adminPage(ctx, act, page, param)
+		if err != nil {
+			return err
+		}
+		last = n
+		for _, r := range rows {
+			if !fn(r) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// listAdmin 按 ListOption 读取后台列表 返回当页的数据行及总数
+// 未指定页码或需要以 match 在本地过滤时读取全部分页，否则只读取第一页、最后一页及覆盖所需区间的分页
+func (s *ZBlogSession) listAdmin(ctx context.Context, act string, param url.Values, opt *base.ListOption, match func(r row) bool) ([]row, int, error) {
+	if opt == nil {
+		opt = &base.ListOption{}
+	}
+	if opt.Page <= 0 || match != nil {
+		rows := make([]row, 0)
+		err := s.walkAdmin(ctx, act, param, func(r row) bool {
+			if match == nil || match(r) {
+				rows = append(rows, r)
+			}
+			return true
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		start, end := opt.Paginate(len(rows))
+		return rows[start:end], len(rows), nil
+	}
+	first, last, err := s.adminPage(ctx, act, 1, param)
+	if err != nil {
+		return nil, 0, err
+	}
+	if last <= 1 || len(first) == 0 {
+		start, end := opt.Paginate(len(first))
+		return first[start:end], len(first), nil
+	}
+	// 除最后一页外每页的数量相同 以第一页的数量计算总数及所需的分页
+	per := len(first)
+	pages := map[int][]row{1: first}
+	if pages[last], _, err = s.adminPage(ctx, act, last, param); err != nil {
+		return nil, 0, err
+	}
+	total := (last-1)*per + len(pages[last])
+	start, end := opt.Paginate(total)
+	data := make([]row, 0, end-start)
+	for page := start/per + 1; start < end && page <= (end-1)/per+1; page++ {
+		rows, ok := pages[page]
+		if !ok {
+			if rows, _, err = s.adminPage(ctx, act, page, param); err != nil {
+				return nil, 0, err
+			}
+		}
+		for i, r := range rows {
+			if n := (page-1)*per + i; n >= start && n < end {
+				data = append(data, r)
+			}
+		}
+	}
+	return data, total, nil
+}
+
+// ArticleList 文章列表
+func (s *ZBlogSession) ArticleList(ctx context.Context, opt *base.ListOption) ([]base.Article, int, error) {
+	if opt == nil {
+		opt = &base.ListOption{}
+	}
+	rows, total, err := s.listAdmin(ctx, "ArticleMng", articleParam(opt.Search, opt), opt, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	data := make([]base.Article, 0, len(rows))
+	for _, r := range rows {
+		data = append(data, articleRow(r))
+	}
+	return data, total, nil
+}
+
+// articleParam 文章管理页的查询条件
+func articleParam(search string, opt *base.ListOption) url.Values {
+	param := url.Values{}
+	param.Set("category", opt.CateID)
+	param.Set("status", opt.Status)
+	param.Set("search", search)
+	return param
+}
+
+// articleRow 文章管理页的一行
+func articleRow(r row) base.Article {
+	return base.Article{
+		Union:     base.Union{ID: r.text("id"), Type: "0"},
+		Title:     r.text("title"),
+		Permalink: r.href("title"),
+		Cate:      &base.Category{Name: r.text("cate")},
+		Status:    articleStatus[r.text("status")],
+		PostTime:  parseTime(r.text("date")),
+	}
+}
+
+// CategoryList 分类列表
+func (s *ZBlogSession) CategoryList(ctx context.Context, opt *base.ListOption) ([]base.Category, int, error) {
+	if opt == nil {
+		opt = &base.ListOption{}
+	}
+	var match func(r row) bool
+	if opt.Search != "" {
+		match = func(r row) bool {
+			return strings.Contains(r.text("name"), opt.Search)
+		}
+	}
+	rows, total, err := s.listAdmin(ctx, "CategoryMng", nil, opt, match)
+	if err != nil {
+		return nil, 0, err
+	}
+	data := make([]base.Category, 0, len(rows))
+	for _, r := range rows {
+		data = append(data, base.Category{
+			Union: base.Union{ID: r.text("id"), Type: "0"},
+			Order: r.text("order"),
+			Name:  r.text("name"),
+			Alias: r.text("alias"),
+		})
+	}
+	return data, total, nil
+}
+
+// TagList 标签列表
+func (s *ZBlogSession) TagList(ctx context.Context, opt *base.ListOption) ([]base.Tag, int, error) {
+	if opt == nil {
+		opt = &base.ListOption{}
+	}
+	param := url.Values{}
+	param.Set("search", opt.Search)
+	rows, total, err := s.listAdmin(ctx, "TagMng", param, opt, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	data := make([]base.Tag, 0, len(rows))
+	for _, r := range rows {
+		data = append(data, base.Tag{
+			Union: base.Union{ID: r.text("id"), Type: "0"},
+			Name:  r.text("name"),
+			Alias: r.text("alias"),
+		})
+	}
+	return data, total, nil
+}
+
+// parseTime 解析后台显示的时间 失败返回零值
+func parseTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
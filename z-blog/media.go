@@ -0,0 +1,119 @@
+package z_blog
+
+import (
+	"context"
+	"fmt"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+// MediaUpload 上传附件 上传后从附件管理中查找访问地址
+func (s *ZBlogSession) MediaUpload(ctx context.Context, m *base.Media) error {
+	// 记录上传前最新的附件 上传后只在其后的附件中查找
+	last, err := s.mediaLast(ctx)
+	if err != nil {
+		return err
+	}
+	req, err := s.NewUploadRequest(ctx, s.zb.BackstagePath+"/"+s.ParamCSRF(ctx, "cmd.php", "UploadPst"), "file", m.Name, m.MimeType, m.Data, nil)
+	if err != nil {
+		return err
+	}
+	var resp *http.Response
+	if resp, err = s.do("MediaUpload", req); err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != 302 {
+		return s.fail("MediaUpload", base.MediaUploadErr, resp)
+	}
+	return s.mediaLocate(ctx, m, last)
+}
+
+// mediaEach 遍历附件管理第一页的附件
+func (s *ZBlogSession) mediaEach(ctx context.Context, fn func(v *base.Media)) error {
+	doc, err := s.AdminDoc(ctx, "UploadMng", 1, nil)
+	if err != nil {
+		return err
+	}
+	return s.eachRow(ctx, "UploadMng", doc, func(r row) bool {
+		v := &base.Media{
+			Union: base.Union{ID: r.text("id")},
+			URL:   r.href("url"),
+		}
+		if v.URL != "" {
+			fn(v)
+		}
+		return true
+	})
+}
+
+// mediaLast 最新附件的ID 没有附件时为空
+func (s *ZBlogSession) mediaLast(ctx context.Context) (string, error) {
+	last := ""
+	err := s.mediaEach(ctx, func(v *base.Media) {
+		if last == "" || articleIDLess(last, v.ID) {
+			last = v.ID
+		}
+	})
+	return last, err
+}
+
+// mediaLocate 在 last 之后新增的附件中查找本次上传的附件
+// 取文件名相同且ID最大者；站点重命名了附件时，新增的附件只有一个才视为本次上传
+// 无法确定时删除新增的附件并返回错误，避免留下无人使用的附件
+func (s *ZBlogSession) mediaLocate(ctx context.Context, m *base.Media, last string) error {
+	var found *base.Media
+	fresh := make([]*base.Media, 0)
+	err := s.mediaEach(ctx, func(v *base.Media) {
+		if last != "" && !articleIDLess(last, v.ID) {
+			return
+		}
+		fresh = append(fresh, v)
+		if path.Base(v.URL) == m.Name && (found == nil || articleIDLess(found.ID, v.ID)) {
+			found = v
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if found == nil && len(fresh) == 1 {
+		found = fresh[0]
+	}
+	if found == nil {
+		for _, v := range fresh {
+			if err = s.MediaDel(ctx, v); err != nil {
+				return s.cause("MediaUpload", base.MediaUploadErr, fmt.Errorf("无法确定上传的文件 %s，删除新增的附件 %s 失败: %w", m.Name, v.URL, err))
+			}
+		}
+		return s.cause("MediaUpload", base.MediaUploadErr, fmt.Errorf("附件管理中找不到上传的文件 %s，已删除新增的附件 %d 个", m.Name, len(fresh)))
+	}
+	m.ID = found.ID
+	m.URL = found.URL
+	return nil
+}
+
+// MediaDel 删除附件
+func (s *ZBlogSession) MediaDel(ctx context.Context, m *base.Media) error {
+	if m.ID == "0" || m.ID == "" {
+		return s.invalid("MediaDel", "请指定附件的id")
+	}
+	param := url.Values{}
+	param.Set("id", m.ID)
+	req, err := s.NewRequest(ctx, http.MethodGet, s.ParamCSRF(ctx, "cmd.php", "UploadDel", param), nil)
+	if err != nil {
+		return err
+	}
+	var resp *http.Response
+	if resp, err = s.do("MediaDel", req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode == 302 {
+		return nil
+	}
+	return s.fail("MediaDel", base.MediaDelErr, resp)
+}
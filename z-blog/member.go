@@ -0,0 +1,201 @@
+package z_blog
+
+import (
+	"context"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// memberLevel 后台角色文本对应的值
+var memberLevel = map[string]string{
+	"管理员":  "1",
+	"网站编辑": "2",
+	"作者":   "3",
+	"协作者":  "4",
+	"评论员":  "5",
+	"游客":   "6",
+}
+
+// MemberList 用户列表
+func (s *ZBlogSession) MemberList(ctx context.Context, opt *base.ListOption) ([]base.Member, int, error) {
+	if opt == nil {
+		opt = &base.ListOption{}
+	}
+	var match func(r row) bool
+	if opt.Search != "" {
+		match = func(r row) bool {
+			return strings.Contains(r.text("name"), opt.Search) || strings.Contains(r.text("alias"), opt.Search)
+		}
+	}
+	rows, total, err := s.listAdmin(ctx, "MemberMng", nil, opt, match)
+	if err != nil {
+		return nil, 0, err
+	}
+	data := make([]base.Member, 0, len(rows))
+	for _, r := range rows {
+		data = append(data, base.Member{
+			Union: base.Union{ID: r.text("id")},
+			Level: memberLevel[r.text("level")],
+			Name:  r.text("name"),
+			Alias: r.text("alias"),
+		})
+	}
+	return data, total, nil
+}
+
+// MemberGet 获取用户 未指定ID时按登录名查找
+func (s *ZBlogSession) MemberGet(ctx context.Context, m *base.Member) error {
+	id := m.ID
+	if id == "" || id == "0" {
+		list, _, err := s.MemberList(ctx, &base.ListOption{Search: m.Name})
+		if err != nil {
+			return err
+		}
+		for _, v := range list {
+			if v.Name == m.Name {
+				id = v.ID
+				break
+			}
+		}
+		if id == "" || id == "0" {
+			return s.fail("MemberGet", base.MemberGetErr, nil)
+		}
+	}
+	param := url.Values{}
+	param.Set("id", id)
+	req, err := s.NewRequest(ctx, http.MethodGet, s.ParamCSRF(ctx, "admin/member_edit.php", "MemberEdt", param), nil)
+	if err != nil {
+		return err
+	}
+	var resp *http.Response
+	if resp, err = s.do("MemberGet", req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != 200 {
+		return s.fail("MemberGet", base.MemberGetErr, resp)
+	}
+	var doc *goquery.Document
+	if doc, err = goquery.NewDocumentFromReader(resp.Body); err != nil {
+		return err
+	}
+	if formValue(doc, "ID") != id {
+		return s.fail("MemberGet", base.MemberGetErr, resp)
+	}
+	m.ID = id
+	m.Level = formValue(doc, "Level")
+	m.Status = formValue(doc, "Status")
+	m.Name = formValue(doc, "Name")
+	m.Alias = formValue(doc, "Alias")
+	m.Email = formValue(doc, "Email")
+	m.HomePage = formValue(doc, "HomePage")
+	m.Intro = formValue(doc, "Intro")
+	m.Template = formValue(doc, "Template")
+	return nil
+}
+
+// MemberNew 创建或修改用户 修改时会提交全部字段，应先以 MemberGet 读取
+func (s *ZBlogSession) MemberNew(ctx context.Context, m *base.Member) error {
+	param := url.Values{}
+	if m.ID == "" {
+		param.Set("ID", "0")
+	} else {
+		param.Set("ID", m.ID)
+	}
+	param.Set("Level", m.Level)
+	param.Set("Status", m.Status)
+	param.Set("Name", m.Name)
+	param.Set("Password", m.Password)
+	param.Set("PasswordRe", m.Password)
+	param.Set("Alias", m.Alias)
+	param.Set("Email", m.Email)
+	param.Set("HomePage", m.HomePage)
+	param.Set("Intro", m.Intro)
+	param.Set("Template", m.Template)
+	req, err := s.NewRequest(ctx, http.MethodPost, s.ParamCSRF(ctx, "cmd.php", "MemberPst"), param)
+	if err != nil {
+		return err
+	}
+	var resp *http.Response
+	if resp, err = s.do("MemberNew", req); err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != 302 {
+		return s.fail("MemberNew", base.MemberNewErr, resp)
+	}
+	if m.ID == "" || m.ID == "0" {
+		v := base.Member{Name: m.Name}
+		if err = s.MemberGet(ctx, &v); err == nil {
+			m.ID = v.ID
+		}
+	}
+	return nil
+}
+
+// MemberPassword 修改用户密码 修改的是当前登录的用户时同时更新会话保存的密码，之后重新登录使用新密码
+func (s *ZBlogSession) MemberPassword(ctx context.Context, m *base.Member, password string) error {
+	if password == "" {
+		return s.invalid("MemberPassword", "密码不能为空")
+	}
+	cur := base.Member{Union: m.Union, Name: m.Name}
+	if err := s.MemberGet(ctx, &cur); err != nil {
+		return err
+	}
+	cur.Password = password
+	if err := s.MemberNew(ctx, &cur); err != nil {
+		return err
+	}
+	if cur.Name == s.username {
+		s.mu.Lock()
+		s.password = password
+		s.mu.Unlock()
+	}
+	m.ID = cur.ID
+	m.Password = password
+	return nil
+}
+
+// MemberRole 设置用户角色
+func (s *ZBlogSession) MemberRole(ctx context.Context, m *base.Member, level string) error {
+	cur := base.Member{Union: m.Union, Name: m.Name}
+	if err := s.MemberGet(ctx, &cur); err != nil {
+		return err
+	}
+	cur.Level = level
+	if err := s.MemberNew(ctx, &cur); err != nil {
+		return err
+	}
+	m.ID = cur.ID
+	m.Level = level
+	return nil
+}
+
+// MemberDel 删除用户
+func (s *ZBlogSession) MemberDel(ctx context.Context, m *base.Member) error {
+	if m.ID == "0" || m.ID == "" {
+		return s.invalid("MemberDel", "请指定用户的id")
+	}
+	param := url.Values{}
+	param.Set("id", m.ID)
+	req, err := s.NewRequest(ctx, http.MethodGet, s.ParamCSRF(ctx, "cmd.php", "MemberDel", param), nil)
+	if err != nil {
+		return err
+	}
+	var resp *http.Response
+	if resp, err = s.do("MemberDel", req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode == 302 {
+		return nil
+	}
+	return s.fail("MemberDel", base.MemberDelErr, resp)
+}
@@ -0,0 +1,117 @@
+package z_blog
+
+import (
+	"bytes"
+	"context"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var checkNewPageSuccess = []byte("cmd.php%3Fact%3DPageMng")
+
+// PageNew 新建或修改独立页面 成功后填充 Article.ID 及 Article.Permalink，已保存但在列表中找不到时返回 ArticleLocateErr
+func (s *ZBlogSession) PageNew(ctx context.Context, a *base.Article) error {
+	if err := a.Validate(); err != nil {
+		return s.cause("PageNew", base.PageNewErr, err)
+	}
+	page := url.Values{}
+	if a.ID == "" {
+		page.Add("ID", "0")
+	} else {
+		page.Add("ID", a.ID)
+	}
+	page.Add("Type", base.TypePage)
+	page.Add("Title", a.Title)
+	page.Add("Content", a.Content)
+	page.Add("Alias", a.Alias)
+	page.Add("Status", string(a.Status))
+	page.Add("Template", a.Template)
+	page.Add("AuthorID", a.AuthorID)
+	page.Add("PostTime", a.PostTime.Format("2006-01-02 15:04:05"))
+	page.Add("IsLock", lockValue(a.IsLock))
+	req, err := s.NewRequest(ctx, http.MethodPost, s.ParamCSRF(ctx, "cmd.php", "PagePst"), page)
+	if err != nil {
+		return err
+	}
+	var resp *http.Response
+	if resp, err = s.do("PageNew", req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	var body []byte
+	if body, err = ioutil.ReadAll(resp.Body); err != nil {
+		return err
+	}
+	if resp.StatusCode != 302 && !bytes.Contains(body, checkNewPageSuccess) {
+		return s.fail("PageNew", base.PageNewErr, resp, body)
+	}
+	a.Type = base.TypePage
+	return s.articleLocate(ctx, "PageNew", pageKind, a)
+}
+
+// PageGet 获取独立页面 查找规则同 ArticleGet
+func (s *ZBlogSession) PageGet(ctx context.Context, a *base.Article) error {
+	return s.articleFind(ctx, pageKind, a)
+}
+
+// PageList 独立页面列表
+func (s *ZBlogSession) PageList(ctx context.Context, opt *base.ListOption) ([]base.Article, int, error) {
+	if opt == nil {
+		opt = &base.ListOption{}
+	}
+	var match func(r row) bool
+	if opt.Search != "" {
+		match = func(r row) bool {
+			return strings.Contains(r.text("title"), opt.Search)
+		}
+	}
+	rows, total, err := s.listAdmin(ctx, "PageMng", nil, opt, match)
+	if err != nil {
+		return nil, 0, err
+	}
+	data := make([]base.Article, 0, len(rows))
+	for _, r := range rows {
+		data = append(data, pageRow(r))
+	}
+	return data, total, nil
+}
+
+// pageRow 页面管理页的一行
+func pageRow(r row) base.Article {
+	return base.Article{
+		Union:     base.Union{ID: r.text("id"), Type: base.TypePage},
+		Title:     r.text("title"),
+		Permalink: r.href("title"),
+		PostTime:  parseTime(r.text("date")),
+		Status:    articleStatus[r.text("status")],
+	}
+}
+
+// PageDel 删除独立页面
+func (s *ZBlogSession) PageDel(ctx context.Context, a *base.Article) error {
+	if a.ID == "0" || a.ID == "" {
+		return s.invalid("PageDel", "请指定页面的id")
+	}
+	param := url.Values{}
+	param.Set("id", a.ID)
+	req, err := s.NewRequest(ctx, http.MethodGet, s.ParamCSRF(ctx, "cmd.php", "PageDel", param), nil)
+	if err != nil {
+		return err
+	}
+	var resp *http.Response
+	if resp, err = s.do("PageDel", req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode == 302 {
+		return nil
+	}
+	return s.fail("PageDel", base.PageDelErr, resp)
+}
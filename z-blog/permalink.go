@@ -0,0 +1,73 @@
+package z_blog
+
+import (
+	"context"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"net/http"
+	"net/url"
+)
+
+const rewritePage = "zb_users/plugin/STACentre/main.php"
+
+// PermalinkSet 通过 STACentre 插件开启伪静态并设置链接结构
+func (s *ZBlogSession) PermalinkSet(ctx context.Context, pl *base.Permalink) error {
+	param := url.Values{}
+	param.Set("csrfToken", s.GetCSRF(ctx))
+	param.Set("reset", "")
+	param.Set("ZC_STATIC_MODE", "REWRITE")
+	param.Set("ZC_ARTICLE_REGEX", pl.Article)
+	param.Set("ZC_PAGE_REGEX", pl.Page)
+	param.Set("ZC_INDEX_REGEX", pl.Index)
+	param.Set("ZC_CATEGORY_REGEX", pl.Category)
+	param.Set("ZC_TAGS_REGEX", pl.Tag)
+	param.Set("radioZC_TAGS_REGEX", pl.Tag)
+	param.Set("ZC_DATE_REGEX", pl.Date)
+	param.Set("ZC_AUTHOR_REGEX", pl.Author)
+	req, err := s.NewRequestHome(ctx, http.MethodPost, s.ParamCSRF(ctx, rewritePage, ""), param)
+	if err != nil {
+		return err
+	}
+	var resp *http.Response
+	if resp, err = s.do("PermalinkSet", req); err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != 302 {
+		return s.fail("PermalinkSet", base.PermalinkSetErr, resp)
+	}
+	return nil
+}
+
+// PermalinkGet 读取 STACentre 插件中的链接结构
+func (s *ZBlogSession) PermalinkGet(ctx context.Context) (*base.Permalink, error) {
+	req, err := s.NewRequestHome(ctx, http.MethodGet, s.ParamCSRF(ctx, rewritePage, ""), nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp *http.Response
+	if resp, err = s.do("PermalinkGet", req); err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != 200 {
+		return nil, s.fail("PermalinkGet", StatusCodeNot200Err, resp)
+	}
+	var doc *goquery.Document
+	if doc, err = goquery.NewDocumentFromReader(resp.Body); err != nil {
+		return nil, err
+	}
+	pl := &base.Permalink{
+		Article:  formValue(doc, "ZC_ARTICLE_REGEX"),
+		Page:     formValue(doc, "ZC_PAGE_REGEX"),
+		Index:    formValue(doc, "ZC_INDEX_REGEX"),
+		Category: formValue(doc, "ZC_CATEGORY_REGEX"),
+		Tag:      formValue(doc, "ZC_TAGS_REGEX"),
+		Date:     formValue(doc, "ZC_DATE_REGEX"),
+		Author:   formValue(doc, "ZC_AUTHOR_REGEX"),
+	}
+	pl.Fill()
+	return pl, nil
+}
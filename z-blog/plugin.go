@@ -0,0 +1,224 @@
+package z_blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var pluginNameRegexp = regexp.MustCompile(`[?&]name=([^&]+)`)
+
+// PluginList 已安装的插件 通过插件管理页中的启用、停用链接识别插件标识及状态
+func (s *ZBlogSession) PluginList(ctx context.Context) ([]base.Plugin, error) {
+	doc, err := s.AdminDoc(ctx, "PluginMng", 1, nil)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]base.Plugin, 0)
+	err = s.eachRow(ctx, "PluginMng", doc, func(r row) bool {
+		if p, ok := pluginRow(r); ok {
+			data = append(data, p)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// pluginRow 读取插件管理页的一行 没有启用或停用链接的行不是插件
+func pluginRow(r row) (base.Plugin, bool) {
+	p := base.Plugin{}
+	r.td.Find("a").EachWithBreak(func(_ int, a *goquery.Selection) bool {
+		href := a.AttrOr("href", "")
+		m := pluginNameRegexp.FindStringSubmatch(href)
+		if m == nil {
+			return true
+		}
+		if strings.Contains(href, "act=PluginDis") {
+			p.Enabled = true
+		} else if !strings.Contains(href, "act=PluginEnb") {
+			return true
+		}
+		p.ID, _ = url.QueryUnescape(m[1])
+		return false
+	})
+	if p.ID == "" {
+		return p, false
+	}
+	name := strings.Fields(r.text("name"))
+	if len(name) > 0 {
+		p.Name = name[0]
+	}
+	if len(name) > 1 {
+		p.Version = name[len(name)-1]
+	}
+	return p, true
+}
+
+// PluginEnable 启用插件并执行插件的安装过程
+func (s *ZBlogSession) PluginEnable(ctx context.Context, p *base.Plugin) error {
+	if p.ID == "" {
+		return s.invalid("PluginEnable", "请指定插件的id")
+	}
+	param := url.Values{}
+	param.Set("name", p.ID)
+	req, err := s.NewRequest(ctx, http.MethodGet, s.ParamCSRF(ctx, "cmd.php", "PluginEnb", param), nil)
+	if err != nil {
+		return err
+	}
+	var resp *http.Response
+	if resp, err = s.do("PluginEnable", req); err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != 302 {
+		return s.fail("PluginEnable", base.PluginEnableErr, resp)
+	}
+	param = url.Values{}
+	param.Set("install", p.ID)
+	req, err = s.NewRequest(ctx, http.MethodGet, s.ParamCSRF(ctx, "cmd.php", "PluginMng", param), nil)
+	if err != nil {
+		return err
+	}
+	if resp, err = s.do("PluginEnable", req); err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != 302 {
+		return s.fail("PluginEnable", base.PluginEnableErr, resp)
+	}
+	p.Enabled = true
+	return nil
+}
+
+// PluginDisable 停用插件
+func (s *ZBlogSession) PluginDisable(ctx context.Context, p *base.Plugin) error {
+	if p.ID == "" {
+		return s.invalid("PluginDisable", "请指定插件的id")
+	}
+	param := url.Values{}
+	param.Set("name", p.ID)
+	req, err := s.NewRequest(ctx, http.MethodGet, s.ParamCSRF(ctx, "cmd.php", "PluginDis", param), nil)
+	if err != nil {
+		return err
+	}
+	var resp *http.Response
+	if resp, err = s.do("PluginDisable", req); err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != 302 {
+		return s.fail("PluginDisable", base.PluginDisableErr, resp)
+	}
+	p.Enabled = false
+	return nil
+}
+
+// PluginInstall 通过应用中心上传 .zba 插件包 上传后重新读取插件列表确认安装结果
+// 指定 Plugin.ID 时确认该插件已在列表中，未指定时以列表中新出现的插件作为结果
+// 覆盖安装已有的插件时须指定 Plugin.ID，否则无法确认
+func (s *ZBlogSession) PluginInstall(ctx context.Context, p *base.Plugin, pkg []byte) error {
+	before, err := s.PluginList(ctx)
+	if err != nil {
+		return err
+	}
+	param := url.Values{}
+	param.Set("csrfToken", s.GetCSRF(ctx))
+	filename := "app.zba"
+	if p.ID != "" {
+		filename = p.ID + ".zba"
+	}
+	req, err := s.NewUploadRequest(ctx, "zb_users/plugin/AppCentre/app_upload.php", "edtFileLoad", filename, "application/octet-stream", pkg, param)
+	if err != nil {
+		return err
+	}
+	var resp *http.Response
+	if resp, err = s.do("PluginInstall", req); err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != 200 && resp.StatusCode != 302 {
+		return s.fail("PluginInstall", base.PluginInstallErr, resp)
+	}
+	// 应用中心出错时同样返回 200 是否安装成功以插件列表为准
+	after, err := s.PluginList(ctx)
+	if err != nil {
+		return err
+	}
+	if v := installedPlugin(p.ID, before, after); v != nil {
+		*p = *v
+		return nil
+	}
+	if p.ID != "" {
+		return s.cause("PluginInstall", base.PluginInstallErr, fmt.Errorf("插件列表中没有 %s", p.ID))
+	}
+	return s.cause("PluginInstall", base.PluginInstallErr, errors.New("插件列表中没有新安装的插件"))
+}
+
+// installedPlugin 安装后的插件 id 为空时取 after 中唯一新出现的插件
+func installedPlugin(id string, before, after []base.Plugin) *base.Plugin {
+	if id != "" {
+		for i := range after {
+			if after[i].ID == id {
+				return &after[i]
+			}
+		}
+		return nil
+	}
+	old := make(map[string]bool, len(before))
+	for _, v := range before {
+		old[v.ID] = true
+	}
+	var found *base.Plugin
+	for i := range after {
+		if old[after[i].ID] {
+			continue
+		}
+		if found != nil {
+			return nil
+		}
+		found = &after[i]
+	}
+	return found
+}
+
+// pluginReconcile 确保插件已启用 返回处理失败的插件
+// 无法读取插件列表时直接尝试启用全部插件
+func (s *ZBlogSession) pluginReconcile(ctx context.Context, ids []string) map[string]error {
+	installed := make(map[string]base.Plugin)
+	list, err := s.PluginList(ctx)
+	if err == nil {
+		for _, p := range list {
+			installed[p.ID] = p
+		}
+	}
+	failed := make(map[string]error)
+	done := make(map[string]bool)
+	for _, id := range ids {
+		if done[id] {
+			continue
+		}
+		done[id] = true
+		p, ok := installed[id]
+		if err == nil && !ok {
+			failed[id] = base.PluginUndefinedErr
+			continue
+		}
+		if p.Enabled {
+			continue
+		}
+		p.ID = id
+		if e := s.PluginEnable(ctx, &p); e != nil {
+			failed[id] = e
+		}
+	}
+	return failed
+}
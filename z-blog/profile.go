@@ -0,0 +1,272 @@
+package z_blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var ProfileMismatchErr = errors.New("后台页面与已知的Z-Blog版本均不匹配")
+
+// table 后台列表的行选择器及各字段所在的列
+type table struct {
+	list  string         // 列表容器 为空时以 rows 判断页面是否匹配
+	rows  string         // 行选择器
+	cells int            // 数据行的列数 0 不检查
+	col   map[string]int // 字段对应的列序号
+}
+
+// profile 某一版本后台页面的选择器
+type profile struct {
+	version string           // 适用的最低版本
+	marker  string           // 分类管理页中只有该版本才有的元素 版本未知时据此识别
+	pagebar string           // 分页链接
+	tables  map[string]table // 以后台 act 为键
+}
+
+// tables15 1.5 的后台列表 1.7 只有分类管理页不同
+var tables15 = map[string]table{
+	"ArticleMng":  {rows: ".table_striped tr", col: map[string]int{"id": 0, "cate": 1, "title": 3, "date": 4, "status": 6}},
+	"PageMng":     {rows: ".table_striped tr", col: map[string]int{"id": 0, "title": 2, "date": 3, "status": 5}},
+	"CategoryMng": {rows: ".tableBorder-thcenter tr", col: map[string]int{"id": 0, "order": 1, "name": 2, "alias": 3}},
+	"TagMng":      {rows: ".table_striped tr", col: map[string]int{"id": 0, "name": 1, "alias": 2}},
+	"CommentMng":  {rows: ".table_striped tr", col: map[string]int{"id": 0, "parent": 1, "name": 2, "content": 3, "log": 4, "date": 5}},
+	"MemberMng":   {rows: ".table_striped tr", col: map[string]int{"id": 0, "level": 1, "name": 2, "alias": 3}},
+	"UploadMng":   {rows: ".table_striped tr", col: map[string]int{"id": 0, "url": 2}},
+	"PluginMng":   {rows: ".table_striped tr", col: map[string]int{"name": 1}},
+	"LinksManage": {list: "#LinksManageList", rows: "#LinksManageList tr", cells: 6},
+}
+
+// profiles 按版本升序
+// 选择器及列号按 1.5、1.7 后台模板整理，尚未与真实站点的后台页面核对，见 testdata/README.md
+// 真实站点的页面中找不到列表时 eachRow 返回 ProfileMismatchErr
+var profiles = []*profile{
+	{
+		version: "1.5",
+		marker:  ".tableBorder-thcenter",
+		pagebar: ".pagebar a",
+		tables:  tables15,
+	},
+	{
+		// 1.7 起分类管理页与其它列表一样使用 table_striped，不再有 tableBorder-thcenter
+		version: "1.7",
+		marker:  "table.table_striped:not(.tableBorder-thcenter)",
+		pagebar: ".pagebar a",
+		tables: override(tables15, map[string]table{
+			"CategoryMng": {rows: ".table_striped tr", col: map[string]int{"id": 0, "order": 1, "name": 2, "alias": 3}},
+		}),
+	},
+}
+
+// override 复制 tables 并替换其中的部分列表
+func override(tables, changed map[string]table) map[string]table {
+	m := make(map[string]table, len(tables))
+	for k, v := range tables {
+		m[k] = v
+	}
+	for k, v := range changed {
+		m[k] = v
+	}
+	return m
+}
+
+// profileByMarker 以分类管理页识别 profile 恰好一个 profile 的 marker 匹配时才返回
+func profileByMarker(doc *goquery.Document) *profile {
+	var p *profile
+	for _, v := range profiles {
+		if doc.Find(v.marker).Length() == 0 {
+			continue
+		}
+		if p != nil {
+			return nil
+		}
+		p = v
+	}
+	return p
+}
+
+// each 遍历 doc 中 act 对应列表的数据行 页面中没有该列表时返回 false
+func (p *profile) each(act string, doc *goquery.Document, fn func(r row) bool) bool {
+	t := p.tables[act]
+	check := t.list
+	if check == "" {
+		check = t.rows
+	}
+	if doc.Find(check).Length() == 0 {
+		return false
+	}
+	doc.Find(t.rows).EachWithBreak(func(_ int, tr *goquery.Selection) bool {
+		td := tr.Find("td")
+		if td.Length() == 0 || (t.cells > 0 && td.Length() != t.cells) {
+			return true
+		}
+		return fn(row{td: td, col: t.col})
+	})
+	return true
+}
+
+// siteProfile 站点识别出的版本及 profile
+type siteProfile struct {
+	version string
+	prof    *profile
+}
+
+// profileCache 以站点地址缓存识别结果 同一站点的多次登录及恢复的会话不再重复识别
+var profileCache sync.Map
+
+// row 列表中的一行
+type row struct {
+	td  *goquery.Selection
+	col map[string]int
+}
+
+func (r row) cell(name string) *goquery.Selection {
+	i, ok := r.col[name]
+	if !ok {
+		return r.td.Slice(0, 0)
+	}
+	return r.td.Eq(i)
+}
+
+// text 字段的文本
+func (r row) text(name string) string {
+	return strings.TrimSpace(r.cell(name).Text())
+}
+
+// href 字段中第一个链接的地址
+func (r row) href(name string) string {
+	return r.cell(name).Find("a").AttrOr("href", "")
+}
+
+// versionLess 按数字逐段比较版本号
+func versionLess(a, b string) bool {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			return x < y
+		}
+	}
+	return false
+}
+
+// profileFor 适用于该版本的 profile 版本低于所有 profile 时返回nil
+func profileFor(version string) *profile {
+	var p *profile
+	for _, v := range profiles {
+		if !versionLess(version, v.version) {
+			p = v
+		}
+	}
+	return p
+}
+
+// Version 后台的 Z-Blog 版本 未识别时为空
+func (s *ZBlogSession) Version() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version
+}
+
+// profile 当前站点使用的 profile 依次使用会话、站点缓存 都没有时识别版本
+func (s *ZBlogSession) profile(ctx context.Context) (*profile, error) {
+	s.mu.Lock()
+	p := s.prof
+	s.mu.Unlock()
+	if p != nil {
+		return p, nil
+	}
+	if v, ok := profileCache.Load(s.zb.HomeURL); ok {
+		sp := v.(*siteProfile)
+		s.mu.Lock()
+		s.version, s.prof = sp.version, sp.prof
+		s.mu.Unlock()
+		return sp.prof, nil
+	}
+	version, err := s.detectVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if version != "" {
+		p = profileFor(version)
+	}
+	if p == nil {
+		// 版本未知时 以分类管理页的结构识别
+		doc, err := s.AdminDoc(ctx, "CategoryMng", 1, nil)
+		if err != nil {
+			return nil, err
+		}
+		p = profileByMarker(doc)
+	}
+	if p == nil {
+		return nil, &base.Error{Op: "Profile", Site: s.zb.HomeURL, Sentinel: ProfileMismatchErr, Err: fmt.Errorf("version %q", version)}
+	}
+	profileCache.Store(s.zb.HomeURL, &siteProfile{version: version, prof: p})
+	s.mu.Lock()
+	s.version, s.prof = version, p
+	s.mu.Unlock()
+	return p, nil
+}
+
+// forgetProfile 页面与 profile 不匹配时清除识别结果 站点升级后下次请求重新识别
+func (s *ZBlogSession) forgetProfile() {
+	profileCache.Delete(s.zb.HomeURL)
+	s.mu.Lock()
+	s.prof = nil
+	s.mu.Unlock()
+}
+
+// detectVersion 从后台首页的 Product 头或页面中的 Z-BlogPHP x.y.z 读取版本
+func (s *ZBlogSession) detectVersion(ctx context.Context) (string, error) {
+	req, err := s.NewRequest(ctx, http.MethodGet, "admin/index.php", nil)
+	if err != nil {
+		return "", err
+	}
+	var resp *http.Response
+	if resp, err = s.do("Version", req); err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != 200 {
+		return "", s.fail("Version", StatusCodeNot200Err, resp)
+	}
+	if m := versionRegexp.FindStringSubmatch(resp.Header.Get("Product")); m != nil {
+		return m[1], nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", s.wrap("Version", req, err)
+	}
+	if m := versionRegexp.FindSubmatch(body); m != nil {
+		return string(m[1]), nil
+	}
+	return "", nil
+}
+
+// eachRow 按 profile 遍历 doc 中 act 对应列表的数据行 找不到列表时返回 ProfileMismatchErr
+func (s *ZBlogSession) eachRow(ctx context.Context, act string, doc *goquery.Document, fn func(r row) bool) error {
+	p, err := s.profile(ctx)
+	if err != nil {
+		return err
+	}
+	if !p.each(act, doc, fn) {
+		s.forgetProfile()
+		return &base.Error{Op: act, Site: s.zb.HomeURL, Sentinel: ProfileMismatchErr, Err: fmt.Errorf("version %q selector %q", s.Version(), p.tables[act].rows)}
+	}
+	return nil
+}
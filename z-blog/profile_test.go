@@ -0,0 +1,157 @@
+package z_blog
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestProfileFor(t *testing.T) {
+	for v, want := range map[string]string{"1.5.2": "1.5", "1.6": "1.5", "1.7.3": "1.7", "1.10": "1.7"} {
+		if p := profileFor(v); p == nil || p.version != want {
+			t.Fatal(v, p)
+		}
+	}
+	if p := profileFor("1.4"); p != nil {
+		t.Fatal(p.version)
+	}
+}
+
+func loadPage(t *testing.T, name string) *goquery.Document {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	doc, err := goquery.NewDocumentFromReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestProfilePages(t *testing.T) {
+	for _, version := range []string{"1.5", "1.7"} {
+		cate := loadPage(t, version+"/CategoryMng.html")
+		m := versionRegexp.FindStringSubmatch(cate.Find(`meta[name="generator"]`).AttrOr("content", ""))
+		if m == nil || profileFor(m[1]).version != version {
+			t.Fatal(version, m)
+		}
+		p := profileByMarker(cate)
+		if p == nil || p.version != version {
+			t.Fatal(version, p)
+		}
+		var names []string
+		p.each("CategoryMng", cate, func(r row) bool {
+			names = append(names, r.text("id")+":"+r.text("name")+":"+r.text("alias"))
+			return true
+		})
+		if strings.Join(names, ",") != "1:默认分类:default,2:新闻:news" {
+			t.Fatal(version, names)
+		}
+		var titles []string
+		p.each("ArticleMng", loadPage(t, version+"/ArticleMng.html"), func(r row) bool {
+			titles = append(titles, r.text("id")+":"+r.text("title")+":"+r.text("status"))
+			return true
+		})
+		if strings.Join(titles, ",") != "3:第三篇文章:公开,2:第二篇文章:草稿" {
+			t.Fatal(version, titles)
+		}
+	}
+	// 两个版本的分类管理页互不兼容
+	if profileFor("1.7").each("CategoryMng", loadPage(t, "1.5/CategoryMng.html"), func(row) bool { return true }) {
+		t.Fatal("1.7 的选择器不应匹配 1.5 的分类管理页")
+	}
+	if profileFor("1.5").each("CategoryMng", loadPage(t, "1.7/CategoryMng.html"), func(row) bool { return true }) {
+		t.Fatal("1.5 的选择器不应匹配 1.7 的分类管理页")
+	}
+}
+
+// rowsOf 以 1.7 的 profile 读取样本页中 act 列表的字段
+func rowsOf(t *testing.T, act string, fields ...string) []string {
+	t.Helper()
+	var data []string
+	ok := profileFor("1.7").each(act, loadPage(t, "1.7/"+act+".html"), func(r row) bool {
+		v := make([]string, 0, len(fields))
+		for _, f := range fields {
+			v = append(v, r.text(f))
+		}
+		data = append(data, strings.Join(v, ":"))
+		return true
+	})
+	if !ok {
+		t.Fatal(act, "列表不匹配")
+	}
+	return data
+}
+
+func TestProfileAdminPages(t *testing.T) {
+	if got := strings.Join(rowsOf(t, "CommentMng", "id", "parent", "name", "content", "log", "date"), ","); got != "12:11:admin:谢谢支持:3:2020-05-03 08:30:00,11:0:访客:写得不错:3:2020-05-03 08:00:00" {
+		t.Fatal(got)
+	}
+	if got := strings.Join(rowsOf(t, "MemberMng", "id", "level", "name", "alias"), ","); got != "1:管理员:admin:admin,2:作者:writer:小编" {
+		t.Fatal(got)
+	}
+	var urls []string
+	profileFor("1.7").each("UploadMng", loadPage(t, "1.7/UploadMng.html"), func(r row) bool {
+		urls = append(urls, r.text("id")+":"+r.href("url"))
+		return true
+	})
+	if strings.Join(urls, ",") != "7:http://www.example.com/zb_users/upload/2020/05/banner.png" {
+		t.Fatal(urls)
+	}
+	var plugins []string
+	profileFor("1.7").each("PluginMng", loadPage(t, "1.7/PluginMng.html"), func(r row) bool {
+		if p, ok := pluginRow(r); ok {
+			plugins = append(plugins, p.ID+":"+p.Version+":"+strconv.FormatBool(p.Enabled))
+		}
+		return true
+	})
+	if strings.Join(plugins, ",") != "AppCentre:1.17:true,STACentre:1.3:false" {
+		t.Fatal(plugins)
+	}
+}
+
+func TestEditForm(t *testing.T) {
+	a := &base.Article{}
+	editForm(loadPage(t, "1.7/ArticleEdt.html"), a)
+	if a.ID != "3" || a.Title != "第三篇文章 & 更新" || a.Content != "<p>正文</p>" || a.Intro != "<p>摘要</p>" {
+		t.Fatal(a)
+	}
+	if a.Cate.ID != "2" || a.Cate.Name != "新闻" || a.Status != base.StatusDraft || a.Template != "single" || a.AuthorID != "1" {
+		t.Fatal(a.Cate, a.Status, a.Template, a.AuthorID)
+	}
+	if strings.Join(a.Tag, ",") != "新闻,公告" || a.PostTime.IsZero() {
+		t.Fatal(a.Tag, a.PostTime)
+	}
+	// 置顶与禁止评论是值为 0 或 1 的文本框
+	if a.IsTop != base.TopNone || a.IsLock != base.LockClosed {
+		t.Fatal(a.IsTop, a.IsLock)
+	}
+}
+
+func TestCSRFErrorPage(t *testing.T) {
+	page, err := ioutil.ReadFile(filepath.Join("testdata", "1.7", "error.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !csrfErrorPage(page) {
+		t.Fatal("未识别后台的错误页")
+	}
+	// 正文中出现同样的文字时不是错误页
+	edit, err := ioutil.ReadFile(filepath.Join("testdata", "1.7", "ArticleEdt.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if csrfErrorPage([]byte(strings.Replace(string(edit), "正文", "非法访问", 1))) {
+		t.Fatal("编辑页被当作错误页")
+	}
+}
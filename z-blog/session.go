@@ -0,0 +1,243 @@
+package z_blog
+
+import (
+	"bytes"
+	"context"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sessionLifetime cookie 未声明过期时间时会话的有效期
+const sessionLifetime = 24 * time.Hour
+
+// reloginInterval 该时间内重复的失效请求共用同一次重新登录
+const reloginInterval = 5 * time.Second
+
+// csrfRejected 后台CSRF校验失败时错误页的提示
+const csrfRejected = "非法访问"
+
+// errorPageLimit 错误页的最大长度 超过该长度的响应不是错误页 不再读取
+const errorPageLimit = 64 << 10
+
+// Session 导出当前会话
+func (s *ZBlogSession) Session() *base.Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expire := time.Now().Add(sessionLifetime)
+	for _, c := range s.cookieValues {
+		if !c.Expires.IsZero() && c.Expires.Before(expire) {
+			expire = c.Expires
+		}
+	}
+	return &base.Session{
+		Program:    "z-blog",
+		Site:       s.zb.HomeURL,
+		Username:   s.username,
+		Cookies:    s.cookieValues,
+		CSRF:       s.csrfS,
+		CSRFExpire: s.csrfT,
+		Expire:     expire,
+	}
+}
+
+// Probe 请求后台首页检查会话是否有效 未登录时后台会跳转到登录页
+func (s *ZBlogSession) Probe(ctx context.Context) error {
+	req, err := s.NewRequest(ctx, http.MethodGet, "admin/index.php", nil)
+	if err != nil {
+		return err
+	}
+	var resp *http.Response
+	if resp, err = s.send("Probe", req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != 200 {
+		return s.fail("Probe", base.SessionExpiredErr, resp)
+	}
+	return nil
+}
+
+// Resume 以保存的会话恢复登录
+func Resume(_ context.Context, ss *base.Session, username, password string, z base.ProgramBaseInfo) (base.ProgramAPIContext, error) {
+	if len(ss.Cookies) == 0 {
+		return nil, base.SessionExpiredErr
+	}
+	s := &ZBlogSession{zb: z, username: username, password: password, csrfS: ss.CSRF, csrfT: ss.CSRFExpire}
+	s.setCookies(ss.Cookies)
+	return s, nil
+}
+
+// OnRelogin 设置重新登录成功后的回调 用于保存新的会话
+func (s *ZBlogSession) OnRelogin(fn func()) {
+	s.mu.Lock()
+	s.onRelogin = fn
+	s.mu.Unlock()
+}
+
+// Relogins 会话失效后重新登录的次数
+func (s *ZBlogSession) Relogins() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.relogins
+}
+
+// send 发送请求 网络错误包装为 *base.Error
+func (s *ZBlogSession) send(op string, req *http.Request) (*http.Response, error) {
+	resp, err := Client.Do(req)
+	if err != nil {
+		return nil, s.wrap(op, req, err)
+	}
+	return resp, nil
+}
+
+// do 发送请求 会话失效(跳转登录页或CSRF校验失败)时重新登录并刷新CSRF后重试一次
+func (s *ZBlogSession) do(op string, req *http.Request) (*http.Response, error) {
+	resp, err := s.send(op, req)
+	if err != nil || !s.expired(req, resp) {
+		return resp, err
+	}
+	_ = resp.Body.Close()
+	if err = s.relogin(req.Context()); err != nil {
+		return nil, s.cause(op, base.SessionExpiredErr, err)
+	}
+	if req, err = s.renew(req); err != nil {
+		return nil, s.wrap(op, req, err)
+	}
+	return s.send(op, req)
+}
+
+// expired 判断响应是否表示会话失效 读取过的响应内容会放回 resp.Body
+// 只有携带了csrfToken的请求才检查CSRF校验失败 且提示须出现在后台错误页的提示框中
+// 文章、评论等正文中出现同样的文字不会被误判
+func (s *ZBlogSession) expired(req *http.Request, resp *http.Response) bool {
+	if resp.StatusCode == 302 {
+		loc := resp.Header.Get("Location")
+		return strings.Contains(loc, "login.php") || strings.Contains(loc, "act=login")
+	}
+	if resp.StatusCode != 200 && resp.StatusCode != 500 || !carriesCSRF(req) {
+		return false
+	}
+	head, err := ioutil.ReadAll(io.LimitReader(resp.Body, errorPageLimit+1))
+	resp.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(head), resp.Body), Closer: resp.Body}
+	if err != nil || len(head) > errorPageLimit {
+		return false
+	}
+	return csrfErrorPage(head)
+}
+
+// csrfErrorPage 是否为CSRF校验失败的错误页
+// 后台错误页以登录框的样式显示 提示内容位于 #frmLogin 下的 .content.lessinfo
+func csrfErrorPage(body []byte) bool {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	tip := doc.Find("#frmLogin .content.lessinfo")
+	return tip.Length() > 0 && strings.Contains(tip.Text(), csrfRejected)
+}
+
+// carriesCSRF 请求的地址或表单是否携带csrfToken
+func carriesCSRF(req *http.Request) bool {
+	if req.URL.Query().Get("csrfToken") != "" {
+		return true
+	}
+	if req.GetBody == nil || req.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		return false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return false
+	}
+	b, err := ioutil.ReadAll(body)
+	_ = body.Close()
+	if err != nil {
+		return false
+	}
+	form, err := url.ParseQuery(string(b))
+	return err == nil && form.Get("csrfToken") != ""
+}
+
+// readCloser 放回已读取部分的响应内容 关闭时关闭原响应
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// relogin 以保存的用户名密码重新登录 短时间内并发的失效请求只登录一次
+func (s *ZBlogSession) relogin(ctx context.Context) error {
+	// 登录期间持有 loginMu 等待中的请求在登录完成后直接使用新会话
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	s.mu.Lock()
+	recent := time.Since(s.reloginAt) < reloginInterval
+	s.mu.Unlock()
+	if recent {
+		return nil
+	}
+	if s.username == "" {
+		return base.LoginFailErr
+	}
+	if err := s.login(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.reloginAt = time.Now()
+	s.relogins++
+	n, fn := s.relogins, s.onRelogin
+	s.mu.Unlock()
+	log.Printf("【%s】z-blog 会话失效 已重新登录 累计%d次", s.zb.HomeURL, n)
+	if fn != nil {
+		fn()
+	}
+	return nil
+}
+
+// renew 复制请求并替换其中的cookie和csrfToken
+func (s *ZBlogSession) renew(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	r.Header.Set("Cookie", s.cookieHeader())
+	var csrf string
+	if q := r.URL.Query(); q.Get("csrfToken") != "" {
+		csrf = s.GetCSRF(r.Context())
+		q.Set("csrfToken", csrf)
+		r.URL.RawQuery = q.Encode()
+	}
+	if req.GetBody == nil {
+		return r, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		r.Body = body
+		return r, nil
+	}
+	b, err := ioutil.ReadAll(body)
+	_ = body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if form, err := url.ParseQuery(string(b)); err == nil && form.Get("csrfToken") != "" {
+		if csrf == "" {
+			csrf = s.GetCSRF(r.Context())
+		}
+		form.Set("csrfToken", csrf)
+		b = []byte(form.Encode())
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(b))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(b)), nil
+	}
+	r.ContentLength = int64(len(b))
+	return r, nil
+}
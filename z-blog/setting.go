@@ -0,0 +1,141 @@
+package z_blog
+
+import (
+	"context"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// settingField base.SiteSetting 中字符串字段对应的设置项
+var settingField = []struct {
+	key   string
+	field func(*base.SiteSetting) *string
+}{
+	{"ZC_BLOG_NAME", func(ss *base.SiteSetting) *string { return &ss.SiteName }},
+	{"ZC_BLOG_SUBNAME", func(ss *base.SiteSetting) *string { return &ss.SubSiteName }},
+	{"ZC_BLOG_KEYWORDS", func(ss *base.SiteSetting) *string { return &ss.SiteKeywords }},
+	{"ZC_BLOG_DESCRIPTION", func(ss *base.SiteSetting) *string { return &ss.SiteDescription }},
+	{"ZC_BLOG_COPYRIGHT", func(ss *base.SiteSetting) *string { return &ss.Copyright }},
+	{"ZC_BLOG_ICP", func(ss *base.SiteSetting) *string { return &ss.ICP }},
+	{"ZC_TIME_ZONE_NAME", func(ss *base.SiteSetting) *string { return &ss.TimeZone }},
+	{"ZC_BLOG_LANGUAGEPACK", func(ss *base.SiteSetting) *string { return &ss.Language }},
+}
+
+const (
+	settingPageSize     = "ZC_DISPLAY_COUNT"
+	settingCommentOff   = "ZC_COMMENT_TURNOFF"
+	settingCommentAudit = "ZC_COMMENT_AUDIT"
+)
+
+// settingForm 读取设置页的完整表单
+func (s *ZBlogSession) settingForm(ctx context.Context) (url.Values, error) {
+	req, err := s.NewRequest(ctx, http.MethodGet, s.ParamCSRF(ctx, "admin/setting.php", "SettingMng"), nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp *http.Response
+	if resp, err = s.do("SettingMng", req); err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != 200 {
+		return nil, s.fail("SettingMng", StatusCodeNot200Err, resp)
+	}
+	var doc *goquery.Document
+	if doc, err = goquery.NewDocumentFromReader(resp.Body); err != nil {
+		return nil, err
+	}
+	form := url.Values{}
+	doc.Find("input[name], select[name], textarea[name]").Each(func(_ int, field *goquery.Selection) {
+		name := field.AttrOr("name", "")
+		if len(name) < 3 || name[:3] != "ZC_" {
+			return
+		}
+		form.Set(name, formValue(doc, name))
+	})
+	if len(form) == 0 {
+		return nil, s.fail("SettingMng", base.SiteSettingErr, resp)
+	}
+	return form, nil
+}
+
+// SiteSettingGet 读取站点设置 未对应到字段的设置项存入 SiteSetting.Extra
+func (s *ZBlogSession) SiteSettingGet(ctx context.Context, ss *base.SiteSetting) error {
+	form, err := s.settingForm(ctx)
+	if err != nil {
+		return err
+	}
+	for _, f := range settingField {
+		*f.field(ss) = form.Get(f.key)
+		form.Del(f.key)
+	}
+	ss.PageSize, _ = strconv.Atoi(form.Get(settingPageSize))
+	off := settingBool(form.Get(settingCommentOff))
+	audit := settingBool(form.Get(settingCommentAudit))
+	ss.CommentOff, ss.CommentAudit = &off, &audit
+	form.Del(settingPageSize)
+	form.Del(settingCommentOff)
+	form.Del(settingCommentAudit)
+	ss.Extra = make(map[string]string, len(form))
+	for k := range form {
+		ss.Extra[k] = form.Get(k)
+	}
+	return nil
+}
+
+// SiteSetting 站点设置 先读取当前设置，再提交完整的表单，避免未指定的设置项被重置
+func (s *ZBlogSession) SiteSetting(ctx context.Context, ss *base.SiteSetting) error {
+	param, err := s.settingForm(ctx)
+	if err != nil {
+		return err
+	}
+	for _, f := range settingField {
+		if v := *f.field(ss); v != "" {
+			param.Set(f.key, v)
+		}
+	}
+	if ss.PageSize > 0 {
+		param.Set(settingPageSize, strconv.Itoa(ss.PageSize))
+	}
+	if ss.CommentOff != nil {
+		param.Set(settingCommentOff, settingValue(*ss.CommentOff))
+	}
+	if ss.CommentAudit != nil {
+		param.Set(settingCommentAudit, settingValue(*ss.CommentAudit))
+	}
+	for k, v := range ss.Extra {
+		param.Set(k, v)
+	}
+	var req *http.Request
+	req, err = s.NewRequest(ctx, http.MethodPost, s.ParamCSRF(ctx, "cmd.php", "SettingSav"), param)
+	if err != nil {
+		return err
+	}
+	var resp *http.Response
+	if resp, err = s.do("SiteSetting", req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode == 302 {
+		return nil
+	}
+	return s.fail("SiteSetting", base.SiteSettingErr, resp)
+}
+
+func settingBool(v string) bool {
+	return v == "1" || v == "True" || v == "true"
+}
+
+func settingValue(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
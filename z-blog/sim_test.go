@@ -0,0 +1,592 @@
+package z_blog
+
+import (
+	"context"
+	"errors"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"github.com/cgghui/bt_site_cluster_program_api/base/conformance"
+	"github.com/cgghui/bt_site_cluster_program_api/z-blog/zblogtest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func simLogin(t *testing.T, srv *zblogtest.Server) *ZBlogSession {
+	t.Helper()
+	api, err := LoginContext(context.Background(), srv.Username, srv.Password, srv.Info())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return api.(*ZBlogSession)
+}
+
+func TestSimConformance(t *testing.T) {
+	srv := zblogtest.NewServer()
+	defer srv.Close()
+	conformance.Run(t, conformance.Config{
+		Login:    LoginContext,
+		Info:     srv.Info(),
+		Username: srv.Username,
+		Password: srv.Password,
+		Expire:   srv.Expire,
+		NavbarList: func(ctx context.Context, api base.ProgramAPIContext) ([]base.Navbar, error) {
+			list, err := base.Unwrap(api).(*ZBlogSession).NavbarList(ctx)
+			if err != nil {
+				return nil, err
+			}
+			data := make([]base.Navbar, 0, len(list))
+			for _, n := range list {
+				data = append(data, *n)
+			}
+			return data, nil
+		},
+	})
+}
+
+func TestSimReloginConcurrent(t *testing.T) {
+	srv := zblogtest.NewServer()
+	defer srv.Close()
+	s := simLogin(t, srv)
+	srv.Expire()
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- s.TagNew(context.Background(), &base.Tag{Name: "concurrent-" + strconv.Itoa(i)})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.Relogins() != 1 {
+		t.Fatal("并发的失效请求应只重新登录一次", s.Relogins())
+	}
+}
+
+func TestSimRotateCSRF(t *testing.T) {
+	srv := zblogtest.NewServer()
+	defer srv.Close()
+	s := simLogin(t, srv)
+	ctx := context.Background()
+	if err := s.TagNew(ctx, &base.Tag{Name: "before"}); err != nil {
+		t.Fatal(err)
+	}
+	srv.RotateCSRF()
+	if err := s.TagNew(ctx, &base.Tag{Name: "after"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.TagGet(ctx, &base.Tag{Name: "after"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSimProfile15(t *testing.T) {
+	srv := zblogtest.NewServer()
+	defer srv.Close()
+	srv.Version = "1.5.2"
+	s := simLogin(t, srv)
+	ctx := context.Background()
+	if err := s.CategoryNew(ctx, &base.Category{Name: "old"}); err != nil {
+		t.Fatal(err)
+	}
+	c := &base.Category{Name: "old"}
+	if err := s.CategoryGet(ctx, c); err != nil || c.ID == "" {
+		t.Fatal(c.ID, err)
+	}
+	if s.Version() != "1.5.2" {
+		t.Fatal(s.Version())
+	}
+}
+
+func TestSimProfileCache(t *testing.T) {
+	srv := zblogtest.NewServer()
+	s := simLogin(t, srv)
+	ss := s.Session()
+	srv.Close()
+	// 站点已识别过 恢复的会话不再请求后台识别版本
+	api, err := Resume(context.Background(), ss, srv.Username, srv.Password, srv.Info())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := api.(*ZBlogSession)
+	if p, err := r.profile(context.Background()); err != nil || p.version != "1.7" {
+		t.Fatal(p, err)
+	}
+	if r.Version() != zblogtest.DefaultVersion {
+		t.Fatal(r.Version())
+	}
+}
+
+func TestSimProfileChanged(t *testing.T) {
+	srv := zblogtest.NewServer()
+	defer srv.Close()
+	s := simLogin(t, srv)
+	ctx := context.Background()
+	srv.Version = "1.5.2"
+	// 站点版本变化后 第一次请求不匹配并清除识别结果 之后按新版本解析
+	if _, _, err := s.CategoryList(ctx, nil); !errors.Is(err, ProfileMismatchErr) {
+		t.Fatal(err)
+	}
+	if _, _, err := s.CategoryList(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if s.Version() != "1.5.2" {
+		t.Fatal(s.Version())
+	}
+}
+
+func TestSimPagination(t *testing.T) {
+	srv := zblogtest.NewServer()
+	defer srv.Close()
+	srv.PageSize = 2
+	s := simLogin(t, srv)
+	ctx := context.Background()
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		if err := s.TagNew(ctx, &base.Tag{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, total, err := s.TagList(ctx, nil)
+	if err != nil || total != 5 {
+		t.Fatal(total, err)
+	}
+	for _, name := range []string{"f", "g", "h", "i"} {
+		if err = s.TagNew(ctx, &base.Tag{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 9 个标签 后台每页 2 个，第 2 页每页 3 个只需读取第 1、5 页计算总数及第 2、3 页
+	n := len(srv.Pages())
+	list, total, err := s.TagList(ctx, &base.ListOption{Page: 2, PageSize: 3})
+	if err != nil || total != 9 {
+		t.Fatal(total, err)
+	}
+	var names []string
+	for _, v := range list {
+		names = append(names, v.Name)
+	}
+	if strings.Join(names, ",") != "f,e,d" {
+		t.Fatal(names)
+	}
+	if pages := strings.Join(srv.Pages()[n:], ","); pages != "TagMng:1,TagMng:5,TagMng:2,TagMng:3" {
+		t.Fatal(pages)
+	}
+}
+
+func TestSimArticleGetPages(t *testing.T) {
+	srv := zblogtest.NewServer()
+	defer srv.Close()
+	srv.PageSize = 2
+	s := simLogin(t, srv)
+	ctx := context.Background()
+	for _, title := range []string{"x 1", "x 2", "x 3", "x 4", "x"} {
+		a := &base.Article{
+			Union:    base.Union{ID: "0", Type: base.TypeArticle},
+			Title:    title,
+			Content:  "<p>x</p>",
+			Status:   base.StatusPublic,
+			AuthorID: "1",
+			PostTime: time.Now(),
+			Cate:     &base.Category{},
+		}
+		if err := s.ArticleNew(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+		if a.ID == "" || a.ID == "0" {
+			t.Fatal(title)
+		}
+	}
+	// 找到后不再读取之后的搜索结果分页
+	n := len(srv.Pages())
+	a := &base.Article{Title: "x"}
+	if err := s.ArticleGet(ctx, a); err != nil || a.Title != "x" {
+		t.Fatal(a, err)
+	}
+	if pages := strings.Join(srv.Pages()[n:], ","); pages != "ArticleMng:1" {
+		t.Fatal(pages)
+	}
+}
+
+func TestSimSiteSetting(t *testing.T) {
+	srv := zblogtest.NewServer()
+	defer srv.Close()
+	s := simLogin(t, srv)
+	if err := s.SiteSetting(context.Background(), &base.SiteSetting{SiteName: "sim"}); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Setting("ZC_BLOG_NAME") != "sim" || srv.Setting("ZC_DISPLAY_COUNT") != "10" {
+		t.Fatal(srv.Setting("ZC_BLOG_NAME"), srv.Setting("ZC_DISPLAY_COUNT"))
+	}
+}
+
+func TestSimInit(t *testing.T) {
+	srv := zblogtest.NewServer()
+	defer srv.Close()
+	info := srv.Info()
+	info.Plugins = []string{"LinksManage"}
+	api, err := LoginContext(context.Background(), srv.Username, srv.Password, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = api.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := srv.Plugin("STACentre"); !p.Enabled {
+		t.Fatal("STACentre 未启用")
+	}
+	pl, err := api.PermalinkGet(context.Background())
+	if err != nil || pl.Article != base.DefaultPermalink.Article {
+		t.Fatal(pl, err)
+	}
+}
+
+func TestSimContentNotRejected(t *testing.T) {
+	srv := zblogtest.NewServer()
+	defer srv.Close()
+	s := simLogin(t, srv)
+	ctx := context.Background()
+	a := &base.Article{
+		Union:    base.Union{ID: "0", Type: base.TypeArticle},
+		Title:    "非法访问",
+		Content:  "<p>非法访问 会被拦截</p>",
+		Status:   base.StatusPublic,
+		AuthorID: "1",
+		PostTime: time.Now(),
+		Cate:     &base.Category{},
+	}
+	if err := s.ArticleNew(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	got := &base.Article{Union: base.Union{ID: a.ID}}
+	if err := s.ArticleGet(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != a.Title {
+		t.Fatal(got.Title)
+	}
+	if s.Relogins() != 0 {
+		t.Fatal("正文中的提示被误判为CSRF校验失败", s.Relogins())
+	}
+}
+
+func TestSimStoreAfterRelogin(t *testing.T) {
+	srv := zblogtest.NewServer()
+	defer srv.Close()
+	store := &base.FileSessionStore{Dir: t.TempDir()}
+	ctx := context.Background()
+	api, err := base.LoginWithStore(ctx, "z-blog", store, srv.Username, srv.Password, srv.Info())
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := store.Load(srv.Info().HomeURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Expire()
+	if err = api.TagNew(ctx, &base.Tag{Name: "store"}); err != nil {
+		t.Fatal(err)
+	}
+	after, err := store.Load(srv.Info().HomeURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cookie(before, "token") == cookie(after, "token") {
+		t.Fatal("重新登录后的会话未保存")
+	}
+	// 保存的会话可直接恢复
+	again, err := base.LoginWithStore(ctx, "z-blog", store, srv.Username, srv.Password, srv.Info())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = again.TagGet(ctx, &base.Tag{Name: "store"}); err != nil {
+		t.Fatal(err)
+	}
+	if again.(*ZBlogSession).Relogins() != 0 {
+		t.Fatal(again.(*ZBlogSession).Relogins())
+	}
+}
+
+func cookie(s *base.Session, name string) string {
+	for _, c := range s.Cookies {
+		if c.Name == name {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+func TestSimMedia(t *testing.T) {
+	srv := zblogtest.NewServer()
+	defer srv.Close()
+	s := simLogin(t, srv)
+	ctx := context.Background()
+	first := &base.Media{Name: "a.png", MimeType: "image/png", Data: []byte("png")}
+	if err := s.MediaUpload(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	second := &base.Media{Name: "a.png", MimeType: "image/png", Data: []byte("png")}
+	if err := s.MediaUpload(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	if second.ID == first.ID || !strings.HasSuffix(second.URL, "/a.png") {
+		t.Fatal(first, second)
+	}
+	// 站点重命名了附件时 只新增了一个附件即为本次上传
+	srv.UploadRename = true
+	renamed := &base.Media{Name: "a.png", Data: []byte("png")}
+	if err := s.MediaUpload(ctx, renamed); err != nil || renamed.ID == second.ID || strings.HasSuffix(renamed.URL, "/a.png") {
+		t.Fatal(renamed, err)
+	}
+	// 同时有其他附件上传时无法确定 删除新增的附件而不是猜测
+	srv.UploadOthers = 1
+	count := func() int {
+		n := 0
+		if err := s.mediaEach(ctx, func(*base.Media) { n++ }); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	before := count()
+	if err := s.MediaUpload(ctx, &base.Media{Name: "a.png", Data: []byte("png")}); !errors.Is(err, base.MediaUploadErr) {
+		t.Fatal(err)
+	}
+	if after := count(); after != before {
+		t.Fatal(before, after)
+	}
+	if err := s.MediaDel(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.MediaDel(ctx, second); !errors.Is(err, base.MediaDelErr) {
+		t.Fatal(err)
+	}
+}
+
+func TestSimPluginInstall(t *testing.T) {
+	srv := zblogtest.NewServer()
+	defer srv.Close()
+	s := simLogin(t, srv)
+	ctx := context.Background()
+	zba := []byte(`<?xml version="1.0" encoding="utf-8"?><app version="php" type="plugin"><id>Totoro</id><name>Totoro</name><version>2.1</version></app>`)
+	p := &base.Plugin{}
+	if err := s.PluginInstall(ctx, p, zba); err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != "Totoro" || p.Version != "2.1" {
+		t.Fatal(p)
+	}
+	// 应用中心以 200 返回错误页时 不能视为安装成功
+	if err := s.PluginInstall(ctx, &base.Plugin{}, []byte("broken")); !errors.Is(err, base.PluginInstallErr) {
+		t.Fatal(err)
+	}
+	if err := s.PluginInstall(ctx, &base.Plugin{ID: "Missing"}, []byte("broken")); !errors.Is(err, base.PluginInstallErr) {
+		t.Fatal(err)
+	}
+	// 覆盖安装已有的插件须指定 ID
+	p = &base.Plugin{ID: "Totoro"}
+	if err := s.PluginInstall(ctx, p, zba); err != nil || p.Version != "2.1" {
+		t.Fatal(p, err)
+	}
+}
+
+func TestSimNavbarReplace(t *testing.T) {
+	srv := zblogtest.NewServer()
+	defer srv.Close()
+	s := simLogin(t, srv)
+	ctx := context.Background()
+	for _, n := range []*base.Navbar{
+		{Href: "/a.html", Title: "a", Text: "a"},
+		{Href: "/b.html", Title: "b", Text: "b"},
+		{Href: "/a.html", Title: "a2", Text: "a2"},
+	} {
+		if err := s.NavbarNew(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 链接相同的导航被替换 位置不变
+	list, err := s.NavbarList(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, n := range list {
+		got = append(got, n.Href+":"+n.Text)
+	}
+	if strings.Join(got, ",") != "/a.html:a2,/b.html:b" {
+		t.Fatal(got)
+	}
+}
+
+func TestSimVersionGenerator(t *testing.T) {
+	srv := zblogtest.NewServer()
+	defer srv.Close()
+	// 没有 Product 头时从页面的 generator 识别版本
+	srv.NoProduct = true
+	s := simLogin(t, srv)
+	if _, _, err := s.CategoryList(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if s.Version() != zblogtest.DefaultVersion {
+		t.Fatal(s.Version())
+	}
+}
+
+func TestSimArticleLock(t *testing.T) {
+	srv := zblogtest.NewServer()
+	defer srv.Close()
+	s := simLogin(t, srv)
+	ctx := context.Background()
+	a := &base.Article{
+		Union:    base.Union{ID: "0", Type: base.TypeArticle},
+		Title:    "lock",
+		Content:  "<p>lock</p>",
+		Status:   base.StatusPublic,
+		IsLock:   base.LockClosed,
+		AuthorID: "1",
+		PostTime: time.Now(),
+		Cate:     &base.Category{},
+	}
+	if err := s.ArticleNew(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	// 编辑页中的开关是值为 0 或 1 的文本框
+	got := &base.Article{Union: base.Union{ID: a.ID}}
+	if err := s.ArticleGet(ctx, got); err != nil || got.IsLock != base.LockClosed {
+		t.Fatal(got.IsLock, err)
+	}
+	got.IsLock = base.LockOpen
+	if err := s.ArticleNew(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ArticleGet(ctx, got); err != nil || got.IsLock != base.LockOpen {
+		t.Fatal(got.IsLock, err)
+	}
+}
+
+func TestSimComment(t *testing.T) {
+	srv := zblogtest.NewServer()
+	defer srv.Close()
+	s := simLogin(t, srv)
+	ctx := context.Background()
+	a := &base.Article{
+		Union:    base.Union{ID: "0", Type: base.TypeArticle},
+		Title:    "comment",
+		Content:  "<p>comment</p>",
+		Status:   base.StatusPublic,
+		AuthorID: "1",
+		PostTime: time.Now(),
+		Cate:     &base.Category{},
+	}
+	if err := s.ArticleNew(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	srv.AddComment(a.ID, "guest", "hello")
+	list, total, err := s.CommentList(ctx, nil)
+	if err != nil || total != 1 || list[0].Name != "guest" || list[0].LogID != a.ID {
+		t.Fatal(list, total, err)
+	}
+	parent := list[0]
+	reply := &base.Comment{Name: "admin", Content: "thanks"}
+	if err = s.CommentReply(ctx, &parent, reply); err != nil || reply.ParentID != parent.ID {
+		t.Fatal(reply, err)
+	}
+	// 站点拒绝评论时以 200 返回错误页
+	if err = s.CommentReply(ctx, &parent, &base.Comment{Content: "anonymous"}); !errors.Is(err, base.CommentReplyErr) {
+		t.Fatal(err)
+	}
+	if list, total, err = s.CommentList(ctx, nil); err != nil || total != 2 || list[0].ParentID != parent.ID {
+		t.Fatal(list, total, err)
+	}
+	if err = s.CommentUnapprove(ctx, &parent); err != nil {
+		t.Fatal(err)
+	}
+	list, total, err = s.CommentList(ctx, &base.ListOption{Status: base.CommentChecking})
+	if err != nil || total != 1 || list[0].ID != parent.ID || !list[0].IsChecking {
+		t.Fatal(list, total, err)
+	}
+	if err = s.CommentApprove(ctx, &parent); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.CommentDel(ctx, &parent); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.CommentDel(ctx, &parent); !errors.Is(err, base.CommentDelErr) {
+		t.Fatal(err)
+	}
+	if _, total, err = s.CommentList(ctx, nil); err != nil || total != 1 {
+		t.Fatal(total, err)
+	}
+}
+
+func TestSimMember(t *testing.T) {
+	srv := zblogtest.NewServer()
+	defer srv.Close()
+	s := simLogin(t, srv)
+	ctx := context.Background()
+	m := &base.Member{Name: "editor", Password: "editor123", Alias: "编辑", Level: "3", Status: "0"}
+	if err := s.MemberNew(ctx, m); err != nil || m.ID == "" {
+		t.Fatal(m, err)
+	}
+	if err := s.MemberRole(ctx, m, "2"); err != nil {
+		t.Fatal(err)
+	}
+	list, total, err := s.MemberList(ctx, &base.ListOption{Search: "editor"})
+	if err != nil || total != 1 || list[0].Level != "2" || list[0].Alias != "编辑" {
+		t.Fatal(list, total, err)
+	}
+	if _, err = LoginContext(ctx, "editor", "editor123", srv.Info()); err != nil {
+		t.Fatal(err)
+	}
+	// 修改当前登录用户的密码后 重新登录使用新密码
+	if err = s.MemberPassword(ctx, &base.Member{Name: srv.Username}, "changed"); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Password != "changed" {
+		t.Fatal(srv.Password)
+	}
+	srv.Expire()
+	if err = s.MemberDel(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	if s.Relogins() != 1 {
+		t.Fatal(s.Relogins())
+	}
+	if err = s.MemberDel(ctx, &base.Member{Union: base.Union{ID: "1"}}); !errors.Is(err, base.MemberDelErr) {
+		t.Fatal(err)
+	}
+}
+
+func TestSimArticleLocateFail(t *testing.T) {
+	srv := zblogtest.NewServer()
+	defer srv.Close()
+	s := simLogin(t, srv)
+	ctx := context.Background()
+	if _, _, err := s.CategoryList(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	// 已保存但读取文章列表失败 返回 ArticleLocateErr 而不是当作成功
+	srv.Broken = map[string]bool{"ArticleMng": true}
+	a := &base.Article{
+		Union:    base.Union{ID: "0", Type: base.TypeArticle},
+		Title:    "locate",
+		Content:  "<p>locate</p>",
+		Status:   base.StatusPublic,
+		AuthorID: "1",
+		PostTime: time.Now(),
+		Cate:     &base.Category{},
+	}
+	if err := s.ArticleNew(ctx, a); !errors.Is(err, base.ArticleLocateErr) || a.ID != "0" {
+		t.Fatal(a.ID, err)
+	}
+	srv.Broken = nil
+	if err := s.ArticleGet(ctx, &base.Article{Title: "locate"}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,28 +2,54 @@ package z_blog
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"github.com/PuerkitoBio/goquery"
 	"github.com/cgghui/bt_site_cluster_program_api/base"
 	"github.com/cgghui/cgghui"
 	"io"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
+// Capabilities z-blog 支持的功能
+var Capabilities = base.Capabilities{
+	Features: []base.Feature{
+		base.FeatureArticle, base.FeatureCategory, base.FeatureTag, base.FeatureNavbar, base.FeatureSetting,
+		base.FeatureList, base.FeatureComment, base.FeaturePage, base.FeatureMember, base.FeatureMedia,
+		base.FeaturePlugin, base.FeaturePermalink,
+	},
+	Formats: []string{base.FormatHTML},
+}
+
 func init() {
-	base.RegisterProgram("z-blog", Login)
+	base.RegisterProgramContext("z-blog", LoginContext, Capabilities)
+	base.RegisterResume("z-blog", Resume)
+	base.RegisterDetector("z-blog", Detect)
 }
 
 type ZBlogSession struct {
 	zb           base.ProgramBaseInfo
+	username     string
+	password     string
+	mu           sync.Mutex
+	loginMu      sync.Mutex // 重新登录互斥
 	cookie       string
 	cookieValues []*http.Cookie
 	csrfS        string
 	csrfT        time.Time
+	relogins     int
+	reloginAt    time.Time
+	onRelogin    func()
+	version      string
+	prof         *profile
 }
 
 var Client = &http.Client{
@@ -35,50 +61,91 @@ var Client = &http.Client{
 
 // Login 登录
 func Login(username, password string, z base.ProgramBaseInfo) (base.ProgramAPI, error) {
+	s, err := LoginContext(context.Background(), username, password, z)
+	if err != nil {
+		return nil, err
+	}
+	return base.Legacy(s), nil
+}
+
+// LoginContext 带上下文的登录
+func LoginContext(ctx context.Context, username, password string, z base.ProgramBaseInfo) (base.ProgramAPIContext, error) {
+	s := &ZBlogSession{zb: z, username: username, password: password}
+	if err := s.login(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := s.profile(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// login 以保存的用户名密码登录 成功后替换当前的cookie并清除CSRF
+func (s *ZBlogSession) login(ctx context.Context) error {
+	s.mu.Lock()
+	password := s.password
+	s.mu.Unlock()
 	param := url.Values{}
-	param.Set("edtUserName", username)
+	param.Set("edtUserName", s.username)
 	param.Set("edtPassWord", password)
 	param.Set("btnPost", "登录")
-	param.Set("username", username)
+	param.Set("username", s.username)
 	param.Set("password", cgghui.MD5(password))
 	param.Set("savedate", "1")
-	req, err := http.NewRequest(http.MethodPost, z.HomeURL+z.BackstagePath+z.LoginPath, strings.NewReader(param.Encode()))
-	req.Header.Add("User-Agent", base.UserAgent)
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.zb.HomeURL+s.zb.BackstagePath+s.zb.LoginPath, strings.NewReader(param.Encode()))
 	if err != nil {
-		return nil, err
+		return err
 	}
+	req.Header.Add("User-Agent", base.UserAgent)
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	var resp *http.Response
-	if resp, err = Client.Do(req); err != nil {
-		return nil, err
+	if resp, err = s.send("Login", req); err != nil {
+		return err
 	}
 	defer func() {
 		_ = resp.Body.Close()
 	}()
 	if resp.StatusCode != 302 {
-		return nil, base.LoginFailErr
+		return s.fail("Login", base.LoginFailErr, resp)
 	}
-	s := &ZBlogSession{zb: z, cookie: ""}
-	c := make([]string, 0)
-	for _, cookie := range resp.Cookies() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setCookies(resp.Cookies())
+	s.csrfS = ""
+	return nil
+}
+
+// setCookies 设置cookie 调用方需持有 s.mu
+func (s *ZBlogSession) setCookies(cookies []*http.Cookie) {
+	c := make([]string, 0, len(cookies))
+	for _, cookie := range cookies {
 		c = append(c, cookie.Name+"="+cookie.Value)
 	}
 	s.cookie = strings.Join(c, "; ")
-	s.cookieValues = resp.Cookies()
-	return s, nil
+	s.cookieValues = cookies
 }
 
-// GetCSRF 获取CSRF
-func (s *ZBlogSession) GetCSRF() string {
+func (s *ZBlogSession) cookieHeader() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cookie
+}
+
+// GetCSRF 获取CSRF 获取失败时返回空字符串且不缓存，请求会因CSRF校验失败而触发重新登录
+func (s *ZBlogSession) GetCSRF(ctx context.Context) string {
+	s.mu.Lock()
 	if s.csrfS != "" && time.Now().Before(s.csrfT) {
-		return s.csrfS
+		csrf := s.csrfS
+		s.mu.Unlock()
+		return csrf
 	}
-	req, err := s.NewRequest(http.MethodGet, "admin/index.php", nil)
+	s.mu.Unlock()
+	req, err := s.NewRequest(ctx, http.MethodGet, "admin/index.php", nil)
 	if err != nil {
 		return ""
 	}
 	var resp *http.Response
-	if resp, err = Client.Do(req); err != nil {
+	if resp, err = s.do("GetCSRF", req); err != nil {
 		return ""
 	}
 	defer func() {
@@ -90,14 +157,16 @@ func (s *ZBlogSession) GetCSRF() string {
 	}
 	csrf := doc.Find(`meta[name="csrfToken"]`).AttrOr("content", "")
 	if csrf != "" {
+		s.mu.Lock()
 		s.csrfT = time.Now().Add(time.Minute)
 		s.csrfS = csrf
+		s.mu.Unlock()
 	}
 	return csrf
 }
 
 // ParamCSRF URL参数
-func (s *ZBlogSession) ParamCSRF(uri, act string, params ...url.Values) string {
+func (s *ZBlogSession) ParamCSRF(ctx context.Context, uri, act string, params ...url.Values) string {
 	var param url.Values
 	if len(params) > 0 {
 		param = params[0]
@@ -107,7 +176,7 @@ func (s *ZBlogSession) ParamCSRF(uri, act string, params ...url.Values) string {
 	} else {
 		param = url.Values{}
 	}
-	param.Set("csrfToken", s.GetCSRF())
+	param.Set("csrfToken", s.GetCSRF(ctx))
 	if act != "" {
 		param.Set("act", act)
 	}
@@ -116,65 +185,34 @@ func (s *ZBlogSession) ParamCSRF(uri, act string, params ...url.Values) string {
 
 var ErrOpenRewriteFail = errors.New("open rewrite fail")
 
-func (s *ZBlogSession) Init() error {
-	// open rewrite
-	param := url.Values{}
-	param.Set("name", "STACentre")
-	req, err := s.NewRequest(http.MethodGet, s.ParamCSRF("cmd.php", "PluginEnb", param), nil)
-	if err != nil {
-		return err
-	}
-	var resp *http.Response
-	if resp, err = Client.Do(req); err != nil {
-		return err
-	}
-	_ = resp.Body.Close()
-	if resp.StatusCode != 302 {
-		return ErrOpenRewriteFail
-	}
-	//
-	param = url.Values{}
-	param.Set("install", "STACentre")
-	req, err = s.NewRequest(http.MethodGet, s.ParamCSRF("cmd.php", "PluginMng", param), nil)
-	if err != nil {
-		return err
-	}
-	if resp, err = Client.Do(req); err != nil {
-		return err
-	}
-	_ = resp.Body.Close()
-	if resp.StatusCode != 302 {
-		return ErrOpenRewriteFail
-	}
-	//
-	param = url.Values{}
-	param.Set("csrfToken", s.GetCSRF())
-	param.Set("reset", "")
-	param.Set("ZC_STATIC_MODE", "REWRITE")
-	param.Set("ZC_ARTICLE_REGEX", "{%host%}post/{%id%}.html")
-	param.Set("ZC_PAGE_REGEX", "{%host%}{%id%}.html")
-	param.Set("ZC_INDEX_REGEX", "{%host%}page_{%page%}.html")
-	param.Set("ZC_CATEGORY_REGEX", "{%host%}category-{%id%}_{%page%}.html")
-	param.Set("ZC_TAGS_REGEX", "{%host%}tags-{%alias%}_{%page%}.html")
-	param.Set("radioZC_TAGS_REGEX", "{%host%}tags-{%alias%}_{%page%}.html")
-	param.Set("ZC_DATE_REGEX", "{%host%}date-{%date%}_{%page%}.html")
-	param.Set("ZC_AUTHOR_REGEX", "{%host%}date-{%date%}_{%page%}.html")
-	req, err = s.NewRequestHome(http.MethodPost, s.ParamCSRF("zb_users/plugin/STACentre/main.php", ""), param)
-	if err != nil {
-		return err
+// rewritePlugin 伪静态插件 Init 总是启用
+const rewritePlugin = "STACentre"
+
+// Init 初始化 启用 ProgramBaseInfo.Plugins 中的插件，按 ProgramBaseInfo.Permalink 开启伪静态
+// 仅有部分插件失败时仍会完成初始化，并返回 *base.PluginError
+func (s *ZBlogSession) Init(ctx context.Context) error {
+	failed := s.pluginReconcile(ctx, append([]string{rewritePlugin}, s.zb.Plugins...))
+	if err, ok := failed[rewritePlugin]; ok {
+		return s.cause("Init", ErrOpenRewriteFail, err)
 	}
-	if resp, err = Client.Do(req); err != nil {
+	// open rewrite
+	if err := s.PermalinkSet(ctx, s.zb.Permalink.Resolve()); err != nil {
+		if errors.Is(err, base.PermalinkSetErr) {
+			return s.cause("Init", ErrOpenRewriteFail, err)
+		}
 		return err
 	}
-	_ = resp.Body.Close()
-	if resp.StatusCode != 302 {
-		return ErrOpenRewriteFail
+	if len(failed) > 0 {
+		return &base.PluginError{Failed: failed}
 	}
 	return nil
 }
 
-// ArticleNew 新建或修改文章
-func (s *ZBlogSession) ArticleNew(a *base.Article) error {
+// ArticleNew 新建或修改文章 成功后填充 Article.ID 及 Article.Permalink，已保存但在列表中找不到时返回 ArticleLocateErr
+func (s *ZBlogSession) ArticleNew(ctx context.Context, a *base.Article) error {
+	if err := a.Validate(); err != nil {
+		return s.cause("ArticleNew", base.ArticleNewErr, err)
+	}
 	art := url.Values{}
 	art.Add("ID", a.ID)
 	art.Add("Type", a.Type)
@@ -183,19 +221,19 @@ func (s *ZBlogSession) ArticleNew(a *base.Article) error {
 	art.Add("Alias", a.Alias)
 	art.Add("Tag", strings.Join(a.Tag, ","))
 	art.Add("CateID", a.Cate.ID)
-	art.Add("Status", a.Status)
+	art.Add("Status", string(a.Status))
 	art.Add("Template", a.Template)
 	art.Add("AuthorID", a.AuthorID)
 	art.Add("PostTime", a.PostTime.Format("2006-01-02 15:04:05"))
-	art.Add("IsTop", a.IsTop)
-	art.Add("IsLock", a.IsLock)
+	art.Add("IsTop", string(a.IsTop))
+	art.Add("IsLock", lockValue(a.IsLock))
 	art.Add("Intro", a.Intro)
-	req, err := s.NewRequest(http.MethodPost, s.ParamCSRF("cmd.php", "ArticlePst"), art)
+	req, err := s.NewRequest(ctx, http.MethodPost, s.ParamCSRF(ctx, "cmd.php", "ArticlePst"), art)
 	if err != nil {
 		return err
 	}
 	var resp *http.Response
-	if resp, err = Client.Do(req); err != nil {
+	if resp, err = s.do("ArticleNew", req); err != nil {
 		return err
 	}
 	defer func() {
@@ -205,80 +243,229 @@ func (s *ZBlogSession) ArticleNew(a *base.Article) error {
 	if body, err = ioutil.ReadAll(resp.Body); err != nil {
 		return err
 	}
-	if bytes.Contains(body, checkNewArticleSuccess) {
-		return nil
+	if !bytes.Contains(body, checkNewArticleSuccess) {
+		return s.fail("ArticleNew", base.ArticleNewErr, resp, body)
 	}
-	return base.ArticleNewErr
+	return s.articleLocate(ctx, "ArticleNew", postKind, a)
 }
 
-func (s *ZBlogSession) ArticleGet(a *base.Article) error {
-	art := url.Values{}
-	art.Add("category", "")
-	art.Add("status", "")
-	art.Add("search", a.Title)
-	req, err := s.NewRequestHome(http.MethodPost, s.ParamCSRF("zb_system/admin/index.php", "ArticleMng"), art)
-	if err != nil {
-		return err
-	}
-	var resp *http.Response
-	if resp, err = Client.Do(req); err != nil {
-		return err
-	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-	var doc *goquery.Document
-	if doc, err = goquery.NewDocumentFromReader(resp.Body); err != nil {
-		return err
+// articleKind 文章与页面共用的后台操作
+type articleKind struct {
+	get      string // 操作名称
+	edt      string // 编辑页的act
+	mng      string // 管理页的act
+	search   bool   // 管理页是否支持按标题搜索 不支持时在本地过滤
+	row      func(r row) base.Article
+	notFound error
+}
+
+var postKind = articleKind{get: "ArticleGet", edt: "ArticleEdt", mng: "ArticleMng", search: true, row: articleRow, notFound: base.ArticleGetErr}
+var pageKind = articleKind{get: "PageGet", edt: "PageEdt", mng: "PageMng", row: pageRow, notFound: base.PageGetErr}
+
+// articleSearch 按标题搜索文章或页面 fn 返回 false 时停止，不再读取之后的分页
+func (s *ZBlogSession) articleSearch(ctx context.Context, k articleKind, title string, fn func(a base.Article) bool) error {
+	var param url.Values
+	if k.search {
+		param = articleParam(title, &base.ListOption{})
 	}
-	doc.Find(".table_striped tr").EachWithBreak(func(i int, tr *goquery.Selection) bool {
-		if i == 0 {
+	return s.walkAdmin(ctx, k.mng, param, func(r row) bool {
+		a := k.row(r)
+		if !strings.Contains(a.Title, title) {
 			return true
 		}
-		if strings.TrimSpace(tr.Find("td").Eq(3).Text()) == a.Title {
-			a.ID = tr.Find("td").Eq(0).Text()
+		return fn(a)
+	})
+}
+
+// articleLocate 发布后从列表查找文章的ID及链接
+// 修改时按ID查找；新建时取标题完全相同且发布时间一致的第一篇，没有时取标题相同且ID最大的一篇
+// 查找失败时不修改 a，返回 ArticleLocateErr
+func (s *ZBlogSession) articleLocate(ctx context.Context, op string, k articleKind, a *base.Article) error {
+	update := a.ID != "" && a.ID != "0"
+	postTime := a.PostTime.Format("2006-01-02 15:04:05")
+	var found *base.Article
+	err := s.articleSearch(ctx, k, a.Title, func(art base.Article) bool {
+		if update {
+			if art.ID == a.ID {
+				found = &art
+				return false
+			}
+			return true
+		}
+		if art.Title != a.Title {
+			return true
+		}
+		if !a.PostTime.IsZero() && art.PostTime.Format("2006-01-02 15:04:05") == postTime {
+			found = &art
 			return false
 		}
+		if found == nil || articleIDLess(found.ID, art.ID) {
+			found = &art
+		}
 		return true
 	})
-	return base.ArticleGetErr
+	if err == nil && found == nil {
+		err = errors.New("列表中没有该标题")
+	}
+	if err != nil {
+		return s.cause(op, base.ArticleLocateErr, err)
+	}
+	a.ID = found.ID
+	a.Permalink = found.Permalink
+	return nil
 }
 
-// ArticleDel 删除文章
-func (s *ZBlogSession) ArticleDel(a *base.Article) error {
-	if a.ID == "0" || a.ID == "" {
-		return errors.New("请指定文章的id")
+func articleIDLess(a, b string) bool {
+	x, _ := strconv.Atoi(a)
+	y, _ := strconv.Atoi(b)
+	return x < y
+}
+
+// ArticleGet 获取文章 按 ID、标题（完全匹配）、别名的顺序查找，找到后读取编辑页填充全部字段
+// 按别名查找需要逐篇读取编辑页，开销较大
+func (s *ZBlogSession) ArticleGet(ctx context.Context, a *base.Article) error {
+	return s.articleFind(ctx, postKind, a)
+}
+
+func (s *ZBlogSession) articleFind(ctx context.Context, k articleKind, a *base.Article) error {
+	if a.ID != "" && a.ID != "0" {
+		return s.articleEdt(ctx, k, a.ID, a)
+	}
+	if a.Title != "" {
+		var found *base.Article
+		err := s.articleSearch(ctx, k, a.Title, func(art base.Article) bool {
+			if art.Title != a.Title {
+				return true
+			}
+			found = &art
+			return false
+		})
+		if err != nil {
+			return err
+		}
+		if found == nil {
+			return s.fail(k.get, k.notFound, nil)
+		}
+		a.Permalink = found.Permalink
+		return s.articleEdt(ctx, k, found.ID, a)
+	}
+	if a.Alias != "" {
+		var found *base.Article
+		var edtErr error
+		err := s.walkAdmin(ctx, k.mng, nil, func(r row) bool {
+			art := k.row(r)
+			if edtErr = s.articleEdt(ctx, k, art.ID, &art); edtErr != nil {
+				return false
+			}
+			if art.Alias != a.Alias {
+				return true
+			}
+			found = &art
+			return false
+		})
+		if err == nil {
+			err = edtErr
+		}
+		if err != nil {
+			return err
+		}
+		if found != nil {
+			*a = *found
+			return nil
+		}
 	}
+	return s.fail(k.get, k.notFound, nil)
+}
+
+// articleEdt 读取文章或页面的编辑页
+func (s *ZBlogSession) articleEdt(ctx context.Context, k articleKind, id string, a *base.Article) error {
 	param := url.Values{}
-	param.Set("id", a.ID)
-	req, err := s.NewRequest(http.MethodGet, s.ParamCSRF("cmd.php", "ArticleDel", param), nil)
+	param.Set("id", id)
+	req, err := s.NewRequest(ctx, http.MethodGet, s.ParamCSRF(ctx, "admin/edit.php", k.edt, param), nil)
 	if err != nil {
 		return err
 	}
 	var resp *http.Response
-	if resp, err = Client.Do(req); err != nil {
+	if resp, err = s.do(k.get, req); err != nil {
 		return err
 	}
 	defer func() {
 		_ = resp.Body.Close()
 	}()
-	if resp.StatusCode == 302 {
-		return nil
+	if resp.StatusCode != 200 {
+		return s.fail(k.get, k.notFound, resp)
+	}
+	var doc *goquery.Document
+	if doc, err = goquery.NewDocumentFromReader(resp.Body); err != nil {
+		return err
 	}
-	return base.ArticleDelErr
+	if v := formValue(doc, "ID"); v == "" || v == "0" {
+		return s.fail(k.get, k.notFound, resp)
+	}
+	editForm(doc, a)
+	return nil
 }
 
-// SiteSetting 站点设置
-func (s *ZBlogSession) SiteSetting(ss *base.SiteSetting) error {
+// editForm 从文章或页面的编辑页读取各字段
+func editForm(doc *goquery.Document, a *base.Article) {
+	a.ID = formValue(doc, "ID")
+	a.Type = formValue(doc, "Type")
+	a.Title = formValue(doc, "Title")
+	a.Content = formValue(doc, "Content")
+	a.Alias = formValue(doc, "Alias")
+	a.Tag = make([]string, 0)
+	for _, tag := range strings.Split(formValue(doc, "Tag"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			a.Tag = append(a.Tag, tag)
+		}
+	}
+	a.Cate = &base.Category{
+		Union: base.Union{ID: formValue(doc, "CateID"), Type: "0"},
+		Name:  strings.TrimSpace(doc.Find(`select[name="CateID"] option[selected]`).First().Text()),
+	}
+	a.Status = base.Status(formValue(doc, "Status"))
+	a.Template = formValue(doc, "Template")
+	a.AuthorID = formValue(doc, "AuthorID")
+	a.PostTime = parseTime(formValue(doc, "PostTime"))
+	a.IsTop = base.TopLevel(formValue(doc, "IsTop"))
+	a.IsLock = lockState(formValue(doc, "IsLock"))
+	a.Intro = formValue(doc, "Intro")
+}
+
+// formValue 读取表单字段的值 支持 input textarea select
+func formValue(doc *goquery.Document, name string) string {
+	field := doc.Find(`[name="` + name + `"]`).First()
+	switch goquery.NodeName(field) {
+	case "textarea":
+		return field.Text()
+	case "select":
+		opt := field.Find("option[selected]").First()
+		if opt.Length() == 0 {
+			opt = field.Find("option").First()
+		}
+		return opt.AttrOr("value", "")
+	case "input":
+		if t := field.AttrOr("type", ""); t == "checkbox" || t == "radio" {
+			checked := doc.Find(`input[name="` + name + `"][checked]`).First()
+			return checked.AttrOr("value", "")
+		}
+	}
+	return field.AttrOr("value", "")
+}
+
+// ArticleDel 删除文章
+func (s *ZBlogSession) ArticleDel(ctx context.Context, a *base.Article) error {
+	if a.ID == "0" || a.ID == "" {
+		return s.invalid("ArticleDel", "请指定文章的id")
+	}
 	param := url.Values{}
-	param.Set("ZC_BLOG_NAME", ss.SiteName)
-	param.Set("ZC_BLOG_SUBNAME", ss.SubSiteName)
-	req, err := s.NewRequest(http.MethodPost, s.ParamCSRF("cmd.php", "SettingSav"), param)
+	param.Set("id", a.ID)
+	req, err := s.NewRequest(ctx, http.MethodGet, s.ParamCSRF(ctx, "cmd.php", "ArticleDel", param), nil)
 	if err != nil {
 		return err
 	}
 	var resp *http.Response
-	if resp, err = Client.Do(req); err != nil {
+	if resp, err = s.do("ArticleDel", req); err != nil {
 		return err
 	}
 	defer func() {
@@ -287,11 +474,11 @@ func (s *ZBlogSession) SiteSetting(ss *base.SiteSetting) error {
 	if resp.StatusCode == 302 {
 		return nil
 	}
-	return base.SiteSettingErr
+	return s.fail("ArticleDel", base.ArticleDelErr, resp)
 }
 
 // CategoryNew 新建或修改分类
-func (s *ZBlogSession) CategoryNew(c *base.Category) error {
+func (s *ZBlogSession) CategoryNew(ctx context.Context, c *base.Category) error {
 	cate := url.Values{}
 	if c.ID == "" {
 		cate.Add("ID", "0")
@@ -318,12 +505,12 @@ func (s *ZBlogSession) CategoryNew(c *base.Category) error {
 	}
 	cate.Add("Intro", c.Intro)
 	cate.Add("AddNavbar", c.AddNavbar)
-	req, err := s.NewRequest(http.MethodPost, s.ParamCSRF("cmd.php", "CategoryPst"), cate)
+	req, err := s.NewRequest(ctx, http.MethodPost, s.ParamCSRF(ctx, "cmd.php", "CategoryPst"), cate)
 	if err != nil {
 		return err
 	}
 	var resp *http.Response
-	if resp, err = Client.Do(req); err != nil {
+	if resp, err = s.do("CategoryNew", req); err != nil {
 		return err
 	}
 	defer func() {
@@ -332,17 +519,17 @@ func (s *ZBlogSession) CategoryNew(c *base.Category) error {
 	if resp.StatusCode == 302 {
 		return nil
 	}
-	return base.CategoryNewErr
+	return s.fail("CategoryNew", base.CategoryNewErr, resp)
 }
 
 // CategoryGet 查找分类
-func (s *ZBlogSession) CategoryGet(c *base.Category) error {
-	req, err := s.NewRequestHome(http.MethodGet, s.ParamCSRF("zb_system/admin/index.php", "CategoryMng"), nil)
+func (s *ZBlogSession) CategoryGet(ctx context.Context, c *base.Category) error {
+	req, err := s.NewRequestHome(ctx, http.MethodGet, s.ParamCSRF(ctx, "zb_system/admin/index.php", "CategoryMng"), nil)
 	if err != nil {
 		return err
 	}
 	var resp *http.Response
-	if resp, err = Client.Do(req); err != nil {
+	if resp, err = s.do("CategoryGet", req); err != nil {
 		return err
 	}
 	defer func() {
@@ -352,39 +539,37 @@ func (s *ZBlogSession) CategoryGet(c *base.Category) error {
 	if doc, err = goquery.NewDocumentFromReader(resp.Body); err != nil {
 		return err
 	}
-	doc.Find(".tableBorder-thcenter tr").EachWithBreak(func(i int, tr *goquery.Selection) bool {
-		if i == 0 {
+	err = s.eachRow(ctx, "CategoryMng", doc, func(r row) bool {
+		if r.text("name") != c.Name {
 			return true
 		}
-		name := strings.TrimSpace(tr.Find("td").Eq(2).Text())
-		if name == c.Name {
-			c.ID = strings.TrimSpace(tr.Find("td").Eq(0).Text())
-			c.Order = strings.TrimSpace(tr.Find("td").Eq(1).Text())
-			c.Alias = strings.TrimSpace(tr.Find("td").Eq(3).Text())
-
-			return false
-		}
-		return true
+		c.ID = r.text("id")
+		c.Order = r.text("order")
+		c.Alias = r.text("alias")
+		return false
 	})
+	if err != nil {
+		return err
+	}
 	if c.ID != "" {
 		return nil
 	}
-	return base.CategoryGetErr
+	return s.fail("CategoryGet", base.CategoryGetErr, resp)
 }
 
 // CategoryDel 删除分类
-func (s *ZBlogSession) CategoryDel(c *base.Category) error {
+func (s *ZBlogSession) CategoryDel(ctx context.Context, c *base.Category) error {
 	if c.ID == "0" || c.ID == "" {
-		return errors.New("请指定分类的id")
+		return s.invalid("CategoryDel", "请指定分类的id")
 	}
 	param := url.Values{}
 	param.Set("id", c.ID)
-	req, err := s.NewRequest(http.MethodGet, s.ParamCSRF("cmd.php", "CategoryDel", param), nil)
+	req, err := s.NewRequest(ctx, http.MethodGet, s.ParamCSRF(ctx, "cmd.php", "CategoryDel", param), nil)
 	if err != nil {
 		return err
 	}
 	var resp *http.Response
-	if resp, err = Client.Do(req); err != nil {
+	if resp, err = s.do("CategoryDel", req); err != nil {
 		return err
 	}
 	defer func() {
@@ -393,57 +578,67 @@ func (s *ZBlogSession) CategoryDel(c *base.Category) error {
 	if resp.StatusCode == 302 {
 		return nil
 	}
-	return base.CategoryDelErr
+	return s.fail("CategoryDel", base.CategoryDelErr, resp)
 }
 
 var StatusCodeNot200Err = errors.New("status code not 200")
 
 // NavbarList 导航
-func (s *ZBlogSession) NavbarList() ([]*base.Navbar, error) {
+func (s *ZBlogSession) NavbarList(ctx context.Context) ([]*base.Navbar, error) {
 	param := url.Values{}
 	param.Set("edit", "navbar")
-	req, err := s.NewRequestHome(http.MethodGet, s.ParamCSRF("zb_users/plugin/LinksManage/main.php", "", param), nil)
+	req, err := s.NewRequestHome(ctx, http.MethodGet, s.ParamCSRF(ctx, "zb_users/plugin/LinksManage/main.php", "", param), nil)
 	if err != nil {
 		return nil, err
 	}
 	var resp *http.Response
-	if resp, err = Client.Do(req); err != nil {
+	if resp, err = s.do("NavbarList", req); err != nil {
 		return nil, err
 	}
 	defer func() {
 		_ = resp.Body.Close()
 	}()
 	if resp.StatusCode != 200 {
-		return nil, StatusCodeNot200Err
+		return nil, s.fail("NavbarList", StatusCodeNot200Err, resp)
 	}
 	var doc *goquery.Document
 	if doc, err = goquery.NewDocumentFromReader(resp.Body); err != nil {
 		return nil, err
 	}
 	data := make([]*base.Navbar, 0)
-	doc.Find("#LinksManageList tr").Each(func(_ int, tr *goquery.Selection) {
-		if tr.Find("td").Length() != 6 {
-			return
-		}
+	err = s.eachRow(ctx, "LinksManage", doc, func(r row) bool {
 		data = append(data, &base.Navbar{
-			Href:   tr.Find(`input[name="href[]"]`).AttrOr("value", ""),
-			Title:  tr.Find(`input[name="title[]"]`).AttrOr("value", ""),
-			Text:   tr.Find(`input[name="text[]"]`).AttrOr("value", ""),
-			Target: tr.Find(`input[name="target[]"]`).AttrOr("value", ""),
-			Sub:    tr.Find(`input[name="sub[]"]`).AttrOr("value", ""),
-			Ico:    tr.Find(`input[name="ico[]"]`).AttrOr("value", ""),
+			Href:   r.td.Find(`input[name="href[]"]`).AttrOr("value", ""),
+			Title:  r.td.Find(`input[name="title[]"]`).AttrOr("value", ""),
+			Text:   r.td.Find(`input[name="text[]"]`).AttrOr("value", ""),
+			Target: r.td.Find(`input[name="target[]"]`).AttrOr("value", ""),
+			Sub:    r.td.Find(`input[name="sub[]"]`).AttrOr("value", ""),
+			Ico:    r.td.Find(`input[name="ico[]"]`).AttrOr("value", ""),
 		})
+		return true
 	})
+	if err != nil {
+		return nil, err
+	}
 	return data, nil
 }
 
-// NavbarNew 创建导航
-func (s *ZBlogSession) NavbarNew(n *base.Navbar) error {
-	navList, err := s.NavbarList()
+// NavbarNew 创建导航 链接已存在时修改该导航
+func (s *ZBlogSession) NavbarNew(ctx context.Context, n *base.Navbar) error {
+	navList, err := s.NavbarList(ctx)
 	if err != nil {
 		navList = make([]*base.Navbar, 0)
 	}
-	navList = append(navList, n)
+	replaced := false
+	for i, nav := range navList {
+		if nav.Href == n.Href {
+			navList[i] = n
+			replaced = true
+		}
+	}
+	if !replaced {
+		navList = append(navList, n)
+	}
 	param := url.Values{}
 	param.Set("ID", "1")
 	param.Set("Source", "system")
@@ -468,7 +663,7 @@ func (s *ZBlogSession) NavbarNew(n *base.Navbar) error {
 	param.Add("sub[]", "")
 	param.Add("ico[]", "")
 	var req *http.Request
-	req, err = s.NewRequestHome(http.MethodPost, s.ParamCSRF("zb_users/plugin/LinksManage/main.php", "save"), param)
+	req, err = s.NewRequestHome(ctx, http.MethodPost, s.ParamCSRF(ctx, "zb_users/plugin/LinksManage/main.php", "save"), param)
 	if err != nil {
 		return err
 	}
@@ -484,12 +679,12 @@ func (s *ZBlogSession) NavbarNew(n *base.Navbar) error {
 	if resp.StatusCode == 302 {
 		return nil
 	}
-	return base.NavbarNewErr
+	return s.fail("NavbarNew", base.NavbarNewErr, resp)
 }
 
 var duplicateTag = []byte("标签名称重复")
 
-func (s *ZBlogSession) TagNew(t *base.Tag) error {
+func (s *ZBlogSession) TagNew(ctx context.Context, t *base.Tag) error {
 	param := url.Values{}
 	param.Set("ID", t.ID)
 	param.Set("Type", t.Type)
@@ -500,12 +695,12 @@ func (s *ZBlogSession) TagNew(t *base.Tag) error {
 	param.Set("AddNavbar", t.AddNavbar)
 	var req *http.Request
 	var err error
-	req, err = s.NewRequest(http.MethodPost, s.ParamCSRF("cmd.php", "TagPst"), param)
+	req, err = s.NewRequest(ctx, http.MethodPost, s.ParamCSRF(ctx, "cmd.php", "TagPst"), param)
 	if err != nil {
 		return err
 	}
 	var resp *http.Response
-	if resp, err = Client.Do(req); err != nil {
+	if resp, err = s.do("TagNew", req); err != nil {
 		return err
 	}
 	defer func() {
@@ -514,23 +709,24 @@ func (s *ZBlogSession) TagNew(t *base.Tag) error {
 	if resp.StatusCode == 302 {
 		return nil
 	}
-	if b, _ := ioutil.ReadAll(resp.Body); bytes.Contains(b, duplicateTag) {
+	b, _ := ioutil.ReadAll(resp.Body)
+	if bytes.Contains(b, duplicateTag) {
 		return nil
 	}
-	return base.TagNewErr
+	return s.fail("TagNew", base.TagNewErr, resp, b)
 }
 
-func (s *ZBlogSession) TagGet(t *base.Tag) error {
+func (s *ZBlogSession) TagGet(ctx context.Context, t *base.Tag) error {
 	param := url.Values{}
 	param.Set("search", t.Name)
 	var req *http.Request
 	var err error
-	req, err = s.NewRequestHome(http.MethodPost, s.ParamCSRF("zb_system/admin/index.php", "TagMng"), param)
+	req, err = s.NewRequestHome(ctx, http.MethodPost, s.ParamCSRF(ctx, "zb_system/admin/index.php", "TagMng"), param)
 	if err != nil {
 		return err
 	}
 	var resp *http.Response
-	if resp, err = Client.Do(req); err != nil {
+	if resp, err = s.do("TagGet", req); err != nil {
 		return err
 	}
 	defer func() {
@@ -540,93 +736,132 @@ func (s *ZBlogSession) TagGet(t *base.Tag) error {
 	if doc, err = goquery.NewDocumentFromReader(resp.Body); err != nil {
 		return err
 	}
-	tr := doc.Find(".table_striped tr")
-	if tr.Length() == 1 {
-		return base.TagUndefinedErr
+	found := false
+	err = s.eachRow(ctx, "TagMng", doc, func(r row) bool {
+		t.ID = r.text("id")
+		t.Name = r.text("name")
+		t.Alias = r.text("alias")
+		found = true
+		return false
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return s.fail("TagGet", base.TagUndefinedErr, resp)
 	}
-	td := tr.Eq(1).Find("td")
-	t.ID = td.Eq(0).Text()
-	t.Name = td.Eq(1).Text()
-	t.Alias = td.Eq(2).Text()
 	return nil
 }
 
-func (s *ZBlogSession) TagDel(t *base.Tag) error {
+func (s *ZBlogSession) TagDel(ctx context.Context, t *base.Tag) error {
 	var err error
-	if err = s.TagGet(t); err != nil {
+	if err = s.TagGet(ctx, t); err != nil {
 		return err
 	}
 	param := url.Values{}
 	param.Set("id", t.ID)
 	var req *http.Request
-	req, err = s.NewRequest(http.MethodGet, s.ParamCSRF("cmd.php", "TagDel", param), nil)
+	req, err = s.NewRequest(ctx, http.MethodGet, s.ParamCSRF(ctx, "cmd.php", "TagDel", param), nil)
 	if err != nil {
 		return err
 	}
 	var resp *http.Response
-	if resp, err = Client.Do(req); err != nil {
+	if resp, err = s.do("TagDel", req); err != nil {
 		return err
 	}
 	defer func() {
 		_ = resp.Body.Close()
 	}()
 	if resp.StatusCode == 302 {
-		return base.TagDelErr
+		return nil
 	}
-	return nil
+	return s.fail("TagDel", base.TagDelErr, resp)
 }
 
-func (s *ZBlogSession) NewRequestHome(method, uri string, param url.Values) (*http.Request, error) {
+func (s *ZBlogSession) NewRequestHome(ctx context.Context, method, uri string, param url.Values) (*http.Request, error) {
 	var body io.Reader
 	if param == nil {
 		body = nil
 	} else {
 		body = strings.NewReader(base.UrlQueryBuild(param))
 	}
-	req, err := http.NewRequest(method, s.zb.HomeURL+uri, body)
+	req, err := http.NewRequestWithContext(ctx, method, s.zb.HomeURL+uri, body)
 	if err != nil {
 		return req, err
 	}
 	req.Header.Add("User-Agent", base.UserAgent)
-	req.Header.Add("Cookie", s.cookie)
+	req.Header.Add("Cookie", s.cookieHeader())
 	if method == http.MethodPost {
 		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	}
 	return req, err
 }
 
+// NewUploadRequest 创建文件上传请求 uri 相对于主站
+func (s *ZBlogSession) NewUploadRequest(ctx context.Context, uri, field, filename, mimeType string, data []byte, param url.Values) (*http.Request, error) {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	for k, vs := range param {
+		for _, v := range vs {
+			if err := w.WriteField(k, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="`+field+`"; filename="`+strings.ReplaceAll(filename, `"`, "")+`"`)
+	h.Set("Content-Type", mimeType)
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = part.Write(data); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPost, s.zb.HomeURL+uri, body); err != nil {
+		return nil, err
+	}
+	req.Header.Add("User-Agent", base.UserAgent)
+	req.Header.Add("Cookie", s.cookieHeader())
+	req.Header.Add("Content-Type", w.FormDataContentType())
+	return req, nil
+}
+
 // NewRequest 发起请求
-func (s *ZBlogSession) NewRequest(method, uri string, param url.Values) (*http.Request, error) {
-	return s.NewRequestHome(method, s.zb.BackstagePath+"/"+uri, param)
+func (s *ZBlogSession) NewRequest(ctx context.Context, method, uri string, param url.Values) (*http.Request, error) {
+	return s.NewRequestHome(ctx, method, s.zb.BackstagePath+"/"+uri, param)
 }
 
+// RequestAction 发起请求并合并响应中的cookie
 func (s *ZBlogSession) RequestAction(req *http.Request) (*http.Response, error) {
-	var resp *http.Response
-	var err error
-	if resp, err = Client.Do(req); err != nil {
+	resp, err := s.do("RequestAction", req)
+	if err != nil {
 		return nil, err
 	}
 	if len(resp.Cookies()) > 0 {
+		s.mu.Lock()
+		values := append([]*http.Cookie{}, s.cookieValues...)
 		for _, c := range resp.Cookies() {
 			has := false
-			for i, cv := range s.cookieValues {
+			for i, cv := range values {
 				if cv.Name == c.Name {
-					s.cookieValues[i] = c
+					values[i] = c
 					has = true
 					break
 				}
 			}
 			if !has {
-				s.cookieValues = append(s.cookieValues, c)
+				values = append(values, c)
 			}
 		}
-		c := make([]string, 0)
-		for _, cookie := range resp.Cookies() {
-			c = append(c, cookie.Name+"="+cookie.Value)
-		}
-		s.cookie = strings.Join(c, "; ")
+		s.setCookies(values)
+		s.mu.Unlock()
 	}
-	return resp, err
+	return resp, nil
 }
 
 var checkNewArticleSuccess = []byte("cmd.php%3Fact%3DArticleMng")
@@ -3,18 +3,24 @@ package z_blog
 import (
 	"fmt"
 	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"os"
 	"testing"
 )
 
+// TestZBlog_Login 登录真实站点 需设置 ZBLOG_HOME、ZBLOG_USER、ZBLOG_PASSWORD，未设置时跳过
 func TestZBlog_Login(t *testing.T) {
+	home, user, password := os.Getenv("ZBLOG_HOME"), os.Getenv("ZBLOG_USER"), os.Getenv("ZBLOG_PASSWORD")
+	if home == "" || user == "" || password == "" {
+		t.Skip("未设置 ZBLOG_HOME、ZBLOG_USER、ZBLOG_PASSWORD")
+	}
 
 	z := base.ProgramBaseInfo{
-		HomeURL:       "http://blog.isolezvoscombles.com/",
+		HomeURL:       home,
 		BackstagePath: "zb_system/",
 		LoginPath:     "cmd.php?act=verify",
 	}
 
-	s, err := Login("admin", "YPVCX6HqGDN15XCZ", z)
+	s, err := Login(user, password, z)
 	if err != nil {
 		t.Fatal(err)
 	}
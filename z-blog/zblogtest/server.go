@@ -0,0 +1,963 @@
+// Package zblogtest 基于 httptest 的 Z-BlogPHP 后台模拟 用于离线测试 z-blog 适配器
+//
+// 模拟登录(cmd.php?act=verify)、后台列表页、文章与页面编辑页、cmd.php 的各类保存与删除、
+// 设置页、LinksManage 导航及 STACentre 伪静态插件页。未登录的后台请求跳转到登录页，
+// cmd.php 及插件页的提交要求正确的 csrfToken，否则返回提示“非法访问”的后台错误页。
+// 另有评论(前台文章页的评论表单、CommentMng)、用户(MemberMng、member_edit.php)、
+// 附件(UploadPst、UploadMng、UploadDel)，应用中心只模拟上传应用包。
+package zblogtest
+
+import (
+	"fmt"
+	"github.com/cgghui/bt_site_cluster_program_api/base"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultVersion 默认模拟的版本
+const DefaultVersion = "1.7.3"
+
+// Server 模拟的 Z-BlogPHP 站点
+type Server struct {
+	*httptest.Server
+	Username string
+	Password string
+	Version  string // 后台输出的版本 1.5 与 1.7 的分类列表结构不同
+	PageSize int    // 后台列表每页数量
+	// NoProduct 不输出 Product 头 部分主机会去掉该响应头，只能从页面的 generator 识别版本
+	NoProduct bool
+	// UploadRename 为 true 时以随机文件名保存附件 模拟开启了附件重命名的站点
+	UploadRename bool
+	// UploadOthers 每次上传时另外新增的附件数 模拟其他用户同时上传
+	UploadOthers int
+	// Broken 以后台列表的 act 为键 对应的列表页返回 500
+	Broken map[string]bool
+
+	mu         sync.Mutex
+	token      string
+	csrf       string
+	seq        int
+	articles   []*base.Article
+	categories []*base.Category
+	tags       []*base.Tag
+	navbar     []base.Navbar
+	plugins    []*base.Plugin
+	uploads    []*base.Media
+	comments   []*base.Comment
+	members    []*base.Member
+	setting    map[string]string
+	rewrite    map[string]string
+	acts       []string
+	pages      []string
+}
+
+// NewServer 启动模拟站点 用户名 admin 密码 123456
+func NewServer() *Server {
+	s := &Server{
+		Username: "admin",
+		Password: "123456",
+		Version:  DefaultVersion,
+		PageSize: 10,
+		plugins: []*base.Plugin{
+			{ID: "STACentre", Name: "STACentre", Version: "1.3"},
+			{ID: "LinksManage", Name: "LinksManage", Version: "1.2", Enabled: true},
+			{ID: "AppCentre", Name: "AppCentre", Version: "2.0", Enabled: true},
+		},
+		setting: map[string]string{
+			"ZC_BLOG_NAME":         "Z-BlogPHP",
+			"ZC_BLOG_SUBNAME":      "Good Luck To You!",
+			"ZC_BLOG_KEYWORDS":     "",
+			"ZC_BLOG_DESCRIPTION":  "",
+			"ZC_BLOG_COPYRIGHT":    "Copyright Your WebSite.Some Rights Reserved.",
+			"ZC_BLOG_ICP":          "",
+			"ZC_TIME_ZONE_NAME":    "Asia/Shanghai",
+			"ZC_BLOG_LANGUAGEPACK": "zh-cn",
+			"ZC_DISPLAY_COUNT":     "10",
+			"ZC_COMMENT_TURNOFF":   "0",
+			"ZC_COMMENT_AUDIT":     "0",
+			"ZC_DEBUG_MODE":        "0",
+		},
+		rewrite: make(map[string]string),
+	}
+	s.categories = append(s.categories, &base.Category{Union: base.Union{ID: s.nextID(), Type: "0"}, Name: "未分类", Order: "0"})
+	// 管理员的登录名和密码以 Username、Password 为准
+	s.members = append(s.members, &base.Member{Union: base.Union{ID: "1"}, Name: s.Username, Alias: s.Username, Level: "1", Status: "0"})
+	s.newSession()
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Info 适配器登录使用的站点信息
+func (s *Server) Info() base.ProgramBaseInfo {
+	return base.ProgramBaseInfo{
+		HomeURL:       s.URL + "/",
+		BackstagePath: "zb_system/",
+		LoginPath:     "cmd.php?act=verify",
+	}
+}
+
+// Expire 使当前登录失效 之后的后台请求将跳转到登录页
+func (s *Server) Expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.newSession()
+}
+
+// RotateCSRF 更换 csrfToken 之前的 token 提交时返回“非法访问”
+func (s *Server) RotateCSRF() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.csrf = fmt.Sprintf("csrf%d", time.Now().UnixNano())
+}
+
+// Pages 收到的后台列表页请求 形如 ArticleMng:2，按顺序
+func (s *Server) Pages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.pages...)
+}
+
+// Acts 收到的 cmd.php 操作 按顺序
+func (s *Server) Acts() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.acts...)
+}
+
+// Setting 当前的设置项
+func (s *Server) Setting(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.setting[key]
+}
+
+// Plugin 插件当前的状态
+func (s *Server) Plugin(id string) (base.Plugin, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.plugins {
+		if p.ID == id {
+			return *p, true
+		}
+	}
+	return base.Plugin{}, false
+}
+
+// Navbar 当前导航
+func (s *Server) Navbar() []base.Navbar {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]base.Navbar(nil), s.navbar...)
+}
+
+func (s *Server) newSession() {
+	now := time.Now().UnixNano()
+	s.token = fmt.Sprintf("token%d", now)
+	s.csrf = fmt.Sprintf("csrf%d", now)
+}
+
+func (s *Server) nextID() string {
+	s.seq++
+	return strconv.Itoa(s.seq)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.NoProduct {
+		w.Header().Set("Product", "Z-BlogPHP "+s.Version)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	p := path.Clean(r.URL.Path)
+	switch {
+	case p == "/" || p == "/index.php":
+		s.front(w, r)
+		return
+	case p == "/zb_system/cmd.php" && r.Form.Get("act") == "cmt":
+		// 评论以表单中的 key 校验 不需要 csrfToken
+		s.commentPost(w, r)
+		return
+	case p == "/zb_system/login.php":
+		s.writePage(w, "登录", `<form method="post" action="cmd.php?act=verify"><input name="edtUserName"><input name="edtPassWord" type="password"></form>`)
+		return
+	case p == "/zb_system/cmd.php" && r.Form.Get("act") == "verify":
+		s.verify(w, r)
+		return
+	}
+	if c, err := r.Cookie("token"); err != nil || c.Value != s.token {
+		http.Redirect(w, r, "/zb_system/login.php", http.StatusFound)
+		return
+	}
+	switch p {
+	case "/zb_system/cmd.php":
+		if !s.checkCSRF(w, r) {
+			return
+		}
+		s.acts = append(s.acts, r.Form.Get("act"))
+		s.cmd(w, r)
+	case "/zb_system/admin/index.php":
+		s.admin(w, r)
+	case "/zb_system/admin/edit.php":
+		s.edit(w, r)
+	case "/zb_system/admin/member_edit.php":
+		s.memberEdit(w, r)
+	case "/zb_system/admin/setting.php":
+		s.settingPage(w)
+	case "/zb_users/plugin/LinksManage/main.php":
+		s.links(w, r)
+	case "/zb_users/plugin/STACentre/main.php":
+		s.stacentre(w, r)
+	case "/zb_users/plugin/AppCentre/app_upload.php":
+		s.appUpload(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) verify(w http.ResponseWriter, r *http.Request) {
+	if !s.checkPassword(r.Form.Get("edtUserName"), r.Form.Get("edtPassWord")) {
+		s.writePage(w, "登录", "用户名或密码错误")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: "username", Value: s.Username, Path: "/"})
+	http.SetCookie(w, &http.Cookie{Name: "token", Value: s.token, Path: "/"})
+	http.Redirect(w, r, "/zb_system/admin/index.php?act=admin", http.StatusFound)
+}
+
+// checkPassword 校验登录名和密码 管理员使用 Username、Password
+func (s *Server) checkPassword(name, password string) bool {
+	if name == s.Username {
+		return password == s.Password
+	}
+	for _, m := range s.members {
+		if m.Name == name {
+			return m.Password != "" && m.Password == password
+		}
+	}
+	return false
+}
+
+func (s *Server) checkCSRF(w http.ResponseWriter, r *http.Request) bool {
+	if r.Form.Get("csrfToken") == s.csrf {
+		return true
+	}
+	s.writeError(w, "非法访问")
+	return false
+}
+
+// done 操作成功 跳转到管理页
+func done(w http.ResponseWriter, r *http.Request, act string) {
+	http.Redirect(w, r, "/zb_system/cmd.php?act="+act, http.StatusFound)
+}
+
+func (s *Server) cmd(w http.ResponseWriter, r *http.Request) {
+	f := r.Form
+	switch f.Get("act") {
+	case "ArticlePst", "PagePst":
+		s.postSave(w, r)
+	case "ArticleDel", "PageDel":
+		for i, a := range s.articles {
+			if a.ID == f.Get("id") {
+				s.articles = append(s.articles[:i], s.articles[i+1:]...)
+				done(w, r, "ArticleMng")
+				return
+			}
+		}
+		s.writeError(w, "文章不存在")
+	case "CategoryPst":
+		c := &base.Category{
+			Union: base.Union{ID: f.Get("ID"), Type: "0"},
+			Name:  f.Get("Name"), Alias: f.Get("Alias"), Order: f.Get("Order"),
+			Template: f.Get("Template"), LogTemplate: f.Get("LogTemplate"), Intro: f.Get("Intro"),
+		}
+		if c.Name == "" {
+			s.writeError(w, "名称不能为空")
+			return
+		}
+		if c.ID == "" || c.ID == "0" {
+			c.ID = s.nextID()
+			s.categories = append(s.categories, c)
+		} else if old := s.category(c.ID); old != nil {
+			*old = *c
+		} else {
+			s.writeError(w, "分类不存在")
+			return
+		}
+		done(w, r, "CategoryMng")
+	case "CategoryDel":
+		for i, c := range s.categories {
+			if c.ID == f.Get("id") {
+				s.categories = append(s.categories[:i], s.categories[i+1:]...)
+				done(w, r, "CategoryMng")
+				return
+			}
+		}
+		s.writeError(w, "分类不存在")
+	case "TagPst":
+		t := &base.Tag{Union: base.Union{ID: f.Get("ID"), Type: "0"}, Name: f.Get("Name"), Alias: f.Get("Alias"), Intro: f.Get("Intro")}
+		if t.ID == "" || t.ID == "0" {
+			if s.tag(t.Name) != nil {
+				s.writeError(w, "标签名称重复")
+				return
+			}
+			t.ID = s.nextID()
+			s.tags = append(s.tags, t)
+		} else {
+			for _, old := range s.tags {
+				if old.ID == t.ID {
+					*old = *t
+				}
+			}
+		}
+		done(w, r, "TagMng")
+	case "TagDel":
+		for i, t := range s.tags {
+			if t.ID == f.Get("id") {
+				s.tags = append(s.tags[:i], s.tags[i+1:]...)
+				done(w, r, "TagMng")
+				return
+			}
+		}
+		s.writeError(w, "标签不存在")
+	case "CommentChk", "CommentDel":
+		for i, c := range s.comments {
+			if c.ID != f.Get("id") {
+				continue
+			}
+			if f.Get("act") == "CommentDel" {
+				s.comments = append(s.comments[:i], s.comments[i+1:]...)
+			} else {
+				c.IsChecking = f.Get("ischecking") == "1"
+			}
+			done(w, r, "CommentMng")
+			return
+		}
+		s.writeError(w, "评论不存在")
+	case "MemberPst":
+		s.memberSave(w, r)
+	case "MemberDel":
+		if f.Get("id") == "1" {
+			s.writeError(w, "不能删除管理员")
+			return
+		}
+		for i, m := range s.members {
+			if m.ID == f.Get("id") {
+				s.members = append(s.members[:i], s.members[i+1:]...)
+				done(w, r, "MemberMng")
+				return
+			}
+		}
+		s.writeError(w, "用户不存在")
+	case "UploadPst":
+		s.upload(w, r)
+	case "UploadDel":
+		for i, m := range s.uploads {
+			if m.ID == f.Get("id") {
+				s.uploads = append(s.uploads[:i], s.uploads[i+1:]...)
+				done(w, r, "UploadMng")
+				return
+			}
+		}
+		s.writeError(w, "附件不存在")
+	case "PluginEnb", "PluginDis":
+		for _, p := range s.plugins {
+			if p.ID == f.Get("name") {
+				p.Enabled = f.Get("act") == "PluginEnb"
+				done(w, r, "PluginMng")
+				return
+			}
+		}
+		s.writeError(w, "插件不存在")
+	case "PluginMng":
+		done(w, r, "PluginMng")
+	case "SettingSav":
+		for k := range f {
+			if strings.HasPrefix(k, "ZC_") {
+				s.setting[k] = f.Get(k)
+			}
+		}
+		done(w, r, "SettingMng")
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// upload 保存上传的附件 地址为 zb_users/upload/年/月/文件名
+func (s *Server) upload(w http.ResponseWriter, r *http.Request) {
+	file, fh, err := r.FormFile("file")
+	if err != nil {
+		s.writeError(w, "没有上传文件")
+		return
+	}
+	_ = file.Close()
+	name := fh.Filename
+	if s.UploadRename {
+		name = fmt.Sprintf("%d%s", time.Now().UnixNano(), path.Ext(name))
+	}
+	s.uploads = append(s.uploads, &base.Media{
+		Union: base.Union{ID: s.nextID()},
+		Name:  name,
+		URL:   s.URL + "/zb_users/upload/" + time.Now().Format("2006/01") + "/" + name,
+	})
+	for i := 0; i < s.UploadOthers; i++ {
+		id := s.nextID()
+		s.uploads = append(s.uploads, &base.Media{
+			Union: base.Union{ID: id},
+			Name:  "other" + id + path.Ext(name),
+			URL:   s.URL + "/zb_users/upload/" + time.Now().Format("2006/01") + "/other" + id + path.Ext(name),
+		})
+	}
+	done(w, r, "UploadMng")
+}
+
+// AddComment 以访客身份添加一条已审核的评论 返回评论ID
+func (s *Server) AddComment(logID, name, content string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := &base.Comment{Union: base.Union{ID: s.nextID()}, LogID: logID, ParentID: "0", Name: name, Content: content, PostTime: time.Now()}
+	s.comments = append(s.comments, c)
+	return c.ID
+}
+
+// front 前台页面 带 id 时为文章页，包含评论表单
+func (s *Server) front(w http.ResponseWriter, r *http.Request) {
+	a := s.article(r.Form.Get("id"))
+	if a == nil {
+		s.writePage(w, "首页", "")
+		return
+	}
+	s.writePage(w, a.Title, `<div class="post">`+a.Content+`</div>`+
+		`<form id="frmSumbit" target="_self" method="post" action="`+s.URL+`/zb_system/cmd.php?act=cmt&amp;postid=`+a.ID+`&amp;key=k`+a.ID+`">`+
+		`<input type="hidden" name="inpId" id="inpId" value="`+a.ID+`"><input type="hidden" name="inpRevID" id="inpRevID" value="0">`+
+		`<input type="text" name="inpName" id="inpName"><input type="text" name="inpEmail" id="inpEmail"><input type="text" name="inpHomePage" id="inpHomePage">`+
+		`<textarea name="txaArticle" id="txaArticle"></textarea></form>`)
+}
+
+// commentPost 发表评论 成功后跳转回文章页，出错时以 200 返回错误页
+func (s *Server) commentPost(w http.ResponseWriter, r *http.Request) {
+	f := r.Form
+	a := s.article(f.Get("postid"))
+	if a == nil || f.Get("key") != "k"+a.ID || f.Get("inpId") != a.ID {
+		s.writeError(w, "非法访问")
+		return
+	}
+	if strings.TrimSpace(f.Get("inpName")) == "" {
+		s.writeError(w, "名称不能为空")
+		return
+	}
+	if strings.TrimSpace(f.Get("txaArticle")) == "" {
+		s.writeError(w, "评论内容不能为空")
+		return
+	}
+	parent := f.Get("inpRevID")
+	if parent == "" {
+		parent = "0"
+	}
+	c := &base.Comment{
+		Union:    base.Union{ID: s.nextID()},
+		LogID:    a.ID,
+		ParentID: parent,
+		Name:     f.Get("inpName"), Email: f.Get("inpEmail"), HomePage: f.Get("inpHomePage"),
+		Content:    f.Get("txaArticle"),
+		PostTime:   time.Now(),
+		IsChecking: s.setting["ZC_COMMENT_AUDIT"] == "1",
+	}
+	s.comments = append(s.comments, c)
+	http.Redirect(w, r, "/?id="+a.ID+"#cmt"+c.ID, http.StatusFound)
+}
+
+func (s *Server) member(id string) *base.Member {
+	for _, m := range s.members {
+		if m.ID == id {
+			return m
+		}
+	}
+	return nil
+}
+
+// memberSave 保存用户 修改管理员的密码时同时修改 Password
+func (s *Server) memberSave(w http.ResponseWriter, r *http.Request) {
+	f := r.Form
+	if f.Get("Password") != f.Get("PasswordRe") {
+		s.writeError(w, "两次输入的密码不一致")
+		return
+	}
+	m := s.member(f.Get("ID"))
+	if m == nil {
+		if f.Get("ID") != "" && f.Get("ID") != "0" {
+			s.writeError(w, "用户不存在")
+			return
+		}
+		if f.Get("Name") == "" || f.Get("Password") == "" {
+			s.writeError(w, "用户名和密码不能为空")
+			return
+		}
+		for _, v := range s.members {
+			if v.Name == f.Get("Name") {
+				s.writeError(w, "用户名已存在")
+				return
+			}
+		}
+		m = &base.Member{Union: base.Union{ID: strconv.Itoa(len(s.members) + 1)}}
+		for s.member(m.ID) != nil {
+			m.ID = strconv.Itoa(atoi(m.ID) + 1)
+		}
+		s.members = append(s.members, m)
+	}
+	m.Name, m.Alias, m.Level, m.Status = f.Get("Name"), f.Get("Alias"), f.Get("Level"), f.Get("Status")
+	m.Email, m.HomePage, m.Intro, m.Template = f.Get("Email"), f.Get("HomePage"), f.Get("Intro"), f.Get("Template")
+	if password := f.Get("Password"); password != "" {
+		m.Password = password
+		if m.ID == "1" {
+			s.Password = password
+		}
+	}
+	if m.ID == "1" {
+		s.Username = m.Name
+	}
+	done(w, r, "MemberMng")
+}
+
+// memberEdit 用户编辑页 不存在时ID为0
+func (s *Server) memberEdit(w http.ResponseWriter, r *http.Request) {
+	m := s.member(r.Form.Get("id"))
+	if m == nil {
+		m = &base.Member{Union: base.Union{ID: "0"}, Level: "5", Status: "0"}
+	}
+	b := &strings.Builder{}
+	input := func(name, value string) {
+		fmt.Fprintf(b, `<input id="edt%s" name="%s" type="text" value="%s">`, name, name, html.EscapeString(value))
+	}
+	input("ID", m.ID)
+	b.WriteString(`<select id="cmbLevel" name="Level">`)
+	for _, level := range []string{"1", "2", "3", "4", "5", "6"} {
+		sel := ""
+		if level == m.Level {
+			sel = ` selected="selected"`
+		}
+		fmt.Fprintf(b, `<option value="%s"%s>%s</option>`, level, sel, memberLevelText[level])
+	}
+	b.WriteString(`</select><select id="cmbStatus" name="Status">`)
+	for _, st := range []string{"0", "1", "2"} {
+		sel := ""
+		if st == m.Status {
+			sel = ` selected="selected"`
+		}
+		fmt.Fprintf(b, `<option value="%s"%s>%s</option>`, st, sel, st)
+	}
+	b.WriteString(`</select>`)
+	input("Name", m.Name)
+	b.WriteString(`<input id="edtPassword" name="Password" type="password" value=""><input id="edtPasswordRe" name="PasswordRe" type="password" value="">`)
+	input("Alias", m.Alias)
+	input("Email", m.Email)
+	input("HomePage", m.HomePage)
+	fmt.Fprintf(b, `<textarea id="edtIntro" name="Intro">%s</textarea>`, html.EscapeString(m.Intro))
+	input("Template", m.Template)
+	s.writePage(w, "用户编辑", `<form id="edit" name="edit" method="post" action="../cmd.php?act=MemberPst">`+b.String()+`</form>`)
+}
+
+// memberLevelText 用户角色的显示文本
+var memberLevelText = map[string]string{"1": "管理员", "2": "网站编辑", "3": "作者", "4": "协作者", "5": "评论员", "6": "游客"}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// zbaRegexp 应用包中的 <id>、<name>、<version>
+var zbaRegexp = regexp.MustCompile(`<(id|name|version)>([^<]*)</`)
+
+// appUpload 应用中心上传应用包 包中的插件加入插件列表 出错时以 200 返回错误页
+func (s *Server) appUpload(w http.ResponseWriter, r *http.Request) {
+	file, _, err := r.FormFile("edtFileLoad")
+	if err != nil {
+		s.writeError(w, "没有上传文件")
+		return
+	}
+	data, _ := ioutil.ReadAll(file)
+	_ = file.Close()
+	if !s.checkCSRF(w, r) {
+		return
+	}
+	app := make(map[string]string)
+	for _, m := range zbaRegexp.FindAllStringSubmatch(string(data), -1) {
+		app[m[1]] = m[2]
+	}
+	if app["id"] == "" {
+		s.writeError(w, "应用包格式错误")
+		return
+	}
+	for _, p := range s.plugins {
+		if p.ID == app["id"] {
+			p.Name, p.Version = app["name"], app["version"]
+			s.writePage(w, "应用中心", "应用已更新")
+			return
+		}
+	}
+	s.plugins = append(s.plugins, &base.Plugin{ID: app["id"], Name: app["name"], Version: app["version"]})
+	s.writePage(w, "应用中心", "应用已安装")
+}
+
+func (s *Server) postSave(w http.ResponseWriter, r *http.Request) {
+	f := r.Form
+	typ := base.TypeArticle
+	mng := "ArticleMng"
+	if f.Get("act") == "PagePst" {
+		typ, mng = base.TypePage, "PageMng"
+	}
+	a := &base.Article{
+		Union:    base.Union{ID: f.Get("ID"), Type: typ},
+		Title:    f.Get("Title"),
+		Content:  f.Get("Content"),
+		Alias:    f.Get("Alias"),
+		Cate:     &base.Category{Union: base.Union{ID: f.Get("CateID")}},
+		Status:   base.Status(f.Get("Status")),
+		Template: f.Get("Template"),
+		AuthorID: f.Get("AuthorID"),
+		IsTop:    base.TopLevel(f.Get("IsTop")),
+		IsLock:   base.LockState(f.Get("IsLock")),
+		Intro:    f.Get("Intro"),
+	}
+	a.PostTime, _ = time.ParseInLocation("2006-01-02 15:04:05", f.Get("PostTime"), time.Local)
+	if a.Title == "" {
+		s.writeError(w, "标题不能为空")
+		return
+	}
+	for _, name := range strings.Split(f.Get("Tag"), ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		a.Tag = append(a.Tag, name)
+		if s.tag(name) == nil {
+			s.tags = append(s.tags, &base.Tag{Union: base.Union{ID: s.nextID(), Type: "0"}, Name: name})
+		}
+	}
+	if a.ID == "" || a.ID == "0" {
+		a.ID = s.nextID()
+		s.articles = append(s.articles, a)
+	} else if old := s.article(a.ID); old != nil {
+		*old = *a
+	} else {
+		s.writeError(w, "文章不存在")
+		return
+	}
+	// 保存后以脚本跳转回管理页
+	s.writePage(w, "提交成功", `<script>location.href="cmd.php?act=`+mng+`&redirect=cmd.php%3Fact%3D`+mng+`";</script>`)
+}
+
+func (s *Server) article(id string) *base.Article {
+	for _, a := range s.articles {
+		if a.ID == id {
+			return a
+		}
+	}
+	return nil
+}
+
+func (s *Server) category(id string) *base.Category {
+	for _, c := range s.categories {
+		if c.ID == id {
+			return c
+		}
+	}
+	return nil
+}
+
+func (s *Server) tag(name string) *base.Tag {
+	for _, t := range s.tags {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+func (s *Server) permalink() *base.Permalink {
+	pl := &base.Permalink{
+		Article: s.rewrite["ZC_ARTICLE_REGEX"], Page: s.rewrite["ZC_PAGE_REGEX"], Index: s.rewrite["ZC_INDEX_REGEX"],
+		Category: s.rewrite["ZC_CATEGORY_REGEX"], Tag: s.rewrite["ZC_TAGS_REGEX"],
+		Date: s.rewrite["ZC_DATE_REGEX"], Author: s.rewrite["ZC_AUTHOR_REGEX"],
+	}
+	pl.Fill()
+	return pl
+}
+
+var statusText = map[base.Status]string{base.StatusPublic: "公开", base.StatusDraft: "草稿", base.StatusAudit: "审核"}
+
+// admin 后台首页及各管理列表
+func (s *Server) admin(w http.ResponseWriter, r *http.Request) {
+	f := r.Form
+	act := f.Get("act")
+	home := s.URL + "/"
+	if s.Broken[act] {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	var head []string
+	var rows [][]string
+	switch act {
+	case "", "admin":
+		s.writePage(w, "网站摘要", "Z-BlogPHP "+s.Version)
+		return
+	case "ArticleMng", "PageMng":
+		typ := base.TypeArticle
+		if act == "PageMng" {
+			typ = base.TypePage
+		}
+		pl := s.permalink()
+		for i := len(s.articles) - 1; i >= 0; i-- {
+			a := s.articles[i]
+			if a.Type != typ || (f.Get("search") != "" && !strings.Contains(a.Title, f.Get("search"))) {
+				continue
+			}
+			if f.Get("status") != "" && string(a.Status) != f.Get("status") {
+				continue
+			}
+			if typ == base.TypePage {
+				link := `<a href="` + html.EscapeString(base.BuildLink(pl.Page, home, a.ID, a.Alias)) + `">` + html.EscapeString(a.Title) + `</a>`
+				rows = append(rows, []string{a.ID, a.AuthorID, link, a.PostTime.Format("2006-01-02 15:04:05"), "0", statusText[a.Status], ""})
+				continue
+			}
+			if f.Get("category") != "" && a.Cate.ID != f.Get("category") {
+				continue
+			}
+			name := ""
+			if c := s.category(a.Cate.ID); c != nil {
+				name = c.Name
+			}
+			link := `<a href="` + html.EscapeString(pl.ArticleURL(home, a.ID, a.Alias)) + `">` + html.EscapeString(a.Title) + `</a>`
+			rows = append(rows, []string{a.ID, html.EscapeString(name), a.AuthorID, link, a.PostTime.Format("2006-01-02 15:04:05"), "0", statusText[a.Status], ""})
+		}
+		head = []string{"ID", "分类", "作者", "标题", "日期", "评论", "状态", "操作"}
+		if typ == base.TypePage {
+			head = []string{"ID", "作者", "标题", "日期", "评论", "状态", "操作"}
+		}
+	case "CategoryMng":
+		for _, c := range s.categories {
+			rows = append(rows, []string{c.ID, html.EscapeString(c.Order), html.EscapeString(c.Name), html.EscapeString(c.Alias), "0", ""})
+		}
+		head = []string{"ID", "排序", "名称", "别名", "文章数", "操作"}
+	case "TagMng":
+		tags := append([]*base.Tag(nil), s.tags...)
+		sort.Slice(tags, func(i, j int) bool {
+			x, _ := strconv.Atoi(tags[i].ID)
+			y, _ := strconv.Atoi(tags[j].ID)
+			return x > y
+		})
+		for _, t := range tags {
+			if f.Get("search") != "" && !strings.Contains(t.Name, f.Get("search")) {
+				continue
+			}
+			rows = append(rows, []string{t.ID, html.EscapeString(t.Name), html.EscapeString(t.Alias), "0", ""})
+		}
+		head = []string{"ID", "名称", "别名", "文章数", "操作"}
+	case "CommentMng":
+		checking := f.Get("ischecking") == "1"
+		for i := len(s.comments) - 1; i >= 0; i-- {
+			c := s.comments[i]
+			if c.IsChecking != checking || (f.Get("search") != "" && !strings.Contains(c.Content, f.Get("search"))) {
+				continue
+			}
+			rows = append(rows, []string{c.ID, c.ParentID, html.EscapeString(c.Name), html.EscapeString(c.Content), c.LogID, c.PostTime.Format("2006-01-02 15:04:05"), ""})
+		}
+		head = []string{"ID", "父ID", "名称", "正文", "文章ID", "日期", "操作"}
+	case "MemberMng":
+		for _, m := range s.members {
+			rows = append(rows, []string{m.ID, memberLevelText[m.Level], html.EscapeString(m.Name), html.EscapeString(m.Alias), "0", "0", "0", "0", ""})
+		}
+		head = []string{"ID", "级别", "用户名", "别名", "文章", "页面", "评论", "附件", "操作"}
+	case "UploadMng":
+		for i := len(s.uploads) - 1; i >= 0; i-- {
+			m := s.uploads[i]
+			link := `<a href="` + html.EscapeString(m.URL) + `" target="_blank">` + html.EscapeString(m.Name) + `</a>`
+			rows = append(rows, []string{m.ID, s.Username, link, "", "0", "", ""})
+		}
+		head = []string{"ID", "作者", "文件名", "上传日期", "大小", "类型", "操作"}
+	case "PluginMng":
+		for _, p := range s.plugins {
+			link := `<a href="../cmd.php?act=PluginEnb&name=` + p.ID + `&csrfToken=` + s.csrf + `">启用</a>`
+			if p.Enabled {
+				link = `<a href="../cmd.php?act=PluginDis&name=` + p.ID + `&csrfToken=` + s.csrf + `">停用</a>`
+			}
+			rows = append(rows, []string{"", p.Name + " " + p.Version, "zblogcn", "", link})
+		}
+		head = []string{"", "名称", "作者", "简介", "操作"}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	class := "tableBorder table_hover table_striped"
+	if act == "CategoryMng" && strings.HasPrefix(s.Version, "1.5") {
+		class = "tableBorder tableBorder-thcenter"
+	}
+	page, _ := strconv.Atoi(f.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	s.pages = append(s.pages, act+":"+strconv.Itoa(page))
+	last := (len(rows) + s.PageSize - 1) / s.PageSize
+	start := (page - 1) * s.PageSize
+	if start > len(rows) {
+		start = len(rows)
+	}
+	end := start + s.PageSize
+	if end > len(rows) {
+		end = len(rows)
+	}
+	b := &strings.Builder{}
+	b.WriteString(`<table class="` + class + `"><tr><th>` + strings.Join(head, "</th><th>") + `</th></tr>`)
+	for _, row := range rows[start:end] {
+		b.WriteString(`<tr><td>` + strings.Join(row, "</td><td>") + `</td></tr>`)
+	}
+	b.WriteString(`</table><p class="pagebar">`)
+	for i := 1; i <= last; i++ {
+		fmt.Fprintf(b, `<a href="index.php?act=%s&page=%d">%d</a>`, act, i, i)
+	}
+	b.WriteString(`</p>`)
+	s.writePage(w, act, b.String())
+}
+
+// edit 文章及页面的编辑页 不存在时ID为0
+func (s *Server) edit(w http.ResponseWriter, r *http.Request) {
+	a := s.article(r.Form.Get("id"))
+	if a == nil {
+		a = &base.Article{Union: base.Union{ID: "0"}, Cate: &base.Category{}}
+	}
+	b := &strings.Builder{}
+	input := func(name, value string) {
+		fmt.Fprintf(b, `<input type="text" name="%s" value="%s">`, name, html.EscapeString(value))
+	}
+	input("ID", a.ID)
+	input("Type", a.Type)
+	input("Title", a.Title)
+	fmt.Fprintf(b, `<textarea name="Content">%s</textarea>`, html.EscapeString(a.Content))
+	input("Alias", a.Alias)
+	input("Tag", strings.Join(a.Tag, ","))
+	b.WriteString(`<select name="CateID">`)
+	for _, c := range s.categories {
+		sel := ""
+		if c.ID == a.Cate.ID {
+			sel = " selected"
+		}
+		fmt.Fprintf(b, `<option value="%s"%s>%s</option>`, c.ID, sel, html.EscapeString(c.Name))
+	}
+	b.WriteString(`</select><select name="Status">`)
+	for _, st := range []base.Status{base.StatusPublic, base.StatusDraft, base.StatusAudit} {
+		sel := ""
+		if st == a.Status {
+			sel = " selected"
+		}
+		fmt.Fprintf(b, `<option value="%s"%s>%s</option>`, string(st), sel, statusText[st])
+	}
+	b.WriteString(`</select>`)
+	input("Template", a.Template)
+	input("AuthorID", a.AuthorID)
+	input("PostTime", a.PostTime.Format("2006-01-02 15:04:05"))
+	input("IsTop", string(a.IsTop))
+	// 后台的开关是 class 为 checkbox 的文本框 由脚本渲染，值为 0 或 1
+	lock := "0"
+	if a.IsLock != "" && a.IsLock != "0" {
+		lock = "1"
+	}
+	fmt.Fprintf(b, `<input id="edtIslock" name="IsLock" style="" type="text" value="%s" class="checkbox"/>`, lock)
+	fmt.Fprintf(b, `<textarea name="Intro">%s</textarea>`, html.EscapeString(a.Intro))
+	s.writePage(w, "编辑", `<form id="edit" method="post">`+b.String()+`</form>`)
+}
+
+func (s *Server) settingPage(w http.ResponseWriter) {
+	keys := make([]string, 0, len(s.setting))
+	for k := range s.setting {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	b := &strings.Builder{}
+	for _, k := range keys {
+		fmt.Fprintf(b, `<input type="text" name="%s" value="%s">`, k, html.EscapeString(s.setting[k]))
+	}
+	s.writePage(w, "网站设置", `<form method="post" action="../cmd.php?act=SettingSav">`+b.String()+`</form>`)
+}
+
+// links LinksManage 插件的导航编辑页
+func (s *Server) links(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		if !s.checkCSRF(w, r) {
+			return
+		}
+		f := r.Form
+		s.navbar = s.navbar[:0]
+		for i, href := range f["href[]"] {
+			if href == "" {
+				continue
+			}
+			at := func(k string) string {
+				if v := f[k]; i < len(v) {
+					return v[i]
+				}
+				return ""
+			}
+			s.navbar = append(s.navbar, base.Navbar{Href: href, Title: at("title[]"), Text: at("text[]"), Target: at("target[]"), Sub: at("sub[]"), Ico: at("ico[]")})
+		}
+		http.Redirect(w, r, "main.php?edit=navbar", http.StatusFound)
+		return
+	}
+	b := &strings.Builder{}
+	b.WriteString(`<table id="LinksManageList"><tr><th>链接</th><th>描述</th><th>文本</th><th>新窗</th><th>二级</th><th>图标</th></tr>`)
+	for _, n := range s.navbar {
+		b.WriteString("<tr>")
+		for _, v := range [][2]string{{"href[]", n.Href}, {"title[]", n.Title}, {"text[]", n.Text}, {"target[]", n.Target}, {"sub[]", n.Sub}, {"ico[]", n.Ico}} {
+			fmt.Fprintf(b, `<td><input name="%s" value="%s"></td>`, v[0], html.EscapeString(v[1]))
+		}
+		b.WriteString("</tr>")
+	}
+	b.WriteString(`</table>`)
+	s.writePage(w, "导航", b.String())
+}
+
+// stacentre 伪静态插件页 插件未启用时返回404
+func (s *Server) stacentre(w http.ResponseWriter, r *http.Request) {
+	for _, p := range s.plugins {
+		if p.ID == "STACentre" && !p.Enabled {
+			http.NotFound(w, r)
+			return
+		}
+	}
+	keys := []string{"ZC_STATIC_MODE", "ZC_ARTICLE_REGEX", "ZC_PAGE_REGEX", "ZC_INDEX_REGEX", "ZC_CATEGORY_REGEX", "ZC_TAGS_REGEX", "ZC_DATE_REGEX", "ZC_AUTHOR_REGEX"}
+	if r.Method == http.MethodPost {
+		if !s.checkCSRF(w, r) {
+			return
+		}
+		for _, k := range keys {
+			s.rewrite[k] = r.Form.Get(k)
+		}
+		http.Redirect(w, r, "main.php", http.StatusFound)
+		return
+	}
+	b := &strings.Builder{}
+	for _, k := range keys {
+		fmt.Fprintf(b, `<input type="text" name="%s" value="%s">`, k, html.EscapeString(s.rewrite[k]))
+	}
+	s.writePage(w, "静态化选项", `<form method="post">`+b.String()+`</form>`)
+}
+
+// writeError 后台错误页 以登录框的样式显示错误提示
+func (s *Server) writeError(w http.ResponseWriter, msg string) {
+	s.writePage(w, "错误提示", `<div class="login loginw"><form id="frmLogin" method="post" action="#">`+
+		`<div class="divHeader lessinfo"><b>错误提示</b></div>`+
+		`<div class="content lessinfo"><div><p id="title">错误原因:</p><p>`+html.EscapeString(msg)+`</p></div></div>`+
+		`</form></div>`)
+}
+
+func (s *Server) writePage(w http.ResponseWriter, title, body string) {
+	_, _ = fmt.Fprintf(w, `<!DOCTYPE html><html><head><meta charset="utf-8"><meta name="generator" content="Z-BlogPHP %s"><meta name="csrfToken" content="%s"><title>%s</title></head><body>%s<div class="footer">Powered by Z-BlogPHP %s</div></body></html>`,
+		s.Version, s.csrf, html.EscapeString(title), body, s.Version)
+}